@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+// Alternate data streams only exist on NTFS.
+func alternateStreams(path string) ([]string, error) {
+	return nil, nil
+}
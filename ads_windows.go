@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procFindFirstStreamW = kernel32.NewProc("FindFirstStreamW")
+	procFindNextStreamW  = kernel32.NewProc("FindNextStreamW")
+)
+
+// win32FindStreamData mirrors WIN32_FIND_STREAM_DATA
+type win32FindStreamData struct {
+	StreamSize int64
+	StreamName [syscall.MAX_PATH + 36]uint16
+}
+
+// alternateStreams returns the paths of all named NTFS data streams
+// attached to path, e.g. "file.txt:Zone.Identifier".
+func alternateStreams(path string) ([]string, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var data win32FindStreamData
+	handle, _, err := procFindFirstStreamW.Call(uintptr(unsafe.Pointer(pathPtr)), 0, uintptr(unsafe.Pointer(&data)), 0)
+	if syscall.Handle(handle) == syscall.InvalidHandle {
+		if err == syscall.ERROR_HANDLE_EOF {
+			return nil, nil // No streams at all
+		}
+		return nil, err
+	}
+	defer syscall.FindClose(syscall.Handle(handle))
+
+	var streams []string
+	for {
+		// Names look like ":Zone.Identifier:$DATA"; the unnamed stream is "::$DATA"
+		name := syscall.UTF16ToString(data.StreamName[:])
+		name = strings.TrimSuffix(name, ":$DATA")
+		if name != "" && name != ":" {
+			streams = append(streams, path+name)
+		}
+
+		ret, _, err := procFindNextStreamW.Call(handle, uintptr(unsafe.Pointer(&data)))
+		if ret == 0 {
+			if err == syscall.ERROR_HANDLE_EOF {
+				break
+			}
+			return streams, err
+		}
+	}
+	return streams, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWipeAlternateStreams tests that named streams are removed and the
+// file's own data is left to the overwrite
+func TestWipeAlternateStreams(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(file, []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file+":hidden", []byte("stream data"), 0600); err != nil {
+		t.Skipf("Cannot create alternate data streams here: %v", err)
+	}
+	if streams, err := alternateStreams(file); err != nil || len(streams) != 1 || streams[0] != file+":hidden" {
+		t.Fatalf("alternateStreams = %q, %v, want the hidden stream", streams, err)
+	}
+
+	wipeAlternateStreams(file)
+	if streams, err := alternateStreams(file); err != nil || len(streams) != 0 {
+		t.Errorf("Expected no streams left, got %q, %v", streams, err)
+	}
+	if data, err := os.ReadFile(file); err != nil || string(data) != "content" {
+		t.Errorf("Expected the file's own data unchanged, got %q, %v", data, err)
+	}
+}
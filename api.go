@@ -0,0 +1,246 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// API options of wipefile daemon
+var (
+	apiAddr      string
+	apiTokenFile string
+	apiRoots     string
+	apiCert      string
+	apiKey       string
+)
+
+// registerAPIFlags adds the options of the daemon's HTTP API to flags
+func registerAPIFlags(flags *flag.FlagSet) {
+	flags.StringVar(&apiAddr, "api-addr", "", "With wipefile daemon, also take jobs over an HTTP API on this address (e.g. :8443), needs -api-token-file and -api-roots")
+	flags.StringVar(&apiTokenFile, "api-token-file", "", "File holding the bearer token API requests must carry")
+	flags.StringVar(&apiRoots, "api-roots", "", "Directories API jobs may wipe below, comma-separated")
+	flags.StringVar(&apiCert, "api-cert", "", "Serve the API over HTTPS with this certificate (PEM)")
+	flags.StringVar(&apiKey, "api-key", "", "Private key of -api-cert (PEM)")
+}
+
+// daemonAPI serves job submission, status and cancellation over HTTP
+type daemonAPI struct {
+	token string
+	roots []string // With symlinks resolved
+}
+
+// newDaemonAPI reads the token and resolves the allowed roots
+func newDaemonAPI(tokenFile, roots string) (*daemonAPI, error) {
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	api := &daemonAPI{token: strings.TrimSpace(string(data))}
+	if api.token == "" {
+		return nil, fmt.Errorf("token file '%s' is empty", tokenFile)
+	}
+	for _, root := range strings.Split(roots, ",") {
+		if root = strings.TrimSpace(root); root == "" {
+			continue
+		}
+		resolved, err := resolveRoot(root)
+		if err != nil {
+			return nil, fmt.Errorf("-api-roots: %s", getSimpleError(err))
+		}
+		api.roots = append(api.roots, resolved)
+	}
+	if len(api.roots) == 0 {
+		return nil, errors.New("-api-roots names no directory")
+	}
+	return api, nil
+}
+
+// serveAPI serves the API on -api-addr, over HTTPS with -api-cert
+func serveAPI(api *daemonAPI) error {
+	listener, err := net.Listen("tcp", apiAddr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/jobs", api.authorized(api.handleSubmit))
+	mux.HandleFunc("/v1/jobs/", api.authorized(api.handleJob))
+	server := &http.Server{Handler: mux}
+	if apiCert != "" {
+		go server.ServeTLS(listener, apiCert, apiKey)
+	} else {
+		go server.Serve(listener)
+	}
+	printStatus("API listening on %s\n", listener.Addr())
+	return nil
+}
+
+// authorized lets requests through to handler only with the bearer token
+func (api *daemonAPI) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(header, "Bearer ")
+		if token == header || subtle.ConstantTimeCompare([]byte(token), []byte(api.token)) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, "missing or wrong token")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// handleSubmit queues the job posted to /v1/jobs
+func (api *daemonAPI) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "use POST to submit a job")
+		return
+	}
+	var request jobRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024*1024)).Decode(&request); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid job: "+err.Error())
+		return
+	}
+	if len(request.Paths) == 0 {
+		writeAPIError(w, http.StatusBadRequest, "job has no paths")
+		return
+	}
+	for i, path := range request.Paths {
+		if err := api.checkPath(path); err != nil {
+			writeAPIError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		// The job wipes the path that was checked: a trailing separator
+		// would make it follow a symlink at the end
+		request.Paths[i] = filepath.Clean(path)
+	}
+	request.Wait = false
+	request.roots = api.roots
+	job, err := queueJob(request)
+	if err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeAPIReply(w, http.StatusAccepted, jobReply{Job: job.id, Status: "queued"})
+}
+
+// handleJob answers GET (status) and DELETE (cancel) of /v1/jobs/ID
+func (api *daemonAPI) handleJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/v1/jobs/"), 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "no such job")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		reply, ok := jobStatus(id)
+		if !ok {
+			writeAPIError(w, http.StatusNotFound, "no such job")
+			return
+		}
+		writeAPIReply(w, http.StatusOK, reply)
+	case http.MethodDelete:
+		switch err := cancelJob(id); err {
+		case nil:
+			reply, _ := jobStatus(id)
+			writeAPIReply(w, http.StatusOK, reply)
+		case errJobNotFound:
+			writeAPIError(w, http.StatusNotFound, err.Error())
+		default:
+			writeAPIError(w, http.StatusConflict, err.Error())
+		}
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, "use GET for the status or DELETE to cancel")
+	}
+}
+
+// checkPath returns why path may not be wiped over the API, if it may not:
+// it has to be absolute and below one of the -api-roots, also once
+// symlinks in the directories leading to it are resolved. The job checks
+// again before wiping, as the directories may change in between.
+func (api *daemonAPI) checkPath(path string) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("'%s' is not an absolute path", path)
+	}
+	path = filepath.Clean(path)
+	resolved, err := resolvePath(path)
+	if err != nil {
+		resolved = path // Let the job report it as missing, if it's below a root at all
+	}
+	if !inRoots(resolved, api.roots) {
+		return fmt.Errorf("'%s' is not below an allowed root", path)
+	}
+	return nil
+}
+
+// inRoots reports whether path is inside one of roots, and not a root
+func inRoots(path string, roots []string) bool {
+	for _, root := range roots {
+		if path != root && isBelow(path, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepInRoots drops the paths that aren't inside one of roots, as
+// checked by inAllowedRoot
+func keepInRoots(paths []string, roots []string) []string {
+	kept := paths[:0]
+	for _, path := range paths {
+		if inAllowedRoot(path, roots) {
+			kept = append(kept, path)
+		}
+	}
+	return kept
+}
+
+// inAllowedRoot reports whether path is inside one of roots once the
+// symlinks in the directories leading to it are resolved, and counts it as
+// denied if not
+func inAllowedRoot(path string, roots []string) bool {
+	resolved, err := resolvePath(filepath.Clean(path))
+	if err == nil && filepath.IsAbs(resolved) && inRoots(resolved, roots) {
+		return true
+	}
+	printError("cannot wipe '%s': Not below an allowed root\n", path)
+	countFailure(path, fs.ErrPermission)
+	return false
+}
+
+// resolveRoot returns the absolute path of the directory root, with its
+// symlinks resolved, for paths to be checked against
+func resolveRoot(root string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(root)
+	if err == nil && !filepath.IsAbs(resolved) {
+		resolved, err = filepath.Abs(resolved)
+	}
+	return resolved, err
+}
+
+// isBelow reports whether path is root or inside it
+func isBelow(path, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// writeAPIReply answers with reply as JSON
+func writeAPIReply(w http.ResponseWriter, status int, reply jobReply) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(reply)
+}
+
+// writeAPIError answers with an error message as JSON
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAPICheckPath tests that only paths below an allowed root pass, also
+// through symlinked directories
+func TestAPICheckPath(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	outside := filepath.Join(dir, "outside")
+	os.Mkdir(root, 0700)
+	os.Mkdir(outside, 0700)
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skipf("Cannot create symlinks: %v", err)
+	}
+	tokenFile := filepath.Join(dir, "token")
+	os.WriteFile(tokenFile, []byte("secret\n"), 0600)
+	api, err := newDaemonAPI(tokenFile, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.token != "secret" {
+		t.Errorf("Expected the token without its newline, got %q", api.token)
+	}
+
+	for path, allowed := range map[string]bool{
+		filepath.Join(root, "a.txt"):            true,
+		filepath.Join(root, "sub", "b.txt"):     true,
+		root:                                    false,
+		filepath.Join(root, "..", "x.txt"):      false,
+		filepath.Join(root, "link", "x.txt"):    false,
+		filepath.Join(outside, "x.txt"):         false,
+		"a.txt":                                 false,
+		filepath.Join(dir, "rootless", "x.txt"): false,
+	} {
+		if err := api.checkPath(path); (err == nil) != allowed {
+			t.Errorf("checkPath(%q) = %v, expected allowed %v", path, err, allowed)
+		}
+	}
+}
+
+// TestAPIJobStaysInRoots tests that a job wipes nothing outside the roots,
+// neither through a trailing separator after a symlink nor through a
+// directory swapped for a symlink after the job was queued
+func TestAPIJobStaysInRoots(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	outside := filepath.Join(dir, "outside")
+	os.MkdirAll(filepath.Join(root, "sub"), 0700)
+	os.Mkdir(outside, 0700)
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Skipf("Cannot create symlinks: %v", err)
+	}
+	kept := filepath.Join(outside, "kept.txt")
+	wiped := filepath.Join(root, "wiped.txt")
+	os.WriteFile(kept, []byte("content"), 0600)
+	os.WriteFile(wiped, []byte("content"), 0600)
+	api := &daemonAPI{token: "secret", roots: []string{root}}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		api.roots = []string{resolved}
+	}
+
+	queue := daemonJobs
+	daemonJobs = make(chan *daemonJob, 1)
+	defer func() { daemonJobs = queue }()
+	body := `{"paths":["` + filepath.ToSlash(link) + `/"],"recursive":true}`
+	r := httptest.NewRequest("POST", "/v1/jobs", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer secret")
+	api.authorized(api.handleSubmit)(httptest.NewRecorder(), r)
+	job := <-daemonJobs
+	if job.request.Paths[0] != link {
+		t.Errorf("Expected the job to wipe the link itself, got %q", job.request.Paths[0])
+	}
+
+	saved, savedRecursive := *parallel, *recursive
+	*parallel, *recursive = 2, true
+	defer func() {
+		*parallel, *recursive = saved, savedRecursive
+		wipeResults.wiped.Store(0)
+		wipeResults.failed.Store(0)
+		wipeResults.denied.Store(0)
+	}()
+	wipeResults.denied.Store(0)
+
+	// A job queued for root/sub, which is a symlink by the time it runs
+	os.Remove(filepath.Join(root, "sub"))
+	os.Symlink(outside, filepath.Join(root, "sub"))
+	paths := []string{link + string(os.PathSeparator), filepath.Join(root, "sub", "kept.txt"), wiped}
+	wipeTargetsBelow(paths, api.roots)
+
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("File outside the roots was wiped: %v", err)
+	}
+	if _, err := os.Stat(wiped); !os.IsNotExist(err) {
+		t.Errorf("File inside the roots wasn't wiped: %v", err)
+	}
+	if denied := wipeResults.denied.Load(); denied == 0 {
+		t.Errorf("Expected the paths outside the roots to be denied")
+	}
+}
+
+// TestAPIRequests tests token auth, and submitting and canceling jobs
+func TestAPIRequests(t *testing.T) {
+	api := &daemonAPI{token: "secret", roots: []string{t.TempDir()}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/jobs", api.authorized(api.handleSubmit))
+	mux.HandleFunc("/v1/jobs/", api.authorized(api.handleJob))
+	request := func(method, path, token, body string) (int, map[string]interface{}) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, r)
+		var reply map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &reply)
+		return recorder.Code, reply
+	}
+
+	if code, _ := request("GET", "/v1/jobs/1", "", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", code)
+	}
+	if code, _ := request("GET", "/v1/jobs/1", "wrong", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong token, got %d", code)
+	}
+	if code, _ := request("POST", "/v1/jobs", "secret", `{"paths":["/etc/passwd"]}`); code != http.StatusForbidden {
+		t.Errorf("Expected 403 outside the roots, got %d", code)
+	}
+
+	// Nothing works this queue, so the job stays queued until canceled
+	queue := daemonJobs
+	daemonJobs = make(chan *daemonJob, 1)
+	defer func() { daemonJobs = queue }()
+	path := filepath.Join(api.roots[0], "a.txt")
+	code, reply := request("POST", "/v1/jobs", "secret", `{"paths":["`+filepath.ToSlash(path)+`"]}`)
+	if code != http.StatusAccepted || reply["status"] != "queued" {
+		t.Fatalf("Expected the job to be queued, got %d %v", code, reply)
+	}
+	job := <-daemonJobs
+	jobPath := fmt.Sprintf("/v1/jobs/%d", job.id)
+	if code, reply := request("DELETE", jobPath, "secret", ""); code != http.StatusOK || reply["status"] != "canceled" {
+		t.Errorf("Expected the job to be canceled, got %d %v", code, reply)
+	}
+	if code, _ := request("DELETE", jobPath, "secret", ""); code != http.StatusConflict {
+		t.Errorf("Expected 409 canceling twice, got %d", code)
+	}
+	if reply := <-job.done; reply.Status != "canceled" {
+		t.Errorf("Expected a waiting submitter to learn of the cancel, got %+v", reply)
+	}
+	if code, _ := request("GET", "/v1/jobs/999999", "secret", ""); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown job, got %d", code)
+	}
+}
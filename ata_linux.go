@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	sgIO         = 0x2285
+	sgDxferNone  = -1
+	sgDxferToDev = -2
+	sgDxferFrom  = -3
+
+	ataPassThrough16 = 0x85
+	ataProtoNonData  = 3
+	ataProtoPioIn    = 4
+	ataProtoPioOut   = 5
+
+	ataIdentify            = 0xec
+	ataSecuritySetPassword = 0xf1
+	ataSecurityErasePrep   = 0xf3
+	ataSecurityEraseUnit   = 0xf4
+
+	// IDENTIFY word 128
+	ataSecuritySupported = 1 << 0
+	ataSecurityEnabled   = 1 << 1
+	ataSecurityLocked    = 1 << 2
+	ataSecurityFrozen    = 1 << 3
+	ataSecurityEnhanced  = 1 << 5
+
+	// Temporary user password, cleared again by the erase itself
+	ataErasePassword = "wipefile"
+)
+
+// sgIoHdr is struct sg_io_hdr from <scsi/sg.h>
+type sgIoHdr struct {
+	InterfaceID    int32
+	DxferDirection int32
+	CmdLen         uint8
+	MxSbLen        uint8
+	IovecCount     uint16
+	DxferLen       uint32
+	Dxferp         uintptr
+	Cmdp           uintptr
+	Sbp            uintptr
+	Timeout        uint32
+	Flags          uint32
+	PackID         int32
+	UsrPtr         uintptr
+	Status         uint8
+	MaskedStatus   uint8
+	MsgStatus      uint8
+	SbLenWr        uint8
+	HostStatus     uint16
+	DriverStatus   uint16
+	Resid          int32
+	Duration       uint32
+	Info           uint32
+}
+
+// ataCommand sends command to the drive through SCSI ATA PASS-THROUGH(16),
+// which works for SATA disks behind libata and most SAT bridges.
+func ataCommand(device *os.File, command uint8, protocol int, data []byte, timeout time.Duration) error {
+	var cdb [16]byte
+	cdb[0] = ataPassThrough16
+	cdb[1] = byte(protocol << 1)
+	direction := int32(sgDxferNone)
+	switch protocol {
+	case ataProtoPioIn:
+		cdb[2] = 0x0e // T_DIR in, BYT_BLOK, length in sector count
+		direction = sgDxferFrom
+	case ataProtoPioOut:
+		cdb[2] = 0x06 // T_DIR out, BYT_BLOK, length in sector count
+		direction = sgDxferToDev
+	}
+	if data != nil {
+		cdb[6] = byte(len(data) / 512)
+	}
+	cdb[14] = command
+
+	var sense [32]byte
+	hdr := sgIoHdr{
+		InterfaceID:    'S',
+		DxferDirection: direction,
+		CmdLen:         uint8(len(cdb)),
+		MxSbLen:        uint8(len(sense)),
+		Cmdp:           uintptr(unsafe.Pointer(&cdb[0])),
+		Sbp:            uintptr(unsafe.Pointer(&sense[0])),
+		Timeout:        uint32(timeout / time.Millisecond),
+	}
+	if data != nil {
+		hdr.DxferLen = uint32(len(data))
+		hdr.Dxferp = uintptr(unsafe.Pointer(&data[0]))
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, device.Fd(), sgIO, uintptr(unsafe.Pointer(&hdr))); errno != 0 {
+		return errno
+	}
+	if hdr.HostStatus != 0 {
+		return fmt.Errorf("ATA command 0x%02x: host status 0x%x", command, hdr.HostStatus)
+	}
+	if hdr.Status != 0 {
+		// Descriptor sense with an ATA status return descriptor
+		if sense[0]&0x7f == 0x72 && sense[8] == 0x09 && sense[8+13]&0x01 != 0 {
+			return fmt.Errorf("ATA command 0x%02x aborted by drive (error 0x%02x)", command, sense[8+3])
+		}
+		return fmt.Errorf("ATA command 0x%02x: SCSI status 0x%x", command, hdr.Status)
+	}
+	return nil
+}
+
+// ataIdentifyWords returns the 256 IDENTIFY DEVICE words of the drive.
+func ataIdentifyWords(device *os.File) ([]uint16, error) {
+	data := make([]byte, 512)
+	if err := ataCommand(device, ataIdentify, ataProtoPioIn, data, 10*time.Second); err != nil {
+		return nil, err
+	}
+	words := make([]uint16, 256)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint16(data[i*2:])
+	}
+	return words, nil
+}
+
+// ataSecureErase runs the ATA security erase sequence on devicePath: set a
+// temporary user password, prepare and erase the unit, then check that the
+// drive came back with security disabled.
+func ataSecureErase(devicePath string, enhanced bool) error {
+	device, err := os.OpenFile(devicePath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer device.Close()
+
+	words, err := ataIdentifyWords(device)
+	if err != nil {
+		return err
+	}
+	security := words[128]
+	switch {
+	case security&ataSecuritySupported == 0:
+		return errors.New("drive does not support the ATA security feature set")
+	case security&ataSecurityFrozen != 0:
+		return errors.New("drive security is frozen (suspend and resume the machine, then retry)")
+	case security&ataSecurityLocked != 0:
+		return errors.New("drive is locked with an unknown password")
+	case security&ataSecurityEnabled != 0:
+		return errors.New("drive already has a user password set")
+	case enhanced && security&ataSecurityEnhanced == 0:
+		return errors.New("drive does not support enhanced security erase")
+	}
+
+	// Words 89/90 give the erase time in units of 2 minutes
+	estimate := words[89]
+	if enhanced {
+		estimate = words[90]
+	}
+	timeout := 12 * time.Hour
+	if estimate != 0 && estimate < 255 {
+		// Allow twice the estimate, drives are optimistic
+		timeout = time.Duration(estimate)*4*time.Minute + 10*time.Minute
+	}
+
+	password := make([]byte, 512)
+	copy(password[2:34], ataErasePassword)
+	if err := ataCommand(device, ataSecuritySetPassword, ataProtoPioOut, password, 30*time.Second); err != nil {
+		return fmt.Errorf("set password: %w", err)
+	}
+
+	if err := ataCommand(device, ataSecurityErasePrep, ataProtoNonData, nil, 30*time.Second); err != nil {
+		return fmt.Errorf("erase prepare: %w", err)
+	}
+	if enhanced {
+		password[0] = 0x02
+	}
+	printVerbose(verboseActions, "erasing '%s', drive estimates %d minutes\n", devicePath, int(estimate)*2)
+	if err := ataCommand(device, ataSecurityEraseUnit, ataProtoPioOut, password, timeout); err != nil {
+		return fmt.Errorf("erase unit: %w (the temporary password is '%s')", err, ataErasePassword)
+	}
+
+	words, err = ataIdentifyWords(device)
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+	if words[128]&ataSecurityEnabled != 0 {
+		return fmt.Errorf("verify: drive still has security enabled (the temporary password is '%s')", ataErasePassword)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// ATA pass-through needs the Linux SG_IO interface.
+func ataSecureErase(devicePath string, enhanced bool) error {
+	return errors.New("only supported on Linux")
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// fileAccessTime falls back to the modification time where the access
+// time isn't looked up.
+func fileAccessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditGenesis is the previous hash of the first entry of an audit log
+var auditGenesis = strings.Repeat("0", 64)
+
+// auditEntry is one line of the -audit-log. Prev is the SHA-256 of the
+// previous line, so editing, removing or reordering entries breaks the
+// chain.
+type auditEntry struct {
+	Seq      int64  `json:"seq"`
+	Path     string `json:"path"`
+	Bytes    *int64 `json:"bytes,omitempty"`
+	Passes   int    `json:"passes"`
+	Started  string `json:"started,omitempty"`
+	Finished string `json:"finished"`
+	Result   string `json:"result"`
+	Error    string `json:"error,omitempty"`
+	Prev     string `json:"prev"`
+}
+
+// auditLog is the -audit-log, nil file without it
+var auditLog struct {
+	sync.Mutex
+	file *os.File
+	seq  int64
+	prev string
+}
+
+// openAuditLog opens path for appending entries, continuing the chain of
+// the entries already in it
+func openAuditLog(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	auditLog.prev = auditGenesis
+	auditLog.seq = 0
+	if last := lastLine(content); last != nil {
+		var entry auditEntry
+		if err := json.Unmarshal(last, &entry); err != nil {
+			file.Close()
+			return fmt.Errorf("last entry is damaged: %w", err)
+		}
+		auditLog.prev = hashLine(last)
+		auditLog.seq = entry.Seq
+	}
+	auditLog.file = file
+	return nil
+}
+
+// closeAuditLog flushes the audit log to disk and closes it
+func closeAuditLog() {
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if auditLog.file == nil {
+		return
+	}
+	if err := auditLog.file.Sync(); err != nil {
+		printError("cannot sync audit log: %s\n", getSimpleError(err))
+	}
+	auditLog.file.Close()
+	auditLog.file = nil
+}
+
+// auditResult appends what happened to a target to the audit log. size is
+// negative if unknown, started zero if the wipe never started.
+func auditResult(path string, size int64, result string, err error, started time.Time) {
+	if auditLog.file == nil {
+		return
+	}
+	entry := auditEntry{Path: path, Result: result, Finished: time.Now().UTC().Format(time.RFC3339Nano)}
+	if size >= 0 {
+		entry.Bytes = &size
+		if size > 0 {
+			entry.Passes = 1
+		}
+	}
+	if !started.IsZero() {
+		entry.Started = started.UTC().Format(time.RFC3339Nano)
+	}
+	if err != nil {
+		entry.Error = getSimpleError(err)
+	}
+
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if auditLog.file == nil {
+		return
+	}
+	entry.Seq = auditLog.seq + 1
+	entry.Prev = auditLog.prev
+	line, _ := json.Marshal(entry)
+	if _, err := auditLog.file.Write(append(line, '\n')); err != nil {
+		printError("cannot write audit log: %s\n", getSimpleError(err))
+		return
+	}
+	auditLog.seq = entry.Seq
+	auditLog.prev = hashLine(line)
+}
+
+// verifyAuditLog checks the chain of the audit log at path, returning the
+// number of entries and the hash of the last one. The last hash should be
+// kept elsewhere: only it shows the newest entries weren't cut off.
+func verifyAuditLog(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	prev := auditGenesis
+	var seq int64
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var entry auditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return seq, "", fmt.Errorf("entry %d is not valid JSON: %w", seq+1, err)
+		}
+		if entry.Seq != seq+1 {
+			return seq, "", fmt.Errorf("entry %d has sequence number %d", seq+1, entry.Seq)
+		}
+		if entry.Prev != prev {
+			return seq, "", fmt.Errorf("entry %d doesn't match the hash of the entry before it", seq+1)
+		}
+		prev = hashLine(line)
+		seq = entry.Seq
+	}
+	return seq, prev, scanner.Err()
+}
+
+// runAuditVerify prints whether the audit log at path is intact, and exits
+// 1 if it isn't
+func runAuditVerify(path string) {
+	entries, last, err := verifyAuditLog(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: audit log '%s' is broken: %s\n", path, getSimpleError(err))
+		os.Exit(1)
+	}
+	fmt.Printf("audit log '%s': %d entries, chain intact, last hash %s\n", path, entries, last)
+}
+
+// hashLine returns the hex SHA-256 of an audit log line without its newline
+func hashLine(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// lastLine returns the last non-empty line of content, or nil
+func lastLine(content []byte) []byte {
+	content = bytes.TrimRight(content, "\n")
+	if len(content) == 0 {
+		return nil
+	}
+	return content[bytes.LastIndexByte(content, '\n')+1:]
+}
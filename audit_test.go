@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAuditLog tests that audit entries chain across runs, and that
+// editing an entry breaks the chain
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for run := 0; run < 2; run++ {
+		if err := openAuditLog(path); err != nil {
+			t.Fatalf("Failed to open audit log: %v", err)
+		}
+		auditResult("/tmp/a.txt", 4096, "wiped", nil, time.Now())
+		auditResult("/tmp/b.txt", -1, "denied", os.ErrPermission, time.Time{})
+		closeAuditLog()
+	}
+
+	entries, last, err := verifyAuditLog(path)
+	if err != nil {
+		t.Fatalf("Expected an intact chain: %v", err)
+	}
+	if entries != 4 || len(last) != 64 {
+		t.Errorf("Expected 4 entries and a SHA-256, got %d and %q", entries, last)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(content), "\n")
+	if !strings.Contains(lines[0], `"bytes":4096,"passes":1,"started":`) || !strings.Contains(lines[0], `"prev":"`+auditGenesis+`"`) {
+		t.Errorf("Unexpected first entry: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"passes":0,"finished":`) || !strings.Contains(lines[1], `"error":"Permission denied"`) {
+		t.Errorf("Unexpected entry for a failure: %s", lines[1])
+	}
+
+	tampered := strings.Replace(string(content), `"result":"denied"`, `"result":"wiped"`, 1)
+	if err := os.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := verifyAuditLog(path); err == nil || !strings.Contains(err.Error(), "entry 3") {
+		t.Errorf("Expected the edit to break the chain at entry 3, got %v", err)
+	}
+}
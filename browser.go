@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// browserInstall is where a browser keeps its profiles and their caches
+type browserInstall struct {
+	name     string // firefox, chrome, chromium, edge or brave
+	title    string
+	firefox  bool   // Firefox profiles, otherwise Chromium's
+	dataDir  string // Firefox: the dir of the profiles; Chromium: the User Data dir
+	cacheDir string // The dir of the profiles' caches, by profile name
+}
+
+// browserNames are the options of -preset browser
+var browserNames = []string{"firefox", "chrome", "chromium", "edge", "brave"}
+
+// Firefox keeps form history in formhistory.sqlite, and browsing and
+// download history in places.sqlite, along with the bookmarks it restores
+// from bookmarkbackups when the database is gone. downloads.sqlite is
+// from before Firefox 20.
+var firefoxFiles = []string{"formhistory.sqlite", "places.sqlite", "downloads.sqlite"}
+
+// Chromium keeps browsing and download history in History, and form
+// autofill entries in Web Data
+var chromiumFiles = []string{"History", "Web Data"}
+
+// chromiumCaches are the cache dirs of a Chromium profile; all of a
+// Firefox profile's cache dir is cache
+var chromiumCaches = []string{"Cache", "Code Cache", "GPUCache"}
+
+// sqliteSuffixes are the suffixes of the files SQLite keeps next to a
+// database, which hold recent changes to it
+var sqliteSuffixes = []string{"", "-wal", "-shm", "-journal"}
+
+// profiles returns the profile dirs of the browser
+func (b browserInstall) profiles() []string {
+	var profiles []string
+	if b.firefox {
+		dirs, _ := filepath.Glob(filepath.Join(b.dataDir, "*"))
+		for _, dir := range dirs {
+			if _, err := os.Stat(filepath.Join(dir, "prefs.js")); err == nil {
+				profiles = append(profiles, dir)
+			}
+		}
+		return profiles
+	}
+	if _, err := os.Stat(filepath.Join(b.dataDir, "Default")); err == nil {
+		profiles = append(profiles, filepath.Join(b.dataDir, "Default"))
+	}
+	numbered, _ := filepath.Glob(filepath.Join(b.dataDir, "Profile *"))
+	return append(profiles, numbered...)
+}
+
+// profileRules returns the rules of the history and form databases and
+// the caches of profile
+func (b browserInstall) profileRules(profile string) []wipeRule {
+	source := "-preset browser (" + b.name + ")"
+	files, caches := chromiumFiles, chromiumCaches
+	if b.firefox {
+		files, caches = firefoxFiles, []string{""}
+	}
+	var rules []wipeRule
+	for _, file := range files {
+		for _, suffix := range sqliteSuffixes {
+			rules = append(rules, wipeRule{source: source, path: filepath.Join(profile, file+suffix)})
+		}
+	}
+	for _, cache := range caches {
+		rules = append(rules, wipeRule{source: source, path: filepath.Join(b.cacheDir, filepath.Base(profile), cache)})
+	}
+	return rules
+}
+
+// browserRules returns the rules of -preset browser for the browsers in
+// names, or all of them if names is empty. Profiles of a browser that is
+// running are skipped and counted as failed: it would only write its
+// history back, and could be corrupted by what is wiped under it.
+func browserRules(names []string) []wipeRule {
+	var rules []wipeRule
+	for _, browser := range browserInstalls() {
+		if len(names) > 0 && !containsString(names, browser.name) {
+			continue
+		}
+		if !browser.firefox && chromiumRunning(browser.dataDir) {
+			printError("%s is running, close it to wipe its profiles\n", browser.title)
+			countFailure(browser.dataDir, nil)
+			continue
+		}
+		for _, profile := range browser.profiles() {
+			if browser.firefox && firefoxRunning(profile) {
+				printError("%s is running with '%s', close it to wipe the profile\n", browser.title, filepath.Base(profile))
+				countFailure(profile, nil)
+				continue
+			}
+			printVerbose(verboseDetails, "%s profile '%s'\n", browser.title, profile)
+			rules = append(rules, browser.profileRules(profile)...)
+		}
+	}
+	return rules
+}
+
+// containsString reports whether list has s, ignoring case
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// browserInstalls returns where browsers keep their profiles, in
+// ~/Library/Application Support, and their caches, in ~/Library/Caches
+func browserInstalls() []browserInstall {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	support := filepath.Join(home, "Library", "Application Support")
+	caches := filepath.Join(home, "Library", "Caches")
+	return []browserInstall{
+		{"firefox", "Firefox", true, filepath.Join(support, "Firefox", "Profiles"), filepath.Join(caches, "Firefox", "Profiles")},
+		{"chrome", "Google Chrome", false, filepath.Join(support, "Google", "Chrome"), filepath.Join(caches, "Google", "Chrome")},
+		{"chromium", "Chromium", false, filepath.Join(support, "Chromium"), filepath.Join(caches, "Chromium")},
+		{"edge", "Microsoft Edge", false, filepath.Join(support, "Microsoft Edge"), filepath.Join(caches, "Microsoft Edge")},
+		{"brave", "Brave", false, filepath.Join(support, "BraveSoftware", "Brave-Browser"), filepath.Join(caches, "BraveSoftware", "Brave-Browser")},
+	}
+}
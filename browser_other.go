@@ -0,0 +1,29 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// browserInstalls returns where browsers keep their profiles, in the XDG
+// config dir, and their caches, in the XDG cache dir. Firefox has neither,
+// and the snap of it keeps its own.
+func browserInstalls() []browserInstall {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	config, _ := os.UserConfigDir()
+	cache, _ := os.UserCacheDir()
+	snap := filepath.Join(home, "snap", "firefox", "common")
+	return []browserInstall{
+		{"firefox", "Firefox", true, filepath.Join(home, ".mozilla", "firefox"), filepath.Join(cache, "mozilla", "firefox")},
+		{"firefox", "Firefox", true, filepath.Join(snap, ".mozilla", "firefox"), filepath.Join(snap, ".cache", "mozilla", "firefox")},
+		{"chrome", "Google Chrome", false, filepath.Join(config, "google-chrome"), filepath.Join(cache, "google-chrome")},
+		{"chromium", "Chromium", false, filepath.Join(config, "chromium"), filepath.Join(cache, "chromium")},
+		{"edge", "Microsoft Edge", false, filepath.Join(config, "microsoft-edge"), filepath.Join(cache, "microsoft-edge")},
+		{"brave", "Brave", false, filepath.Join(config, "BraveSoftware", "Brave-Browser"), filepath.Join(cache, "BraveSoftware", "Brave-Browser")},
+	}
+}
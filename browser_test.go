@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeBrowserHome makes a home with a Firefox and a Chrome profile in the
+// XDG layout, and returns it
+func fakeBrowserHome(t *testing.T) string {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("XDG layout only")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+	for _, name := range []string{
+		".mozilla/firefox/ab12.default-release/prefs.js",
+		".mozilla/firefox/ab12.default-release/formhistory.sqlite",
+		".mozilla/firefox/ab12.default-release/places.sqlite",
+		".mozilla/firefox/ab12.default-release/places.sqlite-wal",
+		".mozilla/firefox/ab12.default-release/logins.json",
+		".cache/mozilla/firefox/ab12.default-release/cache2/entries/3F2A",
+		".config/google-chrome/Default/History",
+		".config/google-chrome/Default/Web Data",
+		".config/google-chrome/Default/Bookmarks",
+		".config/google-chrome/Profile 1/History-journal",
+		".cache/google-chrome/Default/Cache/Cache_Data/data_0",
+	} {
+		path := filepath.Join(home, name)
+		os.MkdirAll(filepath.Dir(path), 0700)
+		if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home
+}
+
+// TestBrowserPreset tests which browser history and cache files -preset browser takes, and its options
+func TestBrowserPreset(t *testing.T) {
+	home := fakeBrowserHome(t)
+	relative := func(files []string) []string {
+		for i, file := range files {
+			files[i], _ = filepath.Rel(home, file)
+		}
+		sort.Strings(files)
+		return files
+	}
+
+	files, _, err := presetFiles("browser")
+	want := []string{
+		".cache/google-chrome/Default/Cache/Cache_Data/data_0",
+		".cache/mozilla/firefox/ab12.default-release/cache2/entries/3F2A",
+		".config/google-chrome/Default/History",
+		".config/google-chrome/Default/Web Data",
+		".config/google-chrome/Profile 1/History-journal",
+		".mozilla/firefox/ab12.default-release/formhistory.sqlite",
+		".mozilla/firefox/ab12.default-release/places.sqlite",
+		".mozilla/firefox/ab12.default-release/places.sqlite-wal",
+	}
+	if err != nil || strings.Join(relative(files), "|") != strings.Join(want, "|") {
+		t.Errorf("-preset browser = %q, %v, want %q", files, err, want)
+	}
+
+	files, _, err = presetFiles("browser=firefox,thumbnails")
+	if err != nil || len(files) != 4 {
+		t.Errorf("-preset browser=firefox,thumbnails = %q, %v, want the 4 Firefox files", relative(files), err)
+	}
+
+	for names, message := range map[string]string{
+		"browser=opera":   "unknown option 'opera' of preset 'browser'",
+		"temp=firefox":    "preset 'temp' takes no options",
+		"browser=,chrome": "unknown option ''",
+	} {
+		if _, _, err := presetFiles(names); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("presetFiles(%q): %v, want %s", names, err, message)
+		}
+	}
+}
+
+// TestChromiumRunning tests chromiumRunning against live, stale and foreign SingletonLock links
+func TestChromiumRunning(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows locks the lockfile instead")
+	}
+	dir := t.TempDir()
+	lock := filepath.Join(dir, "SingletonLock")
+	hostname, _ := os.Hostname()
+
+	if chromiumRunning(dir) {
+		t.Errorf("running without a SingletonLock")
+	}
+	os.Symlink(hostname+"-"+strconv.Itoa(os.Getpid()), lock)
+	if !chromiumRunning(dir) {
+		t.Errorf("not running with the SingletonLock of a live process")
+	}
+
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Skip(err)
+	}
+	os.Remove(lock)
+	os.Symlink(hostname+"-"+strconv.Itoa(exited.Process.Pid), lock)
+	if chromiumRunning(dir) {
+		t.Errorf("running with a stale SingletonLock")
+	}
+	os.Remove(lock)
+	os.Symlink("otherhost-1", lock)
+	if !chromiumRunning(dir) {
+		t.Errorf("not running with the SingletonLock of another host")
+	}
+}
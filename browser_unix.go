@@ -0,0 +1,49 @@
+//go:build unix
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// firefoxRunning reports whether a Firefox has profile open, holding a
+// write lock on its .parentlock
+func firefoxRunning(profile string) bool {
+	file, err := os.Open(filepath.Join(profile, ".parentlock"))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	lock := syscall.Flock_t{Type: syscall.F_WRLCK, Whence: io.SeekStart}
+	if err := syscall.FcntlFlock(file.Fd(), syscall.F_GETLK, &lock); err != nil {
+		return false
+	}
+	return lock.Type != syscall.F_UNLCK
+}
+
+// chromiumRunning reports whether a Chromium browser has dataDir open,
+// going by the HOST-PID its SingletonLock points to. A lock of another
+// host, which shares the home dir, counts as running.
+func chromiumRunning(dataDir string) bool {
+	target, err := os.Readlink(filepath.Join(dataDir, "SingletonLock"))
+	if err != nil {
+		return false
+	}
+	split := strings.LastIndex(target, "-")
+	if split < 0 {
+		return false
+	}
+	pid, err := strconv.Atoi(target[split+1:])
+	if err != nil {
+		return false
+	}
+	if hostname, _ := os.Hostname(); target[:split] != hostname {
+		return true
+	}
+	return syscall.Kill(pid, 0) != syscall.ESRCH
+}
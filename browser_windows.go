@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// browserInstalls returns where browsers keep their profiles: Firefox in
+// %APPDATA% with its caches in %LOCALAPPDATA%, the Chromium browsers all
+// in their User Data dir in %LOCALAPPDATA%
+func browserInstalls() []browserInstall {
+	roaming, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	local, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+	chrome := filepath.Join(local, "Google", "Chrome", "User Data")
+	chromium := filepath.Join(local, "Chromium", "User Data")
+	edge := filepath.Join(local, "Microsoft", "Edge", "User Data")
+	brave := filepath.Join(local, "BraveSoftware", "Brave-Browser", "User Data")
+	return []browserInstall{
+		{"firefox", "Firefox", true, filepath.Join(roaming, "Mozilla", "Firefox", "Profiles"), filepath.Join(local, "Mozilla", "Firefox", "Profiles")},
+		{"chrome", "Google Chrome", false, chrome, chrome},
+		{"chromium", "Chromium", false, chromium, chromium},
+		{"edge", "Microsoft Edge", false, edge, edge},
+		{"brave", "Brave", false, brave, brave},
+	}
+}
+
+// firefoxRunning reports whether a Firefox has profile open, which keeps
+// its parent.lock open exclusively
+func firefoxRunning(profile string) bool {
+	return lockedByOther(filepath.Join(profile, "parent.lock"))
+}
+
+// chromiumRunning reports whether a Chromium browser has dataDir open,
+// which keeps its lockfile open exclusively
+func chromiumRunning(dataDir string) bool {
+	return lockedByOther(filepath.Join(dataDir, "lockfile"))
+}
+
+// lockedByOther reports whether another process keeps path open without
+// sharing it
+func lockedByOther(path string) bool {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return isLockedError(err)
+	}
+	file.Close()
+	return false
+}
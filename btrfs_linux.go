@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// btrfsSnapshots lists the snapshots of the subvolume mounted at root, as
+// reported by "btrfs subvolume show" (needs root). Snapshots are located
+// through the mounts of the same filesystem, since their paths are
+// relative to the top-level subvolume.
+func btrfsSnapshots(root string) ([]snapshot, error) {
+	output, err := exec.Command("btrfs", "subvolume", "show", root).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("btrfs: %s", strings.TrimSpace(string(output)))
+	}
+
+	var names []string
+	inSnapshots := false
+	for _, line := range strings.Split(string(output), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "Snapshot(s):") {
+			inSnapshots = true
+			continue
+		}
+		if !inSnapshots {
+			continue
+		}
+		// The list ends at the next "Key: value" line
+		if trimmed == "" || strings.Contains(trimmed, ":") {
+			break
+		}
+		names = append(names, trimmed)
+	}
+
+	mounts := btrfsMounts(root)
+	snapshots := make([]snapshot, 0, len(names))
+	for _, name := range names {
+		snapshots = append(snapshots, snapshot{name: name, dir: btrfsSnapshotDir(name, mounts)})
+	}
+	return snapshots, nil
+}
+
+// btrfsMounts maps the subvolume paths (without leading slash) mounted
+// from the same device as root to their mount points.
+func btrfsMounts(root string) map[string]string {
+	data, err := os.ReadFile("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+	var source, sourceDir string
+	type mount struct{ source, dir, subvol string }
+	var btrfs []mount
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[2] != "btrfs" {
+			continue
+		}
+		m := mount{source: fields[0], dir: fields[1]}
+		for _, option := range strings.Split(fields[3], ",") {
+			if strings.HasPrefix(option, "subvol=") {
+				m.subvol = strings.Trim(strings.TrimPrefix(option, "subvol="), "/")
+			}
+		}
+		// root may be a nested subvolume below the mount point
+		if (root == m.dir || strings.HasPrefix(root, strings.TrimSuffix(m.dir, "/")+"/")) && len(m.dir) >= len(sourceDir) {
+			source, sourceDir = m.source, m.dir
+		}
+		btrfs = append(btrfs, m)
+	}
+
+	mounts := make(map[string]string)
+	for _, m := range btrfs {
+		if m.source == source {
+			mounts[m.subvol] = m.dir
+		}
+	}
+	return mounts
+}
+
+// btrfsSnapshotDir returns where the snapshot at path (relative to the
+// top-level subvolume) can be read, or "" if no mount reaches it.
+func btrfsSnapshotDir(path string, mounts map[string]string) string {
+	for subvol, dir := range mounts {
+		rel := path
+		if subvol != "" {
+			if !strings.HasPrefix(path, subvol+"/") && path != subvol {
+				continue
+			}
+			rel = strings.TrimPrefix(strings.TrimPrefix(path, subvol), "/")
+		}
+		candidate := filepath.Join(dir, rel)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// sharesBlocks reports whether any extent of pathA sits on the same disk
+// blocks as an extent of pathB.
+func sharesBlocks(pathA, pathB string) (bool, error) {
+	var extents [2][]fiemapExtent
+	for i, path := range []string{pathA, pathB} {
+		file, err := os.Open(path)
+		if err != nil {
+			return false, err
+		}
+		extents[i], err = fileExtents(file)
+		file.Close()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for _, a := range extents[0] {
+		for _, b := range extents[1] {
+			if a.Physical < b.Physical+b.Length && b.Physical < a.Physical+a.Length {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
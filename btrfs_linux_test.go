@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestBtrfsSnapshotDir tests locating snapshots through subvolume mounts
+func TestBtrfsSnapshotDir(t *testing.T) {
+	top := t.TempDir()
+	snapshots := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(top, "@snapshots", "home-1"), 0755); err != nil {
+		t.Fatalf("Failed to create snapshot directory: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(snapshots, "home-2"), 0755); err != nil {
+		t.Fatalf("Failed to create snapshot directory: %v", err)
+	}
+
+	topOnly := map[string]string{"": top}
+	if dir := btrfsSnapshotDir("@snapshots/home-1", topOnly); dir != filepath.Join(top, "@snapshots", "home-1") {
+		t.Errorf("Snapshot should be found below the top-level mount, got %q", dir)
+	}
+
+	subvolOnly := map[string]string{"@snapshots": snapshots}
+	if dir := btrfsSnapshotDir("@snapshots/home-2", subvolOnly); dir != filepath.Join(snapshots, "home-2") {
+		t.Errorf("Snapshot should be found below the @snapshots mount, got %q", dir)
+	}
+	if dir := btrfsSnapshotDir("@other/home-3", subvolOnly); dir != "" {
+		t.Errorf("Unmounted snapshot should not be found, got %q", dir)
+	}
+}
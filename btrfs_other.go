@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// btrfs is only supported on Linux.
+func btrfsSnapshots(root string) ([]snapshot, error) {
+	return nil, errors.New("only supported on Linux")
+}
+
+func sharesBlocks(pathA, pathB string) (bool, error) {
+	return false, errors.New("only supported on Linux")
+}
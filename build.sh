@@ -7,16 +7,16 @@ echo "Building wipefile for common platforms..."
 mkdir -p builds
 
 # Linux AMD64
-GOOS=linux GOARCH=amd64 go build -ldflags="-w -s" -trimpath -o builds/wipefile-linux-amd64 main.go
+GOOS=linux GOARCH=amd64 go build -ldflags="-w -s" -trimpath -o builds/wipefile-linux-amd64 .
 
 # Windows AMD64
-GOOS=windows GOARCH=amd64 go build -ldflags="-w -s" -trimpath -o builds/wipefile-windows-amd64.exe main.go
+GOOS=windows GOARCH=amd64 go build -ldflags="-w -s" -trimpath -o builds/wipefile-windows-amd64.exe .
 
 # macOS AMD64 (Intel)
-GOOS=darwin GOARCH=amd64 go build -ldflags="-w -s" -trimpath -o builds/wipefile-macos-amd64 main.go
+GOOS=darwin GOARCH=amd64 go build -ldflags="-w -s" -trimpath -o builds/wipefile-macos-amd64 .
 
 # macOS ARM64 (Apple Silicon)
-GOOS=darwin GOARCH=arm64 go build -ldflags="-w -s" -trimpath -o builds/wipefile-macos-arm64 main.go
+GOOS=darwin GOARCH=arm64 go build -ldflags="-w -s" -trimpath -o builds/wipefile-macos-arm64 .
 
 echo "Build complete:"
 ls -lh builds/
\ No newline at end of file
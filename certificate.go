@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certificateTarget is what happened to one target, as listed on the
+// certificate
+type certificateTarget struct {
+	Path     string `json:"path"`
+	Bytes    *int64 `json:"bytes,omitempty"`
+	Result   string `json:"result"`
+	Error    string `json:"error,omitempty"`
+	Started  string `json:"started,omitempty"`
+	Finished string `json:"finished"`
+}
+
+// certificate is a certificate of erasure with the fields NIST SP 800-88
+// asks for: what was sanitized, how, whether it was verified, by whom and
+// when
+type certificate struct {
+	ID           string              `json:"id"`
+	Tool         string              `json:"tool"`
+	Host         string              `json:"host"`
+	Operator     string              `json:"operator"`
+	Category     string              `json:"sanitization_category"`
+	Method       string              `json:"method"`
+	Passes       int                 `json:"passes"`
+	Verification string              `json:"verification"`
+	Verified     string              `json:"verification_result"`
+	Started      string              `json:"started"`
+	Finished     string              `json:"finished"`
+	Targets      []certificateTarget `json:"targets"`
+}
+
+// signedCertificate is the -certificate file: the certificate, and the
+// Ed25519 signature over its compact JSON encoding
+type signedCertificate struct {
+	Certificate json.RawMessage `json:"certificate"`
+	Algorithm   string          `json:"signature_algorithm,omitempty"`
+	PublicKey   string          `json:"public_key,omitempty"`
+	Signature   string          `json:"signature,omitempty"`
+}
+
+// certificateVerification describes how wiped targets are verified
+const certificateVerification = "every write and sync of the overwrite completed without error (no read-back sampling)"
+
+// signingKey is the -certificate-key, nil without it
+var signingKey ed25519.PrivateKey
+
+// runStarted is when the run started, for the certificate
+var runStarted = time.Now()
+
+// certificateTargets collects the targets of the run while -certificate
+// is given
+var certificateTargets struct {
+	sync.Mutex
+	enabled bool
+	targets []certificateTarget
+}
+
+// certifyResult adds what happened to a target to the certificate. size is
+// negative if unknown, started zero if the wipe never started.
+func certifyResult(path string, size int64, result string, err error, started time.Time) {
+	certificateTargets.Lock()
+	defer certificateTargets.Unlock()
+	if !certificateTargets.enabled {
+		return
+	}
+	target := certificateTarget{Path: path, Result: result, Finished: time.Now().UTC().Format(time.RFC3339)}
+	if size >= 0 {
+		target.Bytes = &size
+	}
+	if err != nil {
+		target.Error = getSimpleError(err)
+	}
+	if !started.IsZero() {
+		target.Started = started.UTC().Format(time.RFC3339)
+	}
+	certificateTargets.targets = append(certificateTargets.targets, target)
+}
+
+// newCertificate returns the certificate for the targets of the run
+func newCertificate(operator string) certificate {
+	id := make([]byte, 16)
+	rand.Read(id)
+	host, _ := os.Hostname()
+
+	certificateTargets.Lock()
+	targets := append([]certificateTarget{}, certificateTargets.targets...)
+	certificateTargets.Unlock()
+
+	verified := "passed"
+	for _, target := range targets {
+		if target.Result != "wiped" {
+			verified = "failed"
+		}
+	}
+	return certificate{
+		ID:           hex.EncodeToString(id),
+		Tool:         "wipefile v" + version,
+		Host:         host,
+		Operator:     operator,
+		Category:     "Clear",
+		Method:       wipeMethod(),
+		Passes:       1,
+		Verification: certificateVerification,
+		Verified:     verified,
+		Started:      runStarted.UTC().Format(time.RFC3339),
+		Finished:     time.Now().UTC().Format(time.RFC3339),
+		Targets:      targets,
+	}
+}
+
+// wipeMethod describes how the targets of the run are wiped
+func wipeMethod() string {
+	if *deviceMode {
+		return "Overwrite of the entire device with fake file data"
+	}
+	return "Overwrite with fake file data, then rename and delete"
+}
+
+// currentOperator returns the name of the user running wipefile, the
+// default of -operator
+func currentOperator() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return ""
+}
+
+// loadSigningKey reads an Ed25519 private key in PKCS #8 PEM, as written by
+// openssl genpkey -algorithm ed25519
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signingKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an Ed25519 key")
+	}
+	return signingKey, nil
+}
+
+// signCertificate encodes cert, signed with key unless it's nil
+func signCertificate(cert certificate, key ed25519.PrivateKey) ([]byte, error) {
+	body, err := json.Marshal(cert)
+	if err != nil {
+		return nil, err
+	}
+	signed := signedCertificate{Certificate: body}
+	if key != nil {
+		signed.Algorithm = "ed25519"
+		signed.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+		signed.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, body))
+	}
+	return json.MarshalIndent(signed, "", "  ")
+}
+
+// verifyCertificate checks the signature of an encoded certificate,
+// returning the certificate and the public key it was signed with
+func verifyCertificate(data []byte) (certificate, string, error) {
+	var signed signedCertificate
+	var cert certificate
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return cert, "", err
+	}
+	if err := json.Unmarshal(signed.Certificate, &cert); err != nil {
+		return cert, "", err
+	}
+	if signed.Signature == "" {
+		return cert, "", errors.New("certificate is not signed")
+	}
+	if signed.Algorithm != "ed25519" {
+		return cert, "", fmt.Errorf("unknown signature algorithm '%s'", signed.Algorithm)
+	}
+	publicKey, err := base64.StdEncoding.DecodeString(signed.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return cert, "", errors.New("invalid public key")
+	}
+	// The certificate was signed before it was indented in the file
+	var body bytes.Buffer
+	if err := json.Compact(&body, signed.Certificate); err != nil {
+		return cert, "", err
+	}
+	signature, err := base64.StdEncoding.DecodeString(signed.Signature)
+	if err != nil || !ed25519.Verify(publicKey, body.Bytes(), signature) {
+		return cert, "", errors.New("signature doesn't match the certificate")
+	}
+	return cert, signed.PublicKey, nil
+}
+
+// formatCertificate returns cert as printable text
+func formatCertificate(cert certificate) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "CERTIFICATE OF ERASURE\n\n")
+	fmt.Fprintf(&text, "Certificate ID:        %s\n", cert.ID)
+	fmt.Fprintf(&text, "Tool:                  %s\n", cert.Tool)
+	fmt.Fprintf(&text, "Host:                  %s\n", cert.Host)
+	fmt.Fprintf(&text, "Operator:              %s\n", cert.Operator)
+	fmt.Fprintf(&text, "Sanitization category: %s (NIST SP 800-88)\n", cert.Category)
+	fmt.Fprintf(&text, "Method:                %s, %d pass\n", cert.Method, cert.Passes)
+	fmt.Fprintf(&text, "Verification:          %s\n", cert.Verification)
+	fmt.Fprintf(&text, "Verification result:   %s\n", cert.Verified)
+	fmt.Fprintf(&text, "Started:               %s\n", cert.Started)
+	fmt.Fprintf(&text, "Finished:              %s\n", cert.Finished)
+	fmt.Fprintf(&text, "\nTargets (%d):\n", len(cert.Targets))
+	for _, target := range cert.Targets {
+		fmt.Fprintf(&text, "  %s: %s", target.Path, target.Result)
+		if target.Bytes != nil {
+			fmt.Fprintf(&text, ", %d bytes", *target.Bytes)
+		}
+		if target.Error != "" {
+			fmt.Fprintf(&text, " (%s)", target.Error)
+		}
+		fmt.Fprintf(&text, ", finished %s\n", target.Finished)
+	}
+	return text.String()
+}
+
+// certificateTextPath returns where the printable text of the certificate
+// at path goes: erasure.json gets erasure.txt
+func certificateTextPath(path string) string {
+	textPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".txt"
+	if textPath == path {
+		textPath = path + ".txt"
+	}
+	return textPath
+}
+
+// writeCertificate writes the -certificate and its printable text, signed
+// with the -certificate-key if given
+func writeCertificate() {
+	if *certPath == "" {
+		return
+	}
+	if signingKey == nil {
+		printWarning("certificate is not signed, give -certificate-key to sign it\n")
+	}
+
+	cert := newCertificate(*operator)
+	data, err := signCertificate(cert, signingKey)
+	if err == nil {
+		err = os.WriteFile(*certPath, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		printError("cannot write certificate '%s': %s\n", *certPath, getSimpleError(err))
+		return
+	}
+	textPath := certificateTextPath(*certPath)
+	if err := os.WriteFile(textPath, []byte(formatCertificate(cert)), 0644); err != nil {
+		printError("cannot write certificate '%s': %s\n", textPath, getSimpleError(err))
+		return
+	}
+	printStatus("wrote certificate of erasure '%s' and '%s'\n", *certPath, textPath)
+}
+
+// loadPublicKey returns the base64 of the Ed25519 public key in the PKIX
+// PEM file at value, or in value itself as printed by -certificate-verify
+func loadPublicKey(value string) (string, error) {
+	publicKey, err := base64.StdEncoding.DecodeString(value)
+	if err == nil && len(publicKey) == ed25519.PublicKeySize {
+		return value, nil
+	}
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", errors.New("no PEM data found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	verifyKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return "", errors.New("not an Ed25519 key")
+	}
+	return base64.StdEncoding.EncodeToString(verifyKey), nil
+}
+
+// runCertificateVerify prints whether the signature of the certificate at
+// path is valid, and exits 1 if it isn't. The key the certificate carries
+// proves nothing by itself, as anyone can sign with a key of their own, so
+// it has to be expected, or compared with the signer's key out of band.
+func runCertificateVerify(path, expectedKey string) {
+	if expectedKey != "" {
+		key, err := loadPublicKey(expectedKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot load public key '%s': %s\n", expectedKey, getSimpleError(err))
+			os.Exit(1)
+		}
+		expectedKey = key
+	}
+
+	data, err := os.ReadFile(path)
+	var cert certificate
+	var publicKey string
+	if err == nil {
+		cert, publicKey, err = verifyCertificate(data)
+	}
+	if err == nil && expectedKey != "" && publicKey != expectedKey {
+		err = fmt.Errorf("signed with public key %s, not the expected one", publicKey)
+	}
+	if err == nil {
+		fmt.Printf("certificate '%s' (%s): signature valid, public key %s\n", path, cert.ID, publicKey)
+		if expectedKey == "" {
+			fmt.Printf("the key is the one in the certificate: compare it with the signer's key out of band, or give -certificate-public-key\n")
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: certificate '%s': %s\n", path, getSimpleError(err))
+	os.Exit(1)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCertificate tests that the certificate lists the targets, and that
+// its signature survives encoding but not editing
+func TestCertificate(t *testing.T) {
+	certificateTargets.enabled = true
+	defer func() {
+		certificateTargets.enabled = false
+		certificateTargets.targets = nil
+	}()
+	certifyResult("/tmp/a.txt", 4096, "wiped", nil, time.Now())
+	certifyResult("/tmp/b.txt", -1, "missing", nil, time.Time{})
+
+	cert := newCertificate("operator")
+	if cert.Verified != "failed" || len(cert.Targets) != 2 || cert.Operator != "operator" {
+		t.Errorf("Unexpected certificate: %+v", cert)
+	}
+	text := formatCertificate(cert)
+	if !strings.Contains(text, "  /tmp/a.txt: wiped, 4096 bytes, finished ") || !strings.Contains(text, "Operator:              operator\n") {
+		t.Errorf("Unexpected certificate text:\n%s", text)
+	}
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := signCertificate(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified, _, err := verifyCertificate(data)
+	if err != nil {
+		t.Fatalf("Expected a valid signature: %v", err)
+	}
+	if verified.ID != cert.ID || len(verified.Targets) != 2 {
+		t.Errorf("Expected the signed certificate back, got %+v", verified)
+	}
+
+	tampered := strings.Replace(string(data), `"missing"`, `"wiped"`, 1)
+	if _, _, err := verifyCertificate([]byte(tampered)); err == nil {
+		t.Errorf("Expected an edited certificate to fail verification")
+	}
+	unsigned, _ := signCertificate(cert, nil)
+	if _, _, err := verifyCertificate(unsigned); err == nil {
+		t.Errorf("Expected an unsigned certificate to fail verification")
+	}
+}
+
+// TestCertificateTextPath tests where the printable certificate goes
+func TestCertificateTextPath(t *testing.T) {
+	for path, expected := range map[string]string{
+		"erasure.json": "erasure.txt",
+		"erasure":      "erasure.txt",
+		"erasure.txt":  "erasure.txt.txt",
+	} {
+		if got := certificateTextPath(path); got != expected {
+			t.Errorf("certificateTextPath(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
+
+// TestLoadPublicKey tests reading the expected key from PEM and base64
+func TestLoadPublicKey(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemFile := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(pemFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := base64.StdEncoding.EncodeToString(publicKey)
+	for _, value := range []string{pemFile, expected} {
+		if key, err := loadPublicKey(value); err != nil || key != expected {
+			t.Errorf("loadPublicKey(%q) = %q, %v, want %q", value, key, err, expected)
+		}
+	}
+	if _, err := loadPublicKey("c2hvcnQ="); err == nil {
+		t.Error("Expected a short key to be refused")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// checkpoint records how far the overwrite of a large file got, so an
+// interrupted run can continue there instead of starting over. The path
+// isn't stored, the file is known by its device, inode and size instead,
+// and by a hash of its first block, which the overwrite has replaced by the
+// time the first checkpoint is saved. A device is known by the serial
+// number or WWN of its disk instead of an inode.
+type checkpoint struct {
+	Dev     uint64    `json:"dev"`
+	Inode   uint64    `json:"inode"`
+	Serial  string    `json:"serial,omitempty"`
+	ModTime time.Time `json:"mtime"`
+	Size    int64     `json:"size"`
+	Head    string    `json:"head"`
+	Pass    int       `json:"pass"`
+	Offset  int64     `json:"offset"`
+	Updated time.Time `json:"updated"`
+}
+
+var errNoDeviceSerial = errors.New("No serial number or WWN to know the device by")
+
+// fileCheckpoint returns a checkpoint with the identity of the file at
+// path. Device nodes are created anew at every boot, and another disk of
+// the same size can take the same path, so a device is known by its serial
+// number or WWN, and without one has no checkpoint.
+func fileCheckpoint(path string) (checkpoint, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return checkpoint{}, err
+	}
+	if info.Mode()&os.ModeDevice != 0 {
+		serial := deviceSerial(path)
+		if serial == "" {
+			return checkpoint{}, errNoDeviceSerial
+		}
+		return checkpoint{Serial: serial}, nil
+	}
+	id, err := fileIdentity(path)
+	if err != nil {
+		return checkpoint{}, err
+	}
+	return checkpoint{Dev: id.dev, Inode: id.ino, ModTime: info.ModTime()}, nil
+}
+
+// headHash returns the SHA-256 of the first checkpointHeadSize bytes of
+// the file at path.
+func headHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.CopyN(hash, file, checkpointHeadSize); err != nil && err != io.EOF {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// checkpointFile returns where the state of path is stored. The file name
+// is a hash, so the state directory doesn't list what is being wiped.
+func checkpointFile(path string) string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path
+	}
+	sum := sha256.Sum256([]byte(absPath))
+	return filepath.Join(cacheDir, "wipefile", "state", hex.EncodeToString(sum[:])+".json")
+}
+
+// loadCheckpoint returns the offset to resume the overwrite of path at, or
+// 0 if there is no state for this file. A file replaced since has another
+// inode, and one restored from a backup an older modification time. The
+// interrupted overwrite itself changed the modification time after the
+// checkpoint was saved, so a later one still matches. A file restored in
+// place keeps its inode and gets a newer modification time, but has its
+// original first block back, which no longer matches the hash.
+func loadCheckpoint(path string, size int64) int64 {
+	data, err := os.ReadFile(checkpointFile(path))
+	if err != nil {
+		return 0
+	}
+	var state checkpoint
+	if err := json.Unmarshal(data, &state); err != nil || state.Size != size {
+		return 0
+	}
+	current, err := fileCheckpoint(path)
+	if err != nil || current.Dev != state.Dev || current.Inode != state.Inode || current.Serial != state.Serial || current.ModTime.Before(state.ModTime) {
+		return 0
+	}
+	if state.Offset < 0 || state.Offset > size {
+		return 0
+	}
+	if head, err := headHash(path); err != nil || state.Head == "" || head != state.Head {
+		return 0
+	}
+	return state.Offset
+}
+
+func saveCheckpoint(path string, size, offset int64) error {
+	stateFile := checkpointFile(path)
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0700); err != nil {
+		return err
+	}
+
+	state, err := fileCheckpoint(path)
+	if err != nil {
+		return err
+	}
+	if state.Head, err = headHash(path); err != nil {
+		return err
+	}
+	state.Size, state.Pass, state.Offset, state.Updated = size, 1, offset, time.Now()
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	// Write and rename, so a crash never leaves a half-written state file
+	tempFile := stateFile + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, stateFile)
+}
+
+func removeCheckpoint(path string) {
+	os.Remove(checkpointFile(path))
+}
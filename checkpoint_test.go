@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCheckpointRoundTrip tests saving, loading and removing overwrite state
+func TestCheckpointRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	target := filepath.Join(t.TempDir(), "large.img")
+	if err := os.WriteFile(target, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if offset := loadCheckpoint(target, 1000); offset != 0 {
+		t.Errorf("Missing checkpoint should resume at 0, got %d", offset)
+	}
+
+	if err := saveCheckpoint(target, 1000, 600); err != nil {
+		t.Fatalf("saveCheckpoint failed: %v", err)
+	}
+	if offset := loadCheckpoint(target, 1000); offset != 600 {
+		t.Errorf("Checkpoint should resume at 600, got %d", offset)
+	}
+
+	// A file that changed size is not the file the checkpoint was made for
+	if offset := loadCheckpoint(target, 2000); offset != 0 {
+		t.Errorf("Checkpoint for another size should be ignored, got %d", offset)
+	}
+
+	// Nor is one restored in place, which keeps its inode and gets a newer
+	// modification time
+	time.Sleep(10 * time.Millisecond)
+	if err := os.WriteFile(target, []byte("DATA"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if offset := loadCheckpoint(target, 1000); offset != 0 {
+		t.Errorf("Checkpoint for a file restored in place should be ignored, got %d", offset)
+	}
+
+	// Nor is a file replaced by another of the same size, or restored with
+	// an older modification time
+	replacement := target + ".new"
+	if err := os.WriteFile(replacement, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(replacement, target); err != nil {
+		t.Fatal(err)
+	}
+	if offset := loadCheckpoint(target, 1000); offset != 0 {
+		t.Errorf("Checkpoint for a replaced file should be ignored, got %d", offset)
+	}
+	if err := saveCheckpoint(target, 1000, 600); err != nil {
+		t.Fatalf("saveCheckpoint failed: %v", err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(target, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if offset := loadCheckpoint(target, 1000); offset != 0 {
+		t.Errorf("Checkpoint for a restored file should be ignored, got %d", offset)
+	}
+	data, err := os.ReadFile(checkpointFile(target))
+	if err != nil || strings.Contains(string(data), filepath.Base(target)) {
+		t.Errorf("Checkpoint should not store the path, got %s", data)
+	}
+
+	removeCheckpoint(target)
+	if _, err := os.Stat(checkpointFile(target)); !os.IsNotExist(err) {
+		t.Error("Checkpoint file should be removed")
+	}
+}
+
+// TestDeviceCheckpoint tests that a device without a serial number or WWN
+// gets no checkpoint, so a wipe of it never resumes
+func TestDeviceCheckpoint(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	if info, err := os.Stat(os.DevNull); err != nil || info.Mode()&os.ModeDevice == 0 {
+		t.Skip("no null device")
+	}
+	if err := saveCheckpoint(os.DevNull, 1000, 600); err != errNoDeviceSerial {
+		t.Errorf("saveCheckpoint of a device without a serial: %v, want %v", err, errNoDeviceSerial)
+	}
+	if offset := loadCheckpoint(os.DevNull, 1000); offset != 0 {
+		t.Errorf("Device without a serial should resume at 0, got %d", offset)
+	}
+}
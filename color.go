@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// ANSI escape sequences for the colors of terminal output
+const (
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorBold   = "\x1b[1m"
+	colorReset  = "\x1b[0m"
+)
+
+// noColor is set by -no-color
+var noColor bool
+
+// terminalColors records whether stdout and stderr get colors, decided on
+// first use, after the flags are parsed
+var terminalColors struct {
+	once   sync.Once
+	stdout bool
+	stderr bool
+}
+
+// colorAllowed reports whether colors may be used at all: not with
+// -no-color, NO_COLOR (https://no-color.org) or a dumb terminal
+func colorAllowed() bool {
+	return !noColor && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+}
+
+// isTerminal reports whether file is a terminal rather than a pipe or file
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize returns message in color if it goes to a terminal (stdout or
+// stderr) that gets colors, with a trailing newline kept after the reset
+func colorize(output *os.File, color, message string) string {
+	terminalColors.once.Do(func() {
+		if colorAllowed() {
+			terminalColors.stdout = isTerminal(os.Stdout)
+			terminalColors.stderr = isTerminal(os.Stderr)
+		}
+	})
+	colored := (output == os.Stdout && terminalColors.stdout) || (output == os.Stderr && terminalColors.stderr)
+	if !colored {
+		return message
+	}
+	text := strings.TrimSuffix(message, "\n")
+	return color + text + colorReset + message[len(text):]
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// TestColorAllowed tests that -no-color, NO_COLOR and TERM=dumb turn
+// colors off
+func TestColorAllowed(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm-256color")
+	if !colorAllowed() {
+		t.Error("Colors should be allowed by default")
+	}
+
+	noColor = true
+	if colorAllowed() {
+		t.Error("-no-color should turn colors off")
+	}
+	noColor = false
+
+	t.Setenv("NO_COLOR", "1")
+	if colorAllowed() {
+		t.Error("NO_COLOR should turn colors off")
+	}
+	t.Setenv("NO_COLOR", "")
+
+	t.Setenv("TERM", "dumb")
+	if colorAllowed() {
+		t.Error("A dumb terminal should get no colors")
+	}
+}
+
+// TestColorize tests that only terminals that get colors see escape codes
+func TestColorize(t *testing.T) {
+	defer func(stdout, stderr bool) {
+		terminalColors.stdout, terminalColors.stderr = stdout, stderr
+	}(terminalColors.stdout, terminalColors.stderr)
+	terminalColors.once.Do(func() {})
+
+	terminalColors.stdout, terminalColors.stderr = false, true
+	if got := colorize(os.Stdout, colorGreen, "removed 'a'\n"); got != "removed 'a'\n" {
+		t.Errorf("Expected no colors without a terminal, got %q", got)
+	}
+	if got := colorize(os.Stderr, colorRed, "wipefile: cannot open 'a'\n"); got != colorRed+"wipefile: cannot open 'a'"+colorReset+"\n" {
+		t.Errorf("Expected red with the newline after the reset, got %q", got)
+	}
+}
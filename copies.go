@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// copyTarget is a file to be wiped that -find-copies looks for copies of
+type copyTarget struct {
+	path string
+	info fs.FileInfo
+	sum  []byte // Hashed when a file of the same size turns up
+	err  error
+}
+
+// findCopies reports the files below root with the same content as one of
+// files, before these are wiped: backups, exports and copies made by other
+// programs keep what the wipe destroys. Only files of the same size as a
+// target are hashed, and empty files are left out. It returns how many
+// copies it found.
+func findCopies(root string, files []string) int {
+	targets := make(map[int64][]*copyTarget)
+	for _, file := range files {
+		info, err := os.Lstat(file)
+		if err != nil || !info.Mode().IsRegular() || info.Size() == 0 {
+			continue
+		}
+		targets[info.Size()] = append(targets[info.Size()], &copyTarget{path: file, info: info})
+	}
+	if len(targets) == 0 {
+		return 0
+	}
+
+	found := 0
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if entry == nil {
+				return err // The root itself
+			}
+			printVerboseWarning(verboseActions, "-find-copies: skipping '%s': %s\n", path, getSimpleError(err))
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil || len(targets[info.Size()]) == 0 {
+			return nil
+		}
+
+		for _, target := range targets[info.Size()] {
+			if os.SameFile(info, target.info) {
+				return nil // A target itself, or a hard link of one
+			}
+		}
+
+		var sum []byte
+		for _, target := range targets[info.Size()] {
+			if target.sum == nil && target.err == nil {
+				target.sum, target.err = hashFile(target.path)
+			}
+			if target.err != nil {
+				continue
+			}
+			if sum == nil {
+				if sum, err = hashFile(path); err != nil {
+					printVerboseWarning(verboseActions, "-find-copies: skipping '%s': %s\n", path, getSimpleError(err))
+					return nil
+				}
+			}
+			if bytes.Equal(sum, target.sum) {
+				printWarning("'%s' has a copy at '%s', which is not wiped\n", target.path, path)
+				found++
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		printWarning("-find-copies: cannot read '%s': %s\n", root, getSimpleError(err))
+	} else if found == 0 {
+		printStatus("no copies of the targets found below '%s'\n", root)
+	}
+	return found
+}
+
+// hashFile returns the SHA-256 of the contents of path
+func hashFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
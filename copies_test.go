@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFindCopies tests that findCopies counts copies by content, not ones wiped too
+func TestFindCopies(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	secret := write("secret.txt", "the plan")
+	empty := write("empty.txt", "")
+	write("backup/secret.txt.bak", "the plan")
+	write("backup/other.txt", "the plot")
+	write("backup/empty.txt", "")
+	write("exports/2024/plan.txt", "the plan")
+	os.Link(secret, filepath.Join(dir, "backup", "linked.txt"))
+
+	if found := findCopies(dir, []string{secret, empty}); found != 2 {
+		t.Errorf("findCopies found %d copies, want 2", found)
+	}
+
+	// Copies that are wiped too aren't reported
+	both := []string{secret, filepath.Join(dir, "backup", "secret.txt.bak"), filepath.Join(dir, "exports", "2024", "plan.txt")}
+	if found := findCopies(dir, both); found != 0 {
+		t.Errorf("findCopies of all copies found %d, want 0", found)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCronNext tests the next run times of cron expressions
+func TestCronNext(t *testing.T) {
+	// A Friday
+	start := time.Date(2026, 10, 16, 14, 30, 0, 0, time.UTC)
+	for expr, expected := range map[string]time.Time{
+		"* * * * *":       time.Date(2026, 10, 16, 14, 31, 0, 0, time.UTC),
+		"0 3 * * *":       time.Date(2026, 10, 17, 3, 0, 0, 0, time.UTC),
+		"@daily":          time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+		"*/20 * * * *":    time.Date(2026, 10, 16, 14, 40, 0, 0, time.UTC),
+		"0 4 * * sun":     time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC),
+		"0 4 * * 7":       time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC),
+		"0 2 * * mon-fri": time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC),
+		"15 1 1 jan *":    time.Date(2027, 1, 1, 1, 15, 0, 0, time.UTC),
+		"0 0 29 2 *":      time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		"0 12 20 * mon":   time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), // Day or weekday
+		"30 14,16 * * *":  time.Date(2026, 10, 16, 16, 30, 0, 0, time.UTC),
+		"0 9-17/4 * * *":  time.Date(2026, 10, 16, 17, 0, 0, 0, time.UTC),
+		"0 0 30 feb *":    {},
+	} {
+		schedule, err := parseCron(expr)
+		if err != nil {
+			t.Errorf("parseCron(%q) failed: %v", expr, err)
+			continue
+		}
+		if got := schedule.next(start); !got.Equal(expected) {
+			t.Errorf("Next run of %q: expected %s, got %s", expr, expected, got)
+		}
+	}
+}
+
+// TestCronHalfHourZone tests that hours are stepped in local time in zones
+// with half-hour offsets
+func TestCronHalfHourZone(t *testing.T) {
+	india := time.FixedZone("IST", 5*3600+1800)
+	schedule, _ := parseCron("0 3 * * *")
+	expected := time.Date(2026, 10, 17, 3, 0, 0, 0, india)
+	if got := schedule.next(time.Date(2026, 10, 16, 14, 10, 0, 0, india)); !got.Equal(expected) {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+// TestParseCronInvalid tests that malformed expressions are rejected
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "* * * foo *", "@often"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
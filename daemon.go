@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// daemonQueueSize is how many submitted jobs the daemon queues before it
+// turns new ones away
+const daemonQueueSize = 1000
+
+// socketPath is set by -socket
+var socketPath string
+
+// registerSocketFlag adds -socket to flags
+func registerSocketFlag(flags *flag.FlagSet) {
+	flags.StringVar(&socketPath, "socket", defaultSocketPath(), "Control socket of wipefile daemon, which wipefile submit sends jobs to")
+}
+
+// jobRequest is a wipe job sent by wipefile submit
+type jobRequest struct {
+	Paths     []string `json:"paths"`
+	Recursive bool     `json:"recursive,omitempty"`
+	Wait      bool     `json:"wait,omitempty"`
+
+	roots []string // For API jobs, the -api-roots everything wiped has to be below
+}
+
+// jobReply is the daemon's answer to a job request: once when it's queued
+// (or refused), and with the results when it's done if the submitter waits
+type jobReply struct {
+	Job     int64  `json:"job,omitempty"`
+	Status  string `json:"status,omitempty"` // queued, running, done or canceled
+	Done    bool   `json:"done,omitempty"`
+	Wiped   int64  `json:"wiped"`
+	Missing int64  `json:"missing"`
+	Failed  int64  `json:"failed"`
+	Denied  int64  `json:"denied"`
+	Error   string `json:"error,omitempty"`
+}
+
+// daemonJob is a queued job, with where to send its results
+type daemonJob struct {
+	id      int64
+	request jobRequest
+	done    chan jobReply
+	reply   jobReply // Status and, once done, results; guarded by jobRegistry
+}
+
+// maxKeptJobs is how many jobs the daemon remembers for status requests
+const maxKeptJobs = 1000
+
+// jobRegistry holds the jobs of the daemon by number
+var jobRegistry struct {
+	sync.Mutex
+	jobs map[int64]*daemonJob
+}
+
+// Errors of cancelJob
+var (
+	errJobNotFound = errors.New("no such job")
+	errJobRunning  = errors.New("job is already running")
+	errJobFinished = errors.New("job has already finished")
+)
+
+// daemonJobs is the job queue, worked off one job at a time so the wipes of
+// all submitters share the -p workers and the -limit-rate
+var (
+	daemonJobs  = make(chan *daemonJob, daemonQueueSize)
+	lastJobID   atomic.Int64
+	daemonRecur bool // -r given to the daemon, for every job
+)
+
+// runDaemon listens on the -socket, and the -api-addr if given, for jobs
+// until SIGINT or SIGTERM, with the options of the main command applied to
+// every job
+func runDaemon() {
+	listener, err := listenSocket(socketPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot listen on '%s': %s\n", socketPath, getSimpleError(err))
+		os.Exit(1)
+	}
+	if apiAddr != "" {
+		if (apiCert == "") != (apiKey == "") {
+			fmt.Fprintf(os.Stderr, "Error: -api-cert and -api-key go together\n")
+			os.Exit(1)
+		}
+		api, err := newDaemonAPI(apiTokenFile, apiRoots)
+		if err == nil {
+			err = serveAPI(api)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot serve the API: %s\n", getSimpleError(err))
+			os.Exit(1)
+		}
+		if apiCert == "" {
+			printWarning("the API token is sent in plain text without -api-cert\n")
+		}
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		printStatus("stopping daemon\n")
+		listener.Close()
+	}()
+
+	daemonRecur = *recursive
+	go workJobs()
+	printStatus("daemon listening on '%s'\n", socketPath)
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			break
+		} else if err != nil {
+			printError("cannot accept job: %s\n", getSimpleError(err))
+			continue
+		}
+		go handleJobConn(conn)
+	}
+	os.Remove(socketPath)
+}
+
+// listenSocket listens on the unix socket at path, readable and writable
+// only by the daemon's user, since jobs run with the daemon's permissions.
+// A socket left behind by a daemon that died is replaced.
+func listenSocket(path string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, errors.New("another daemon is listening")
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// handleJobConn reads a job from conn and queues it, answering with its
+// number, and with its results once done if the submitter waits
+func handleJobConn(conn net.Conn) {
+	defer conn.Close()
+	encoder := json.NewEncoder(conn)
+
+	var request jobRequest
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&request); err != nil {
+		encoder.Encode(jobReply{Error: "invalid job: " + err.Error()})
+		return
+	}
+	if len(request.Paths) == 0 {
+		encoder.Encode(jobReply{Error: "job has no paths"})
+		return
+	}
+	for _, path := range request.Paths {
+		if !filepath.IsAbs(path) {
+			encoder.Encode(jobReply{Error: fmt.Sprintf("'%s' is not an absolute path", path)})
+			return
+		}
+	}
+
+	job, err := queueJob(request)
+	if err != nil {
+		encoder.Encode(jobReply{Error: err.Error()})
+		return
+	}
+	if err := encoder.Encode(jobReply{Job: job.id, Status: "queued"}); err != nil || !request.Wait {
+		return
+	}
+	encoder.Encode(<-job.done)
+}
+
+// queueJob registers and queues a job for request
+func queueJob(request jobRequest) (*daemonJob, error) {
+	job := &daemonJob{request: request, done: make(chan jobReply, 1)}
+	jobRegistry.Lock()
+	defer jobRegistry.Unlock()
+	job.id = lastJobID.Add(1)
+	job.reply = jobReply{Job: job.id, Status: "queued"}
+	select {
+	case daemonJobs <- job:
+	default:
+		return nil, errors.New("queue is full")
+	}
+	if jobRegistry.jobs == nil {
+		jobRegistry.jobs = make(map[int64]*daemonJob)
+	}
+	jobRegistry.jobs[job.id] = job
+	if old, ok := jobRegistry.jobs[job.id-maxKeptJobs]; ok && (old.reply.Done || old.reply.Status == "canceled") {
+		delete(jobRegistry.jobs, old.id)
+	}
+	metrics.queueDepth.Add(int64(len(request.Paths)))
+	printVerbose(verboseActions, "queued job %d: %d paths\n", job.id, len(request.Paths))
+	return job, nil
+}
+
+// jobStatus returns the status of job id, and whether there is such a job
+func jobStatus(id int64) (jobReply, bool) {
+	jobRegistry.Lock()
+	defer jobRegistry.Unlock()
+	job, ok := jobRegistry.jobs[id]
+	if !ok {
+		return jobReply{}, false
+	}
+	return job.reply, true
+}
+
+// cancelJob cancels job id if it's still queued
+func cancelJob(id int64) error {
+	jobRegistry.Lock()
+	defer jobRegistry.Unlock()
+	job, ok := jobRegistry.jobs[id]
+	switch {
+	case !ok:
+		return errJobNotFound
+	case job.reply.Status == "running":
+		return errJobRunning
+	case job.reply.Status != "queued":
+		return errJobFinished
+	}
+	job.reply.Status = "canceled"
+	job.done <- job.reply
+	printStatus("canceled job %d\n", id)
+	return nil
+}
+
+// setJobReply updates the status of job
+func setJobReply(job *daemonJob, reply jobReply) {
+	jobRegistry.Lock()
+	defer jobRegistry.Unlock()
+	job.reply = reply
+}
+
+// workJobs wipes the queued jobs one after the other, skipping those
+// canceled while they waited
+func workJobs() {
+	for job := range daemonJobs {
+		metrics.queueDepth.Add(-int64(len(job.request.Paths)))
+		jobRegistry.Lock()
+		canceled := job.reply.Status == "canceled"
+		if !canceled {
+			job.reply.Status = "running"
+		}
+		jobRegistry.Unlock()
+		if canceled {
+			continue
+		}
+		reply := runJob(job)
+		setJobReply(job, reply)
+		job.done <- reply
+	}
+}
+
+// runJob wipes the paths of job, returning its results. Jobs run one at a
+// time, so the results are what the run's counts grew by.
+func runJob(job *daemonJob) jobReply {
+	printStatus("starting job %d: %d paths\n", job.id, len(job.request.Paths))
+	wiped, missing := wipeResults.wiped.Load(), wipeResults.missing.Load()
+	failed, denied := wipeResults.failed.Load(), wipeResults.denied.Load()
+
+	*recursive = daemonRecur || job.request.Recursive
+	wipeTargetsBelow(job.request.Paths, job.request.roots)
+
+	reply := jobReply{
+		Job:     job.id,
+		Status:  "done",
+		Done:    true,
+		Wiped:   wipeResults.wiped.Load() - wiped,
+		Missing: wipeResults.missing.Load() - missing,
+		Failed:  wipeResults.failed.Load() - failed,
+		Denied:  wipeResults.denied.Load() - denied,
+	}
+	printStatus("finished job %d: %d wiped, %d failed, %d missing\n", job.id, reply.Wiped, reply.Failed, reply.Missing)
+	return reply
+}
+
+// runSubmitCommand implements "wipefile submit", which sends paths to a
+// running wipefile daemon to wipe
+func runSubmitCommand(args []string) {
+	submitFlags := flag.NewFlagSet("submit", flag.ExitOnError)
+	registerSocketFlag(submitFlags)
+	recursiveJob := submitFlags.Bool("r", false, "Wipe directories recursively")
+	wait := submitFlags.Bool("wait", false, "Wait for the job to finish and exit like wipefile would")
+	quietSubmit := submitFlags.Bool("q", false, "Print nothing but errors")
+	submitFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s submit [options] <path> [path] ...\n", os.Args[0])
+		submitFlags.PrintDefaults()
+	}
+	parseFlags(submitFlags, "submit", args)
+	if submitFlags.NArg() == 0 {
+		submitFlags.Usage()
+		os.Exit(1)
+	}
+
+	// The daemon may run in another directory
+	request := jobRequest{Recursive: *recursiveJob, Wait: *wait}
+	for _, path := range submitFlags.Args() {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		request.Paths = append(request.Paths, absPath)
+	}
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot reach the daemon at '%s': %s\n", socketPath, getSimpleError(err))
+		os.Exit(1)
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot submit job: %s\n", getSimpleError(err))
+		os.Exit(1)
+	}
+
+	decoder := json.NewDecoder(conn)
+	var reply jobReply
+	if err := decoder.Decode(&reply); err != nil || reply.Error != "" {
+		if reply.Error != "" {
+			err = errors.New(reply.Error)
+		}
+		fmt.Fprintf(os.Stderr, "Error: job not queued: %s\n", getSimpleError(err))
+		os.Exit(1)
+	}
+	if !*quietSubmit {
+		fmt.Printf("queued job %d\n", reply.Job)
+	}
+	if !*wait {
+		return
+	}
+
+	if err := decoder.Decode(&reply); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: lost the daemon before job %d finished: %s\n", reply.Job, getSimpleError(err))
+		os.Exit(1)
+	}
+	if reply.Status == "canceled" {
+		fmt.Fprintf(os.Stderr, "Error: job %d was canceled\n", reply.Job)
+		os.Exit(1)
+	}
+	if !*quietSubmit {
+		fmt.Printf("finished job %d: %d wiped, %d failed, %d missing\n", reply.Job, reply.Wiped, reply.Failed, reply.Missing)
+	}
+	os.Exit(exitCodeFor(reply.Wiped, reply.Missing, reply.Failed, reply.Denied))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDaemonJob tests that a submitted job is wiped and its results sent
+// back to a waiting submitter
+func TestDaemonJob(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, too short for t.TempDir
+	dir, err := os.MkdirTemp("", "wipefile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "d.sock")
+	listener, err := listenSocket(socket)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	if info, err := os.Stat(socket); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the socket to be private to its owner, got %v %v", info.Mode(), err)
+	}
+	if _, err := listenSocket(socket); err == nil {
+		t.Errorf("Expected a second daemon to be refused")
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handleJobConn(conn)
+		}
+	}()
+	go workJobs()
+
+	target := filepath.Join(dir, "secret.txt")
+	if err := os.WriteFile(target, []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	json.NewEncoder(conn).Encode(jobRequest{Paths: []string{target, filepath.Join(dir, "missing")}, Wait: true})
+
+	decoder := json.NewDecoder(conn)
+	var queued, done jobReply
+	if err := decoder.Decode(&queued); err != nil || queued.Job == 0 {
+		t.Fatalf("Expected the job to be queued, got %+v %v", queued, err)
+	}
+	if err := decoder.Decode(&done); err != nil || !done.Done {
+		t.Fatalf("Expected the job's results, got %+v %v", done, err)
+	}
+	if done.Wiped != 1 || done.Missing != 1 || done.Failed != 0 {
+		t.Errorf("Expected 1 wiped and 1 missing, got %+v", done)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be wiped", target)
+	}
+
+	relative, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer relative.Close()
+	json.NewEncoder(relative).Encode(jobRequest{Paths: []string{"secret.txt"}})
+	var refused jobReply
+	if err := json.NewDecoder(relative).Decode(&refused); err != nil || refused.Error == "" {
+		t.Errorf("Expected a relative path to be refused, got %+v %v", refused, err)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// defaultSocketPath returns where the daemon listens by default:
+// /run/wipefile.sock for root, the user's runtime directory otherwise
+func defaultSocketPath() string {
+	if os.Geteuid() == 0 {
+		return "/run/wipefile.sock"
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "wipefile.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("wipefile-%d.sock", os.Getuid()))
+}
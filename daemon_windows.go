@@ -0,0 +1,12 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// defaultSocketPath returns where the daemon listens by default, in the
+// user's temp directory. Windows 10 and later have unix sockets.
+func defaultSocketPath() string {
+	return filepath.Join(os.TempDir(), "wipefile.sock")
+}
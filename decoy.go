@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// decoyMaxAge is how far back decoy timestamps go
+const decoyMaxAge = 3 * 365 * 24 * time.Hour
+
+// decoyType is a kind of file a decoy can pass for: the fake header its
+// first 4K starts with, and how such files are usually named
+type decoyType struct {
+	header string
+	name   func(t time.Time) string
+}
+
+var decoyTypes = []decoyType{
+	// .jpg from a phone or camera
+	{"\\ff\\d8\\ff\\e0\\00\\10\\4a\\46\\49\\46\\00\\01\\01\\01\\00%x\\00%x\\00\\00\\ff\\e1\\00\\68\\45\\78\\69\\66\\00\\00", func(t time.Time) string {
+		return fmt.Sprintf("IMG_%s_%s.jpg", t.Format("20060102"), t.Format("150405"))
+	}},
+	{"\\ff\\d8\\ff\\e0\\00\\10\\4a\\46\\49\\46\\00\\01\\01\\00\\00\\01\\00\\01\\00\\00\\ff\\fe\\00\\3b%b{4}", func(t time.Time) string {
+		return fmt.Sprintf("DSC%05d.JPG", rand.Intn(100000))
+	}},
+
+	// .png screenshot
+	{"\\89\\50\\4e\\47\\0d\\0a\\1a\\0a\\00\\00\\00\\0d\\49\\48\\44\\52\\00\\00%x%x\\00\\00%x%x%b", func(t time.Time) string {
+		return fmt.Sprintf("Screenshot %s at %s.png", t.Format("2006-01-02"), t.Format("15.04.05"))
+	}},
+
+	// .mp4
+	{"\\00\\00\\00 ftypisom\\00\\00\\02\\00isomiso2avc1mp4100%x%x%xmoov\\00\\00\\00lmvhd\\00{14}\\03\\e8\\00", func(t time.Time) string {
+		return fmt.Sprintf("VID_%s_%s.mp4", t.Format("20060102"), t.Format("150405"))
+	}},
+
+	// .pdf
+	{"\\25PDF-1.7\\0a1 0 obj\\0a<< /Type /Catalog >>\\0aendobj\\0a2 0 obj\\0a<< /Filter /FlateDecode\\0a/Length %d{5}? >>\\0astream\\0a", func(t time.Time) string {
+		return fmt.Sprintf("Invoice-%d%05d.pdf", t.Year(), rand.Intn(100000))
+	}},
+	{"\\25PDF-1.7\\0a1 0 obj\\0a<< /Type /Catalog >>\\0aendobj\\0a2 0 obj\\0a<< /Filter /FlateDecode\\0a/Length %d{5}? >>\\0astream\\0a", func(t time.Time) string {
+		return fmt.Sprintf("scan_%s.pdf", t.Format("2006-01-02_150405"))
+	}},
+
+	// .zip
+	{"\\50\\4b\\03\\04\\14\\00\\08\\00\\08\\00", func(t time.Time) string {
+		return fmt.Sprintf("backup_%s.zip", t.Format("2006-01-02"))
+	}},
+
+	// .gz
+	{"\\1f\\8b\\08\\08%x{4}\\00\\03", func(t time.Time) string {
+		return fmt.Sprintf("logs-%s.tar.gz", t.Format("20060102"))
+	}},
+
+	// .7z
+	{"7z\\bc\\af\\27\\1c\\00\\04", func(t time.Time) string {
+		return fmt.Sprintf("documents %s.7z", t.Format("Jan 2006"))
+	}},
+}
+
+// parseSizeRange parses a size like "1M" or a range like "1M-50M"
+func parseSizeRange(s string) (int64, int64, error) {
+	low, high, isRange := strings.Cut(s, "-")
+	minSize, err := parseSize(low)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxSize := minSize
+	if isRange {
+		if maxSize, err = parseSize(high); err != nil {
+			return 0, 0, err
+		}
+	}
+	if minSize == 0 || maxSize < minSize {
+		return 0, 0, fmt.Errorf("invalid size range '%s'", s)
+	}
+	return minSize, maxSize, nil
+}
+
+// createDecoy writes a file of size bytes in dir that starts with the fake
+// header of a random decoy type, named and timestamped like such a file
+// would be. It returns the path of the new file.
+func createDecoy(dir string, size int64, fakeData *fakeReader) (string, error) {
+	kind := decoyTypes[rand.Intn(len(decoyTypes))]
+	modTime := time.Now().Add(-time.Duration(rand.Int63n(int64(decoyMaxAge))))
+
+	var file *os.File
+	var filePath string
+	for {
+		filePath = filepath.Join(dir, kind.name(modTime))
+		var err error
+		file, err = os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return "", err
+		}
+		// Taken, try a few seconds later, like the next photo in a burst
+		modTime = modTime.Add(time.Duration(1+rand.Intn(60)) * time.Second)
+	}
+
+	buffer := getBlock()
+	defer putBlock(buffer)
+	written := int64(0)
+	for written < size {
+		length := min64(int64(len(buffer)), size-written)
+		fakeData.Read(buffer[:length])
+		if written == 0 {
+			copy(buffer, generateBuffer(make([]byte, bufferSize), kind.header, newPaddingStream()))
+		}
+		writeLimiter.wait(int(length))
+		n, err := writeAtWithRetry(file, buffer[:length], written)
+		written += int64(n)
+		if err != nil {
+			file.Close()
+			os.Remove(filePath)
+			return "", err
+		}
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(filePath)
+		return "", err
+	}
+
+	// Accessed some time after it was last written
+	accessTime := modTime.Add(time.Duration(rand.Int63n(int64(time.Since(modTime)) + 1)))
+	if err := os.Chtimes(filePath, accessTime, modTime); err != nil {
+		printVerboseWarning(verboseActions, "cannot set times of '%s': %s\n", filePath, getSimpleError(err))
+	}
+	return filePath, nil
+}
+
+// runDecoyCommand implements "wipefile decoy", which fills a directory
+// with plausible-looking files made by the fake-header generator, so the
+// noise around wiped files looks like ordinary user data.
+func runDecoyCommand(args []string) {
+	decoyFlags := flag.NewFlagSet("decoy", flag.ExitOnError)
+	count := decoyFlags.Int("count", 10, "Number of decoy files to create")
+	sizeRange := decoyFlags.String("size", "1M-50M", "Size or size range of each decoy file (e.g. 512K, 1M-50M)")
+	registerOutputFlags(decoyFlags)
+	registerLogFlags(decoyFlags)
+	decoyFlags.BoolVar(quiet, "q", false, "Quiet: print nothing but errors")
+	decoyFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s decoy [options] <dir>\n", os.Args[0])
+		decoyFlags.PrintDefaults()
+	}
+	parseFlags(decoyFlags, "decoy", args)
+
+	if decoyFlags.NArg() != 1 || *count < 1 {
+		decoyFlags.Usage()
+		os.Exit(1)
+	}
+	minSize, maxSize, err := parseSizeRange(*sizeRange)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -size: %s\n", err)
+		os.Exit(1)
+	}
+	dir := decoyFlags.Arg(0)
+	startLog()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		printError("cannot create '%s': %s\n", dir, getSimpleError(err))
+		os.Exit(1)
+	}
+
+	fakeData := newFakeReader()
+	defer fakeData.Close()
+	total := int64(0)
+	for i := 0; i < *count; i++ {
+		size := minSize + rand.Int63n(maxSize-minSize+1)
+		filePath, err := createDecoy(dir, size, fakeData)
+		if err != nil {
+			printError("cannot create decoy in '%s': %s\n", dir, getSimpleError(err))
+			os.Exit(1)
+		}
+		total += size
+		printVerbose(verboseActions, "created %s (%d KB)\n", filepath.Base(filePath), size/1024)
+	}
+	printSummary("created %d decoy files (%d MB) in '%s'\n", *count, total/(1024*1024), dir)
+}
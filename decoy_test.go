@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestParseSizeRange tests parsing single sizes and size ranges
+func TestParseSizeRange(t *testing.T) {
+	minSize, maxSize, err := parseSizeRange("1M-50M")
+	if err != nil || minSize != 1024*1024 || maxSize != 50*1024*1024 {
+		t.Errorf("Expected 1M-50M, got %d-%d (%v)", minSize, maxSize, err)
+	}
+	minSize, maxSize, err = parseSizeRange("512K")
+	if err != nil || minSize != 512*1024 || maxSize != 512*1024 {
+		t.Errorf("Expected 512K-512K, got %d-%d (%v)", minSize, maxSize, err)
+	}
+	for _, invalid := range []string{"", "0", "50M-1M", "1M-", "abc"} {
+		if _, _, err := parseSizeRange(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+// TestCreateDecoy tests that decoys have the requested size and a past timestamp
+func TestCreateDecoy(t *testing.T) {
+	dir := t.TempDir()
+	fakeData := newFakeReader()
+	defer fakeData.Close()
+
+	for _, size := range []int64{100, bufferSize, 3*bufferSize + 17} {
+		filePath, err := createDecoy(dir, size, fakeData)
+		if err != nil {
+			t.Fatalf("createDecoy failed: %v", err)
+		}
+		if filepath.Dir(filePath) != dir || filepath.Ext(filePath) == "" {
+			t.Errorf("Decoy should be a named file in %s, got %s", dir, filePath)
+		}
+		info, err := os.Stat(filePath)
+		if err != nil {
+			t.Fatalf("Failed to stat decoy: %v", err)
+		}
+		if info.Size() != size {
+			t.Errorf("Expected decoy of %d bytes, got %d", size, info.Size())
+		}
+		if age := time.Since(info.ModTime()); age < 0 || age > decoyMaxAge {
+			t.Errorf("Decoy modification time should be in the past %v, got %v ago", decoyMaxAge, age)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// wipeDevice overwrites all of the block device at devicePath, after the
+// user confirms by typing the device path again. Progress is always shown,
+// since a full disk takes hours, and with -checkpoint recorded so an
+// interrupted wipe can resume.
+func wipeDevice(devicePath string) {
+	startTarget(devicePath)
+	info, err := os.Stat(devicePath)
+	if err != nil {
+		printError("cannot access '%s': %s\n", devicePath, getSimpleError(err))
+		if os.IsNotExist(err) {
+			countMissing(devicePath)
+		} else {
+			countFailure(devicePath, err)
+		}
+		return
+	}
+	if info.Mode()&os.ModeDevice == 0 {
+		printError("'%s' is not a device\n", devicePath)
+		countFailure(devicePath, nil)
+		return
+	}
+	if !confirmDevice(devicePath) {
+		countFailure(devicePath, nil)
+		return
+	}
+
+	*showProg = true
+	progressMinSize = 0
+	if *checkpoints {
+		confirmResume(devicePath)
+	}
+
+	printStatus("wiping device: %s\n", devicePath)
+	if !overwriteFile(devicePath) {
+		printError("wiping '%s' failed\n", devicePath)
+		countFailure(devicePath, accessError(devicePath))
+		return
+	}
+	printSummary("wiped device '%s'\n", devicePath)
+	countWiped(devicePath, deviceSize(devicePath))
+}
+
+// deviceSize returns the size of the device at devicePath, or -1 if it
+// can't be told. Devices don't report their size in stat, but can seek to
+// the end.
+func deviceSize(devicePath string) int64 {
+	file, err := os.Open(devicePath)
+	if err != nil {
+		return -1
+	}
+	defer file.Close()
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1
+	}
+	return size
+}
+
+// confirmResume has the user confirm resuming the interrupted wipe of
+// devicePath that its checkpoint records, and removes the checkpoint if
+// they don't, so the wipe starts over. A disk without a serial number or
+// WWN can't be told from another at the same path, so it never resumes.
+func confirmResume(devicePath string) {
+	if deviceSerial(devicePath) == "" {
+		printWarning("'%s' reports no serial number or WWN, so an interrupted wipe of it can't resume\n", devicePath)
+		return
+	}
+	offset := loadCheckpoint(devicePath, deviceSize(devicePath))
+	if offset == 0 {
+		return
+	}
+	fmt.Printf("An interrupted wipe of '%s' got to %d MB.\n", devicePath, offset/(1024*1024))
+	fmt.Printf("Resume there? [y/N] ")
+	answer, _ := stdinReader.ReadString('\n')
+	if answer := strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+		removeCheckpoint(devicePath)
+	}
+}
+
+// stdinReader reads the answers to confirmation prompts. It is shared, since
+// a reader of its own per prompt would buffer and lose the answers piped in
+// for the devices after it.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// confirmDevice checks that devicePath isn't in use and has the user type
+// the path again before anything destructive happens to it. An erase that
+// reaches more than devicePath, such as a sanitize of all the namespaces of
+// an NVMe controller, passes the devices it erases, which are all checked
+// and listed.
+func confirmDevice(devicePath string, erased ...string) bool {
+	if len(erased) == 0 {
+		erased = []string{devicePath}
+	}
+	var users []string
+	for _, device := range erased {
+		users = append(users, deviceUsers(device)...)
+	}
+	if len(users) > 0 {
+		printError("'%s' is in use: %s\n", devicePath, strings.Join(users, ", "))
+		return false
+	}
+
+	if len(erased) == 1 && erased[0] == devicePath {
+		fmt.Printf("ALL DATA on '%s' will be destroyed.\n", devicePath)
+	} else {
+		fmt.Printf("ALL DATA on '%s' will be destroyed, on all of:\n", devicePath)
+		for _, device := range erased {
+			fmt.Printf("  %s\n", device)
+		}
+	}
+	fmt.Printf("Type the device path again to confirm: ")
+	answer, _ := stdinReader.ReadString('\n')
+	if strings.TrimSpace(answer) != devicePath {
+		fmt.Printf("not confirmed, '%s' left untouched\n", devicePath)
+		return false
+	}
+	return true
+}
+
+// runDeviceCommand implements "wipefile device", which uses the drive's own
+// erase commands to reach remapped and overprovisioned sectors that
+// overwriting can't.
+func runDeviceCommand(args []string) {
+	deviceFlags := flag.NewFlagSet("device", flag.ExitOnError)
+	ataErase := deviceFlags.Bool("ata-secure-erase", false, "Issue ATA SECURITY ERASE UNIT to a SATA drive (Linux only)")
+	enhanced := deviceFlags.Bool("enhanced", false, "Use the enhanced security erase mode with -ata-secure-erase")
+	sanitize := deviceFlags.Bool("nvme-sanitize", false, "Sanitize the whole NVMe controller (Linux only)")
+	sanitizeAction := deviceFlags.String("sanitize-action", "block", "Sanitize operation: block, crypto or overwrite")
+	format := deviceFlags.Bool("nvme-format", false, "Format the NVMe namespace with a secure erase setting (Linux only)")
+	ses := deviceFlags.Int("ses", 1, "Secure erase setting for -nvme-format: 1 user data erase, 2 crypto erase")
+	registerOutputFlags(deviceFlags)
+	registerLogFlags(deviceFlags)
+	deviceFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s device [options] <device>\n", os.Args[0])
+		deviceFlags.PrintDefaults()
+	}
+	parseFlags(deviceFlags, "device", args)
+
+	actions := 0
+	for _, set := range []bool{*ataErase, *sanitize, *format} {
+		if set {
+			actions++
+		}
+	}
+	if deviceFlags.NArg() != 1 || actions != 1 {
+		deviceFlags.Usage()
+		os.Exit(1)
+	}
+	if *sanitizeAction != "block" && *sanitizeAction != "crypto" && *sanitizeAction != "overwrite" {
+		fmt.Fprintf(os.Stderr, "Error: -sanitize-action must be block, crypto or overwrite\n")
+		os.Exit(1)
+	}
+	if *ses < 1 || *ses > 2 {
+		fmt.Fprintf(os.Stderr, "Error: -ses must be 1 or 2\n")
+		os.Exit(1)
+	}
+	devicePath := deviceFlags.Arg(0)
+	startLog()
+
+	// A sanitize erases every namespace of the controller, not just the
+	// one given
+	erased := []string{devicePath}
+	if *sanitize {
+		namespaces, err := nvmeNamespaces(devicePath)
+		if err != nil {
+			printError("cannot list the namespaces of '%s': %s\n", devicePath, getSimpleError(err))
+			os.Exit(1)
+		}
+		if len(namespaces) > 0 {
+			erased = namespaces
+		}
+	}
+	if !confirmDevice(devicePath, erased...) {
+		os.Exit(1)
+	}
+	var err error
+	switch {
+	case *ataErase:
+		err = ataSecureErase(devicePath, *enhanced)
+	case *sanitize:
+		err = nvmeSanitize(devicePath, *sanitizeAction)
+	case *format:
+		err = nvmeFormat(devicePath, *ses)
+	}
+	if err != nil {
+		printError("erasing '%s' failed: %s\n", devicePath, getSimpleError(err))
+		os.Exit(1)
+	}
+	printSummary("erased '%s'\n", devicePath)
+}
@@ -0,0 +1,77 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// deviceOf returns the ID of the device holding path.
+func deviceOf(path string) (uint64, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, errors.New("no device information")
+	}
+	return uint64(stat.Dev), nil
+}
+
+// fileIdentity returns the device and inode number of path, not following
+// a symlink.
+func fileIdentity(path string) (fileID, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fileID{}, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, errors.New("no inode information")
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, nil
+}
+
+// linkCount returns the number of hard links to the file.
+func linkCount(path string, info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Nlink)
+	}
+	return 1
+}
+
+// mountPoint returns the root of the filesystem holding path, found by
+// walking up until the device changes.
+func mountPoint(path string) (string, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	dev, err := deviceOf(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+		if parentDev, err := deviceOf(parent); err != nil || parentDev != dev {
+			return dir, nil
+		}
+		dir = parent
+	}
+}
+
+// allocationUnit returns the block size the filesystem allocates file
+// data in, as reported by stat.
+func allocationUnit(path string, info os.FileInfo) int64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Blksize > 0 {
+		return int64(stat.Blksize)
+	}
+	return bufferSize
+}
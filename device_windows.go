@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceW = kernel32.NewProc("GetDiskFreeSpaceW")
+
+// deviceOf returns the serial number of the volume holding path.
+func deviceOf(path string) (uint64, error) {
+	info, err := fileInformation(path)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(info.VolumeSerialNumber), nil
+}
+
+// fileIdentity returns the volume serial number and file index of path,
+// not following a reparse point.
+func fileIdentity(path string) (fileID, error) {
+	info, err := fileInformation(path)
+	if err != nil {
+		return fileID{}, err
+	}
+	index := uint64(info.FileIndexHigh)<<32 | uint64(info.FileIndexLow)
+	return fileID{dev: uint64(info.VolumeSerialNumber), ino: index}, nil
+}
+
+// linkCount returns the number of hard links to the file.
+func linkCount(path string, info os.FileInfo) uint64 {
+	handleInfo, err := fileInformation(path)
+	if err != nil {
+		return 1
+	}
+	return uint64(handleInfo.NumberOfLinks)
+}
+
+func fileInformation(path string) (*syscall.ByHandleFileInformation, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := syscall.CreateFile(pathPtr, 0,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS|syscall.FILE_FLAG_OPEN_REPARSE_POINT, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.CloseHandle(handle)
+
+	var info syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(handle, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// mountPoint returns the root of the volume holding path.
+func mountPoint(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.VolumeName(absPath) + `\`, nil
+}
+
+// allocationUnit returns the cluster size of the volume holding path.
+func allocationUnit(path string, info os.FileInfo) int64 {
+	root, err := mountPoint(path)
+	if err != nil {
+		return bufferSize
+	}
+	rootPtr, err := syscall.UTF16PtrFromString(root)
+	if err != nil {
+		return bufferSize
+	}
+	var sectorsPerCluster, bytesPerSector uint32
+	if ret, _, _ := procGetDiskFreeSpaceW.Call(uintptr(unsafe.Pointer(rootPtr)),
+		uintptr(unsafe.Pointer(&sectorsPerCluster)), uintptr(unsafe.Pointer(&bytesPerSector)), 0, 0); ret == 0 {
+		return bufferSize
+	}
+	return int64(sectorsPerCluster) * int64(bytesPerSector)
+}
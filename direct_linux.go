@@ -0,0 +1,6 @@
+package main
+
+import "syscall"
+
+// oDirect bypasses the page cache for overwrite writes
+const oDirect = syscall.O_DIRECT
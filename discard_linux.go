@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	blkDiscard    = 0x1277 // _IO(0x12, 119)
+	blkSecDiscard = 0x127d // _IO(0x12, 125)
+
+	// Extents whose physical address isn't a plain device offset
+	fiemapExtentUnsafe = 0x2 | 0x4 | 0x8 | 0x100 | 0x200 | 0x400 | fiemapExtentShare // UNKNOWN, DELALLOC, ENCODED, NOT_ALIGNED, DATA_INLINE, DATA_TAIL
+)
+
+// discardFilesystems are filesystems whose FIEMAP physical addresses are
+// offsets into the single block device they are mounted from.
+var discardFilesystems = map[string]bool{
+	"ext4": true,
+	"xfs":  true,
+	"f2fs": true,
+	"vfat": true,
+}
+
+// discardFile discards the device blocks currently holding path, using
+// secure discard where the device supports it and plain discard otherwise.
+// It reports whether secure discard was used.
+func discardFile(path string) (bool, error) {
+	fsType, err := filesystemType(path)
+	if err != nil {
+		return false, err
+	}
+	if !discardFilesystems[fsType] {
+		return false, fmt.Errorf("not supported on %s", fsType)
+	}
+	dev, err := deviceOf(path)
+	if err != nil {
+		return false, err
+	}
+	devicePath, err := blockDevicePath(dev)
+	if err != nil {
+		return false, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	extents, err := fileExtents(file)
+	file.Close()
+	if err != nil {
+		return false, err
+	}
+
+	device, err := os.OpenFile(devicePath, os.O_WRONLY, 0)
+	if err != nil {
+		return false, err
+	}
+	defer device.Close()
+
+	secure := true
+	for _, extent := range extents {
+		if extent.Flags&fiemapExtentUnsafe != 0 {
+			continue
+		}
+		blockRange := [2]uint64{extent.Physical, extent.Length}
+		if secure {
+			err = blockIoctl(device, blkSecDiscard, &blockRange)
+			if errors.Is(err, syscall.EOPNOTSUPP) {
+				secure = false
+			}
+		}
+		if !secure {
+			err = blockIoctl(device, blkDiscard, &blockRange)
+		}
+		if err != nil {
+			return secure, err
+		}
+	}
+	return secure, nil
+}
+
+func blockIoctl(device *os.File, request uintptr, blockRange *[2]uint64) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, device.Fd(), request, uintptr(unsafe.Pointer(blockRange))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// Discarding ranges of a mounted device needs the Linux block ioctls.
+func discardFile(path string) (bool, error) {
+	return false, errors.New("only supported on Linux")
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Options of wipefile docker
+var (
+	dockerVolume    string
+	dockerContainer string
+	containerCLI    string
+	skipFreeSpace   bool
+)
+
+// registerDockerFlags adds the options of wipefile docker to flags
+func registerDockerFlags(flags *flag.FlagSet) {
+	flags.StringVar(&dockerVolume, "volume", "", "With wipefile docker, wipe the data of this named volume")
+	flags.StringVar(&dockerContainer, "container", "", "With wipefile docker, wipe the writable layer of this stopped container")
+	flags.StringVar(&containerCLI, "container-cli", "docker", "With wipefile docker, the CLI that locates volumes and containers (docker or podman)")
+	flags.BoolVar(&skipFreeSpace, "skip-free-space", false, "With wipefile docker, don't wipe the free space of the filesystem afterwards")
+}
+
+// inspectContainer runs the container CLI with args and returns what it
+// printed, or what it complained about as the error
+func inspectContainer(args ...string) (string, error) {
+	output, err := exec.Command(containerCLI, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%s: %s", containerCLI, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// volumeDir returns the directory the data of volume is stored in. The
+// volume must not be in use by a running container.
+func volumeDir(volume string) (string, error) {
+	dir, err := inspectContainer("volume", "inspect", "--format", "{{.Mountpoint}}", volume)
+	if err != nil {
+		return "", err
+	}
+	users, err := inspectContainer("ps", "--quiet", "--filter", "volume="+volume)
+	if err != nil {
+		return "", err
+	}
+	if users != "" {
+		return "", fmt.Errorf("volume '%s' is used by running containers: %s", volume, strings.Join(strings.Fields(users), ", "))
+	}
+	return dir, nil
+}
+
+// containerLayerDir returns the directory that holds the writable layer of
+// container, which must be stopped. Only storage drivers that keep it in
+// an overlay upper directory (overlay2, fuse-overlayfs, podman's overlay)
+// can be located.
+func containerLayerDir(container string) (string, error) {
+	output, err := inspectContainer("container", "inspect", "--format",
+		"{{.State.Running}}\n{{.GraphDriver.Name}}\n{{.GraphDriver.Data.UpperDir}}", container)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.SplitN(output, "\n", 3)
+	if len(fields) != 3 {
+		return "", fmt.Errorf("%s: unexpected inspect output '%s'", containerCLI, output)
+	}
+	if fields[0] == "true" {
+		return "", fmt.Errorf("container '%s' is running, stop it first", container)
+	}
+	if fields[2] == "" || fields[2] == "<no value>" {
+		return "", fmt.Errorf("cannot locate the writable layer of '%s' with the %s storage driver", container, fields[1])
+	}
+	return fields[2], nil
+}
+
+// runDocker implements "wipefile docker", which wipes everything in a
+// volume or the writable layer of a container, then the free space of the
+// filesystem it is on, before the container runtime is left to remove it.
+func runDocker() {
+	var dir, target string
+	var err error
+	switch {
+	case dockerVolume != "" && dockerContainer == "":
+		target = "volume '" + dockerVolume + "'"
+		dir, err = volumeDir(dockerVolume)
+	case dockerContainer != "" && dockerVolume == "":
+		target = "container '" + dockerContainer + "'"
+		dir, err = containerLayerDir(dockerContainer)
+	default:
+		fmt.Fprintf(os.Stderr, "Usage: %s docker [options] -volume <name> | -container <id>\n", os.Args[0])
+		os.Exit(1)
+	}
+	if err == nil {
+		var info os.FileInfo
+		if info, err = os.Stat(dir); err == nil && !info.IsDir() {
+			err = fmt.Errorf("'%s' is not a directory", dir)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot locate %s: %s\n", target, getSimpleError(err))
+		os.Exit(1)
+	}
+
+	// The directory itself belongs to the runtime, which still expects it
+	// until the volume or container is removed
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot read '%s': %s\n", dir, getSimpleError(err))
+		os.Exit(1)
+	}
+	printStatus("wiping %s in '%s'\n", target, dir)
+	*recursive = true
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+	wipeTargets(paths)
+
+	if !skipFreeSpace {
+		handleInterrupts()
+		wipeFreeSpace(dir)
+		if isInterrupted() {
+			os.Exit(130)
+		}
+	}
+	reportFailures()
+	printSummary("wiped %s, ready to be removed\n", target)
+}
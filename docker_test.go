@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeContainerCLI makes containerCLI a script that prints output for any
+// arguments starting with one of its keys, and fails for the rest
+func fakeContainerCLI(t *testing.T, outputs map[string]string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script")
+	}
+	script := "#!/bin/sh\ncase \"$*\" in\n"
+	for args, output := range outputs {
+		script += args + "*) printf '%s' '" + output + "' ;;\n"
+	}
+	script += "*) echo \"Error: No such object\" >&2; exit 1 ;;\nesac\n"
+	path := filepath.Join(t.TempDir(), "docker")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	previous := containerCLI
+	containerCLI = path
+	t.Cleanup(func() { containerCLI = previous })
+}
+
+// TestVolumeDir tests finding a volume's data dir, refusing volumes that containers use
+func TestVolumeDir(t *testing.T) {
+	fakeContainerCLI(t, map[string]string{
+		"'volume inspect --format {{.Mountpoint}} secrets'": "/var/lib/docker/volumes/secrets/_data\n",
+		"'volume inspect --format {{.Mountpoint}} busy'":    "/var/lib/docker/volumes/busy/_data\n",
+		"'ps --quiet --filter volume=secrets'":              "",
+		"'ps --quiet --filter volume=busy'":                 "3f2a\n9c1d\n",
+	})
+	dir, err := volumeDir("secrets")
+	if err != nil || dir != "/var/lib/docker/volumes/secrets/_data" {
+		t.Errorf("volumeDir = %q, %v", dir, err)
+	}
+	if _, err := volumeDir("busy"); err == nil || !strings.Contains(err.Error(), "3f2a, 9c1d") {
+		t.Errorf("volumeDir of a volume in use: %v", err)
+	}
+	if _, err := volumeDir("missing"); err == nil || !strings.Contains(err.Error(), "No such object") {
+		t.Errorf("volumeDir of a missing volume: %v", err)
+	}
+}
+
+// TestContainerLayerDir tests finding the writable layer of a stopped overlay2 container
+func TestContainerLayerDir(t *testing.T) {
+	format := "{{.State.Running}}\n{{.GraphDriver.Name}}\n{{.GraphDriver.Data.UpperDir}}"
+	fakeContainerCLI(t, map[string]string{
+		"'container inspect --format " + format + " stopped'": "false\noverlay2\n/var/lib/docker/overlay2/ab12/diff\n",
+		"'container inspect --format " + format + " running'": "true\noverlay2\n/var/lib/docker/overlay2/cd34/diff\n",
+		"'container inspect --format " + format + " zfs'":     "false\nzfs\n<no value>\n",
+	})
+	dir, err := containerLayerDir("stopped")
+	if err != nil || dir != "/var/lib/docker/overlay2/ab12/diff" {
+		t.Errorf("containerLayerDir = %q, %v", dir, err)
+	}
+	if _, err := containerLayerDir("running"); err == nil || !strings.Contains(err.Error(), "stop it first") {
+		t.Errorf("containerLayerDir of a running container: %v", err)
+	}
+	if _, err := containerLayerDir("zfs"); err == nil || !strings.Contains(err.Error(), "zfs storage driver") {
+		t.Errorf("containerLayerDir with the zfs driver: %v", err)
+	}
+}
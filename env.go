@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// envPrefix starts the environment variables that set flags, so containers
+// and CI jobs can configure wipefile without wrapping its command line
+const envPrefix = "WIPEFILE_"
+
+// subcommands are the commands whose flags have their own prefix
+var subcommands = []string{"device", "zap", "decoy", "patterns", "daemon", "submit", "watch", "schedule", "s3", "docker", "apply-rules", "trash"}
+
+// envName returns the environment variable of the flag name of command
+// ("" for the main command): -limit-rate is WIPEFILE_LIMIT_RATE, and the
+// -count of decoy WIPEFILE_DECOY_COUNT
+func envName(command, name string) string {
+	if command != "" {
+		name = command + "_" + name
+	}
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnvFlags sets the flags of flags that have an environment variable.
+// Booleans take anything strconv.ParseBool does, e.g. WIPEFILE_R=1.
+func applyEnvFlags(flags *flag.FlagSet, command string) error {
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(command, f.Name))
+		if !ok || err != nil {
+			return
+		}
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %s", value, envName(command, f.Name), setErr)
+		}
+	})
+	return err
+}
+
+// unknownEnvFlags returns the WIPEFILE_ variables that set no flag of the
+// main command and aren't meant for a subcommand, most likely typos
+func unknownEnvFlags() []string {
+	known := make(map[string]bool)
+	flag.VisitAll(func(f *flag.Flag) {
+		known[envName("", f.Name)] = true
+	})
+	var unknown []string
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, envPrefix) || known[name] {
+			continue
+		}
+		forSubcommand := false
+		for _, command := range subcommands {
+			forSubcommand = forSubcommand || strings.HasPrefix(name, envName(command, ""))
+		}
+		if !forSubcommand {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// parseFlags parses args into flags, after setting them from the
+// environment, so the command line takes precedence
+func parseFlags(flags *flag.FlagSet, command string, args []string) {
+	if err := applyEnvFlags(flags, command); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	flags.Parse(args)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+// TestApplyEnvFlags tests setting flags from WIPEFILE_ variables
+func TestApplyEnvFlags(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	rate := flags.String("limit-rate", "", "")
+	recursive := flags.Bool("r", false, "")
+	count := flags.Int("count", 10, "")
+
+	t.Setenv("WIPEFILE_LIMIT_RATE", "50M")
+	t.Setenv("WIPEFILE_R", "1")
+	t.Setenv("WIPEFILE_DECOY_COUNT", "3")
+	if err := applyEnvFlags(flags, ""); err != nil {
+		t.Fatalf("applyEnvFlags failed: %v", err)
+	}
+	if *rate != "50M" || !*recursive || *count != 10 {
+		t.Errorf("Expected -limit-rate 50M and -r, got %q, %v, %d", *rate, *recursive, *count)
+	}
+
+	// The command line takes precedence, and subcommands have their own prefix
+	if err := flags.Parse([]string{"-limit-rate", "10M"}); err != nil || *rate != "10M" {
+		t.Errorf("Expected the command line to override, got %q (%v)", *rate, err)
+	}
+	if err := applyEnvFlags(flags, "decoy"); err != nil || *count != 3 {
+		t.Errorf("Expected WIPEFILE_DECOY_COUNT to set decoy's -count, got %d (%v)", *count, err)
+	}
+
+	t.Setenv("WIPEFILE_COUNT", "many")
+	if err := applyEnvFlags(flags, ""); err == nil {
+		t.Error("Expected error for an invalid value")
+	}
+}
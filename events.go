@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// eventInterval is how often pass-progress events are sent per file
+const eventInterval = time.Second
+
+// events is where -progress-json sends its JSON lines, nil without it
+var events struct {
+	sync.Mutex
+	output *os.File
+}
+
+// startEvents opens file descriptor fd (from -progress-json) for events
+// and sends the start event. fd 0 means no events.
+func startEvents(fd int) {
+	if fd == 0 {
+		return
+	}
+	switch fd {
+	case 1:
+		events.output = os.Stdout
+	case 2:
+		events.output = os.Stderr
+	default:
+		events.output = os.NewFile(uintptr(fd), "progress-json")
+	}
+	if events.output == nil {
+		fmt.Fprintf(os.Stderr, "Error: -progress-json: invalid file descriptor %d\n", fd)
+		os.Exit(1)
+	}
+	if _, err := events.output.Stat(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -progress-json: file descriptor %d is not open\n", fd)
+		os.Exit(1)
+	}
+	emitEvent("start", map[string]interface{}{"targets": flag.Args()})
+}
+
+// emitEvent sends an event as one JSON line, with its name and time added
+// to fields
+func emitEvent(name string, fields map[string]interface{}) {
+	if events.output == nil {
+		return
+	}
+	fields["event"] = name
+	fields["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	line, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+	events.Lock()
+	defer events.Unlock()
+	events.output.Write(append(line, '\n'))
+}
+
+// emitResult sends a file-done event for a target. size is negative if
+// unknown.
+func emitResult(path string, size int64, result string, err error) {
+	if events.output == nil {
+		return
+	}
+	fields := map[string]interface{}{"path": path, "result": result}
+	if size >= 0 {
+		fields["bytes"] = size
+	}
+	if err != nil {
+		fields["error"] = getSimpleError(err)
+	}
+	emitEvent("file-done", fields)
+}
+
+// emitError sends an error event with an error message as printed
+func emitError(format string, args ...interface{}) {
+	if events.output == nil {
+		return
+	}
+	message := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	emitEvent("error", map[string]interface{}{"message": message})
+}
+
+// eventProgress sends pass-progress events while a file is overwritten,
+// at most once per eventInterval whatever its size
+type eventProgress struct {
+	path     string
+	size     int64
+	lastSent time.Time
+}
+
+// newEventProgress returns nil, which sends nothing, without -progress-json
+func newEventProgress(path string, size int64) *eventProgress {
+	if events.output == nil {
+		return nil
+	}
+	return &eventProgress{path: path, size: size}
+}
+
+// update reports done bytes. A nil eventProgress does nothing.
+func (p *eventProgress) update(done int64) {
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	if now.Sub(p.lastSent) < eventInterval && done < p.size {
+		return
+	}
+	p.lastSent = now
+	emitEvent("pass-progress", map[string]interface{}{
+		"path": p.path, "pass": 1, "bytes": done, "total": p.size,
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEmitEvents tests that events are written as one JSON object a line
+func TestEmitEvents(t *testing.T) {
+	output, err := os.Create(filepath.Join(t.TempDir(), "events.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer output.Close()
+	events.output = output
+	defer func() { events.output = nil }()
+
+	emitResult("/tmp/a.txt", 4096, "wiped", nil)
+	emitResult("/tmp/b.txt", -1, "denied", os.ErrPermission)
+	emitError("cannot open '%s': %s\n", "/tmp/b.txt", "Permission denied")
+	progress := newEventProgress("/tmp/c.txt", 8192)
+	progress.update(4096)
+	progress.update(6144) // Within eventInterval, so not sent
+	progress.update(8192)
+
+	content, err := os.ReadFile(output.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 events, got %d: %q", len(lines), content)
+	}
+	var decoded []map[string]interface{}
+	for _, line := range lines {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", line, err)
+		}
+		if event["time"] == nil {
+			t.Errorf("Expected a time in %q", line)
+		}
+		decoded = append(decoded, event)
+	}
+
+	if decoded[0]["event"] != "file-done" || decoded[0]["bytes"] != 4096.0 || decoded[0]["error"] != nil {
+		t.Errorf("Unexpected event for a wiped file: %v", decoded[0])
+	}
+	if decoded[1]["result"] != "denied" || decoded[1]["bytes"] != nil || decoded[1]["error"] != "Permission denied" {
+		t.Errorf("Unexpected event for a failure: %v", decoded[1])
+	}
+	if decoded[2]["event"] != "error" || decoded[2]["message"] != "cannot open '/tmp/b.txt': Permission denied" {
+		t.Errorf("Unexpected error event: %v", decoded[2])
+	}
+	if decoded[3]["event"] != "pass-progress" || decoded[3]["bytes"] != 4096.0 || decoded[4]["bytes"] != 8192.0 {
+		t.Errorf("Expected progress at 4096 and the final 8192 bytes, got %v and %v", decoded[3], decoded[4])
+	}
+}
+
+// TestEventsOff tests that nothing is sent without -progress-json
+func TestEventsOff(t *testing.T) {
+	if progress := newEventProgress("/tmp/a.txt", 4096); progress != nil {
+		t.Errorf("Expected no progress without -progress-json")
+	}
+	emitEvent("summary", map[string]interface{}{"total": 1})
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Exit codes besides 0 (everything wiped), 1 (usage or fatal error) and
+// 130 (interrupted), so scripts can tell what went wrong
+const (
+	exitPartialFailure = 2 // Some targets could not be wiped
+	exitNothingMatched = 3 // None of the targets exist
+	exitPermission     = 4 // Targets could not be wiped for lack of permission
+)
+
+// wipeResults counts what happened to the targets of a run
+var wipeResults struct {
+	wiped     atomic.Int64
+	missing   atomic.Int64
+	failed    atomic.Int64
+	denied    atomic.Int64
+	scheduled atomic.Int64 // Locked files left for deletion at the next boot
+	errors    atomic.Int64 // Errors printed, for runs that don't count targets
+}
+
+// countWiped records a target that was wiped, size bytes of it overwritten
+// (negative if unknown)
+func countWiped(path string, size int64) {
+	wipeResults.wiped.Add(1)
+	recordResult(path, size, "wiped", nil)
+}
+
+// countScheduled records a target that was only scheduled for deletion at
+// the next boot. It wasn't overwritten, so it isn't counted as wiped, nor
+// as failed, since -on-locked reboot asked for it.
+func countScheduled(path string) {
+	wipeResults.scheduled.Add(1)
+	recordResult(path, -1, "scheduled", nil)
+}
+
+// countMissing records a target that doesn't exist
+func countMissing(path string) {
+	wipeResults.missing.Add(1)
+	recordResult(path, -1, "missing", nil)
+}
+
+// countFailure records a target that could not be wiped because of err, or
+// for a reason already reported if err is nil
+func countFailure(path string, err error) {
+	wipeResults.failed.Add(1)
+	if errors.Is(err, fs.ErrPermission) {
+		wipeResults.denied.Add(1)
+		recordResult(path, -1, "denied", err)
+		return
+	}
+	recordResult(path, -1, "failed", err)
+}
+
+// targetStarts holds when the wipe of each target started
+var targetStarts sync.Map
+
+// startTarget records that the wipe of path starts now
+func startTarget(path string) {
+	targetStarts.Store(path, time.Now())
+}
+
+// recordResult passes what happened to a target on to the system log, the
+// -progress-json events, the audit log, the certificate and the report.
+// size is negative if unknown.
+func recordResult(path string, size int64, result string, err error) {
+	var started time.Time
+	if start, ok := targetStarts.LoadAndDelete(path); ok {
+		started = start.(time.Time)
+	}
+	logResult(path, size, result, err)
+	emitResult(path, size, result, err)
+	auditResult(path, size, result, err, started)
+	certifyResult(path, size, result, err, started)
+	reportResult(path, size, result, err, started)
+}
+
+// runSummary returns the counted results and exit code of the run
+func runSummary(code int) map[string]interface{} {
+	wiped, failed, missing := wipeResults.wiped.Load(), wipeResults.failed.Load(), wipeResults.missing.Load()
+	scheduled := wipeResults.scheduled.Load()
+	return map[string]interface{}{
+		"wiped": wiped, "failed": failed, "missing": missing, "scheduled": scheduled,
+		"total": wiped + failed + missing + scheduled, "exit_code": code,
+	}
+}
+
+// accessError returns why filePath can't be opened for writing, if it
+// can't, to tell permission problems from other failures
+func accessError(filePath string) error {
+	file, err := os.OpenFile(filePath, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return nil
+}
+
+// exitCode returns the exit code for the counted results. A run that
+// counted no targets, such as a free-space wipe whose cleanup failed, fails
+// if it printed an error.
+func exitCode() int {
+	wiped, missing, failed := wipeResults.wiped.Load(), wipeResults.missing.Load(), wipeResults.failed.Load()
+	handled := wiped + wipeResults.scheduled.Load()
+	if handled+missing+failed == 0 && wipeResults.errors.Load() > 0 {
+		return exitPartialFailure
+	}
+	return exitCodeFor(handled, missing, failed, wipeResults.denied.Load())
+}
+
+// exitCodeFor returns the exit code for counts of targets wiped, missing,
+// failed and denied
+func exitCodeFor(wiped, missing, failed, denied int64) int {
+	switch {
+	case denied > 0:
+		return exitPermission
+	case failed > 0:
+		return exitPartialFailure
+	case missing > 0 && wiped == 0:
+		return exitNothingMatched
+	case missing > 0:
+		return exitPartialFailure
+	}
+	return 0
+}
+
+// reportFailures prints how many targets were not wiped, if any, and exits
+// with the matching code. The log file records the outcome either way.
+func reportFailures() {
+	code := exitCode()
+	notWiped := wipeResults.failed.Load() + wipeResults.missing.Load()
+	total := notWiped + wipeResults.wiped.Load() + wipeResults.scheduled.Load()
+	writeLog(logInfo, "finished: %d of %d targets wiped, exit code %d\n", wipeResults.wiped.Load(), total, code)
+	emitEvent("summary", runSummary(code))
+	closeAuditLog()
+	writeCertificate()
+	closeReport()
+	mode := "files"
+	switch {
+	case *deviceMode:
+		mode = "device"
+	case *slackPath != "":
+		mode = "slack"
+	case *luksHeader != "":
+		mode = "luks-header"
+	}
+	notifyFinished(mode, runSummary(code))
+	if code == 0 {
+		return
+	}
+	if total > 0 {
+		printError("%d of %d targets could not be wiped\n", notWiped, total)
+	}
+	os.Exit(code)
+}
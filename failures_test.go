@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExitCode tests the exit code for combinations of results
+func TestExitCode(t *testing.T) {
+	reset := func() {
+		wipeResults.wiped.Store(0)
+		wipeResults.missing.Store(0)
+		wipeResults.failed.Store(0)
+		wipeResults.denied.Store(0)
+	}
+	defer reset()
+
+	for _, test := range []struct {
+		wiped, missing int
+		failures       []error
+		expected       int
+	}{
+		{3, 0, nil, 0},
+		{0, 2, nil, exitNothingMatched},
+		{1, 1, nil, exitPartialFailure},
+		{2, 0, []error{fmt.Errorf("disk full")}, exitPartialFailure},
+		{0, 0, []error{nil}, exitPartialFailure},
+		{1, 1, []error{nil, &fs.PathError{Op: "open", Path: "x", Err: fs.ErrPermission}}, exitPermission},
+	} {
+		reset()
+		for i := 0; i < test.wiped; i++ {
+			countWiped("wiped", 0)
+		}
+		for i := 0; i < test.missing; i++ {
+			countMissing("missing")
+		}
+		for _, err := range test.failures {
+			countFailure("failed", err)
+		}
+		if code := exitCode(); code != test.expected {
+			t.Errorf("%d wiped, %d missing, failures %v: expected exit code %d, got %d", test.wiped, test.missing, test.failures, test.expected, code)
+		}
+	}
+}
+
+// TestWipeCountsResults tests that wiping counts wiped and missing targets
+func TestWipeCountsResults(t *testing.T) {
+	defer func() {
+		wipeResults.wiped.Store(0)
+		wipeResults.missing.Store(0)
+	}()
+	wipeResults.wiped.Store(0)
+	wipeResults.missing.Store(0)
+
+	dir := t.TempDir()
+	var files, folders []string
+	collectPaths(filepath.Join(dir, "missing.txt"), &files, &folders)
+	if wipeResults.missing.Load() != 1 || len(files) != 0 {
+		t.Errorf("Expected a missing target, got %d", wipeResults.missing.Load())
+	}
+
+	file := filepath.Join(dir, "wipe.txt")
+	if err := os.WriteFile(file, make([]byte, 10000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wipeFile(file)
+	if wipeResults.wiped.Load() != 1 || exitCode() != exitPartialFailure {
+		t.Errorf("Expected one wiped target and a partial failure, got %d wiped, exit code %d", wipeResults.wiped.Load(), exitCode())
+	}
+}
+
+// TestRunsWithoutTargetsFail tests that -s and -slack count what they
+// can't wipe, and that a run counting no targets fails on printed errors
+func TestRunsWithoutTargetsFail(t *testing.T) {
+	reset := func() {
+		wipeResults.wiped.Store(0)
+		wipeResults.missing.Store(0)
+		wipeResults.failed.Store(0)
+		wipeResults.denied.Store(0)
+		wipeResults.errors.Store(0)
+	}
+	defer reset()
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	reset()
+	wipeSlack(missing)
+	if code := exitCode(); code != exitNothingMatched {
+		t.Errorf("-slack of a missing path: expected exit code %d, got %d", exitNothingMatched, code)
+	}
+
+	reset()
+	wipeFreeSpace(missing)
+	if code := exitCode(); code != exitNothingMatched {
+		t.Errorf("-s of a missing dir: expected exit code %d, got %d", exitNothingMatched, code)
+	}
+
+	reset()
+	printError("cannot remove temp directory\n")
+	if code := exitCode(); code != exitPartialFailure {
+		t.Errorf("expected exit code %d after an error, got %d", exitPartialFailure, code)
+	}
+}
+
+// TestScheduledNotWiped tests that a locked file scheduled for deletion at
+// reboot doesn't fail the run, but isn't certified as wiped either
+func TestScheduledNotWiped(t *testing.T) {
+	certificateTargets.enabled = true
+	defer func() {
+		certificateTargets.enabled = false
+		certificateTargets.targets = nil
+		wipeResults.wiped.Store(0)
+		wipeResults.scheduled.Store(0)
+	}()
+	wipeResults.wiped.Store(0)
+	wipeResults.missing.Store(0)
+	wipeResults.failed.Store(0)
+
+	countWiped("wiped.txt", 10)
+	countScheduled("locked.txt")
+	if code := exitCode(); code != 0 {
+		t.Errorf("Expected exit code 0 with a scheduled file, got %d", code)
+	}
+	if wiped := wipeResults.wiped.Load(); wiped != 1 {
+		t.Errorf("Expected only 1 target counted as wiped, got %d", wiped)
+	}
+	cert := newCertificate("tester")
+	if cert.Verified != "failed" || cert.Targets[1].Result != "scheduled" {
+		t.Errorf("Expected the certificate to record the scheduled file and fail verification, got %s %+v", cert.Verified, cert.Targets)
+	}
+}
+
+// TestLockedFileStops tests that -on-locked fail stops the run without
+// exiting, and that the next run, such as the next daemon job, starts over
+func TestLockedFileStops(t *testing.T) {
+	oldOnLocked, oldParallel := *onLocked, *parallel
+	*onLocked, *parallel = "fail", 2
+	defer func() {
+		*onLocked, *parallel = oldOnLocked, oldParallel
+		stopWiping.Store(false)
+		wipeResults.wiped.Store(0)
+		wipeResults.failed.Store(0)
+	}()
+	wipeResults.wiped.Store(0)
+	wipeResults.missing.Store(0)
+	wipeResults.failed.Store(0)
+
+	handleLockedFile("locked.txt")
+	if !stopWiping.Load() || wipeResults.failed.Load() != 1 {
+		t.Fatalf("Expected a locked file to fail and stop the run")
+	}
+	if code := exitCode(); code != exitPartialFailure {
+		t.Errorf("Expected exit code %d after a locked file, got %d", exitPartialFailure, code)
+	}
+
+	path := filepath.Join(t.TempDir(), "next.txt")
+	if err := os.WriteFile(path, []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	wipeTargets([]string{path})
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the next run to wipe '%s' after the stop", path)
+	}
+}
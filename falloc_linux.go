@@ -0,0 +1,15 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+const fallocKeepSize = 0x1 // FALLOC_FL_KEEP_SIZE
+
+// preallocate allocates size bytes of disk space for file up front, so it
+// gets few large extents instead of growing one write at a time. The file
+// size stays as is, so it still only covers what was actually written.
+func preallocate(file *os.File, size int64) error {
+	return syscall.Fallocate(int(file.Fd()), fallocKeepSize, 0, size)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// Preallocation uses fallocate, elsewhere files grow as they are written.
+func preallocate(file *os.File, size int64) error {
+	return errors.New("only supported on Linux")
+}
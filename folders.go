@@ -0,0 +1,77 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// folderNode is a folder to remove, with the folders below it that have to
+// go first
+type folderNode struct {
+	path    string
+	parent  *folderNode // The folder holding it, if that is removed too
+	pending int         // Folders directly below it not done yet
+}
+
+// removeFolders wipes folders with -p workers, each one as soon as all the
+// folders directly below it are done, so sibling subtrees are removed at
+// the same time. A folder whose subfolders failed is still tried, for its
+// failure to be reported. Folders go in the order given when they are
+// ready at the same time.
+func removeFolders(folders []string) {
+	nodes := make(map[string]*folderNode, len(folders))
+	var order []*folderNode
+	for _, folder := range folders {
+		key := filepath.Clean(folder)
+		if nodes[key] == nil {
+			nodes[key] = &folderNode{path: folder}
+			order = append(order, nodes[key])
+		}
+	}
+	if len(order) == 0 {
+		return
+	}
+	for key, node := range nodes {
+		if parent := nodes[filepath.Dir(key)]; parent != nil && parent != node {
+			node.parent = parent
+			parent.pending++
+		}
+	}
+
+	// Every folder is queued once, so the queue never blocks
+	ready := make(chan *folderNode, len(order))
+	for _, node := range order {
+		if node.pending == 0 {
+			ready <- node
+		}
+	}
+	metrics.queueDepth.Add(int64(len(order)))
+
+	var mu sync.Mutex
+	remaining := len(order)
+	var wg sync.WaitGroup
+	for i := 0; i < *parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for node := range ready {
+				metrics.queueDepth.Add(-1)
+				wipeFolder(node.path)
+
+				mu.Lock()
+				if parent := node.parent; parent != nil {
+					parent.pending--
+					if parent.pending == 0 {
+						ready <- parent
+					}
+				}
+				remaining--
+				if remaining == 0 {
+					close(ready)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRemoveFolders tests that a tree of folders given in any order is
+// removed with every folder after the ones below it
+func TestRemoveFolders(t *testing.T) {
+	defer func() {
+		wipeResults.wiped.Store(0)
+		wipeResults.failed.Store(0)
+	}()
+	wipeResults.failed.Store(0)
+	root := filepath.Join(t.TempDir(), "root")
+
+	// Parents first, the order collectPaths finds them in
+	folders := []string{root}
+	for i := 0; i < 20; i++ {
+		branch := filepath.Join(root, fmt.Sprintf("branch%d", i))
+		folders = append(folders, branch)
+		for j := 0; j < 5; j++ {
+			leaf := filepath.Join(branch, fmt.Sprintf("leaf%d", j), "deeper")
+			os.MkdirAll(leaf, 0755)
+			folders = append(folders, filepath.Dir(leaf), leaf)
+		}
+	}
+
+	saved := *parallel
+	*parallel = 8
+	defer func() { *parallel = saved }()
+	removeFolders(append(folders, root+string(os.PathSeparator)))
+
+	if failed := wipeResults.failed.Load(); failed != 0 {
+		t.Errorf("%d folders failed to be removed", failed)
+	}
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Errorf("root folder still there: %v", err)
+	}
+}
+
+// TestRemoveFoldersFailed tests that a folder is still tried, and its
+// parent, when a folder below it can't be removed
+func TestRemoveFoldersFailed(t *testing.T) {
+	defer wipeResults.failed.Store(0)
+	wipeResults.failed.Store(0)
+	root := t.TempDir()
+	parent := filepath.Join(root, "parent")
+	child := filepath.Join(parent, "child")
+	os.MkdirAll(child, 0755)
+	os.WriteFile(filepath.Join(child, "kept.txt"), []byte("content"), 0644)
+
+	saved := *parallel
+	*parallel = 2
+	defer func() { *parallel = saved }()
+	removeFolders([]string{parent, child})
+	if failed := wipeResults.failed.Load(); failed != 2 {
+		t.Errorf("%d folders failed, want the child and its parent", failed)
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// freeSpaceChunkSizes are temp file sizes for filesystems where the
+// default doesn't suit: FAT and exFAT are mostly small cards and sticks,
+// and FAT can't hold files of 4GB and up at all
+var freeSpaceChunkSizes = map[string]int64{
+	"vfat":  1024 * 1024 * 1024,
+	"msdos": 1024 * 1024 * 1024,
+	"fat":   1024 * 1024 * 1024,
+	"fat32": 1024 * 1024 * 1024,
+	"exfat": 1024 * 1024 * 1024,
+}
+
+// freeSpaceChunkFor returns the temp file size for filling dir: -chunk-size
+// if given, else a default for the filesystem, scaled down so that small
+// filesystems still get split over several files.
+func freeSpaceChunkFor(dir string) int64 {
+	if freeSpaceChunkBytes > 0 {
+		return freeSpaceChunkBytes
+	}
+
+	chunk := int64(freeSpaceChunkSize)
+	if fsType, err := filesystemType(dir); err == nil {
+		if size, ok := freeSpaceChunkSizes[fsType]; ok {
+			chunk = size
+		}
+	}
+	if free, err := freeSpaceBytes(dir); err == nil {
+		scaled := free / freeSpaceMinChunks
+		scaled -= scaled % int64(writeBlockSize)
+		if scaled < chunk {
+			chunk = scaled
+		}
+	}
+	if chunk < freeSpaceMinChunkSize {
+		chunk = freeSpaceMinChunkSize
+	}
+	return chunk
+}
+
+var (
+	// interrupted is set once SIGINT or SIGTERM arrived
+	interrupted int32
+
+	fillsMu     sync.Mutex
+	activeFills []*freeSpaceFill
+)
+
+// handleInterrupts makes SIGINT and SIGTERM stop all free-space fills, so
+// their temp files get removed before exiting. A second signal exits
+// right away.
+func handleInterrupts() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		printError("interrupted, removing temp files (interrupt again to quit now)\n")
+		atomic.StoreInt32(&interrupted, 1)
+		fillsMu.Lock()
+		for _, fill := range activeFills {
+			fill.stop()
+		}
+		fillsMu.Unlock()
+
+		<-signals
+		os.Exit(130)
+	}()
+}
+
+func isInterrupted() bool {
+	return atomic.LoadInt32(&interrupted) != 0
+}
+
+// registerFill makes fill stop on interrupt, right away if one already
+// arrived
+func registerFill(fill *freeSpaceFill) {
+	fillsMu.Lock()
+	defer fillsMu.Unlock()
+	activeFills = append(activeFills, fill)
+	if isInterrupted() {
+		fill.stop()
+	}
+}
+
+// freeSpaceFill is shared by the workers filling one filesystem with temp
+// files, so together they stay within the fill budget
+type freeSpaceFill struct {
+	mu         sync.Mutex
+	dir        string
+	chunkSize  int64 // Size of each temp file
+	counter    int
+	filled     int64 // Bytes handed out to workers so far
+	budget     int64 // Bytes left to hand out, as of the last check
+	sinceCheck int64
+	checked    bool
+	stopped    bool
+	stopErr    error // Write error that ended the fill, if any
+	written    int64 // Bytes actually written by finished temp files
+	total      int64 // Expected bytes to fill in all, 0 if unknown
+	start      time.Time
+	lastPrint  time.Time
+	lastFilled int64
+}
+
+// reserve hands out up to n bytes of the fill budget and returns how many
+// may be written. It returns 0 once the budget is used up or after stop.
+func (f *freeSpaceFill) reserve(n int64) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stopped {
+		return 0
+	}
+
+	// Free space is looked up again now and then, as other processes may
+	// be writing to the filesystem too
+	if !f.checked || f.sinceCheck >= freeSpaceCheckInterval {
+		f.budget = fillBudget(f.dir, f.filled)
+		f.updateTotal()
+		f.sinceCheck = 0
+		if !f.checked {
+			f.start, f.lastPrint = time.Now(), time.Now()
+			f.checked = true
+		}
+	}
+	if f.budget < n {
+		n = f.budget
+	}
+	if n <= 0 {
+		printVerbose(verboseDetails, "fill limit reached, stopping freespace wipe\n")
+		f.stopped = true
+		return 0
+	}
+	f.budget -= n
+	f.filled += n
+	f.sinceCheck += n
+	f.report()
+	return n
+}
+
+// updateTotal estimates how much will be filled in all, from the fill
+// budget and the free space the filesystem reports right now
+func (f *freeSpaceFill) updateTotal() {
+	remaining := f.budget
+	if free, err := freeSpaceBytes(f.dir); err == nil && free-leaveFreeBytes < remaining {
+		remaining = free - leaveFreeBytes
+	}
+	if remaining == math.MaxInt64 {
+		f.total = 0
+		return
+	}
+	f.total = f.filled + max64(remaining, 0)
+}
+
+// report prints how much has been filled, at most once per
+// fillProgressInterval
+func (f *freeSpaceFill) report() {
+	now := time.Now()
+	elapsed := now.Sub(f.lastPrint)
+	if elapsed < fillProgressInterval {
+		return
+	}
+
+	rate := float64(f.filled-f.lastFilled) / elapsed.Seconds() / (1024 * 1024)
+	if f.total > 0 {
+		percent := min(int(f.filled*100/f.total), 100)
+		printStatus("filling '%s': %.1f GB of %.1f GB (%d%%), %.1f MB/s\n",
+			filepath.Dir(f.dir), gigabytes(f.filled), gigabytes(f.total), percent, rate)
+	} else {
+		printStatus("filling '%s': %.1f GB, %.1f MB/s\n", filepath.Dir(f.dir), gigabytes(f.filled), rate)
+	}
+	f.lastPrint = now
+	f.lastFilled = f.filled
+}
+
+// addWritten records the bytes a finished temp file holds
+func (f *freeSpaceFill) addWritten(n int64) {
+	f.mu.Lock()
+	f.written += n
+	f.mu.Unlock()
+}
+
+func gigabytes(n int64) float64 {
+	return float64(n) / (1024 * 1024 * 1024)
+}
+
+// stop makes all workers finish their current temp file
+func (f *freeSpaceFill) stop() {
+	f.mu.Lock()
+	f.stopped = true
+	f.mu.Unlock()
+}
+
+// stopWithError stops the fill because a write failed with err
+func (f *freeSpaceFill) stopWithError(err error) {
+	f.mu.Lock()
+	f.stopped = true
+	if f.stopErr == nil {
+		f.stopErr = err
+	}
+	f.mu.Unlock()
+}
+
+// reportCoverage tells how close to zero free space the fill got, while
+// the temp files still exist, and why it fell short if it did
+func (f *freeSpaceFill) reportCoverage(dir string) {
+	available, err1 := freeSpaceBytes(dir)
+	free, err2 := totalFreeBytes(dir)
+	if err1 != nil || err2 != nil {
+		printSummary("filled %.1f GB of free space in '%s'\n", gigabytes(f.written), dir)
+		return
+	}
+	printSummary("filled %.1f GB of free space in '%s', %.1f GB left free\n", gigabytes(f.written), dir, gigabytes(free))
+
+	switch {
+	case free <= fillCoverageSlack:
+		return
+	case fillLimitBytes > 0 || leaveFreeBytes > 0:
+		printStatus("'%s': %.1f GB left unwiped because of -fill-limit/-leave-free\n", dir, gigabytes(free))
+	case errors.Is(f.stopErr, syscall.EDQUOT):
+		printWarning("a disk quota stopped the fill of '%s', %.1f GB of free space was not overwritten\n", dir, gigabytes(free))
+	case available > fillCoverageSlack:
+		printWarning("the fill of '%s' stopped early (%s), %.1f GB of free space was not overwritten\n", dir, stopReason(f.stopErr), gigabytes(available))
+	default:
+		printWarning("%.1f GB reserved for root on '%s' was not overwritten (run as root to include it)\n", gigabytes(free-available), dir)
+	}
+}
+
+func stopReason(err error) string {
+	if err == nil {
+		return "interrupted"
+	}
+	return getSimpleError(err)
+}
+
+// nextFile returns the name of the next temp file, or "" after stop
+func (f *freeSpaceFill) nextFile() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stopped {
+		return ""
+	}
+	f.counter++
+	return filepath.Join(f.dir, fmt.Sprintf("wipe_%d.tmp", f.counter-1))
+}
+
+// preallocSize returns how much of the next temp file is sure to fit: no
+// more than the fill budget and the free space left. With -leave-free
+// nothing is preallocated, as the budget is rechecked against the free
+// space, where blocks preallocated but not yet written would count twice.
+func (f *freeSpaceFill) preallocSize() int64 {
+	if leaveFreeBytes > 0 {
+		return 0
+	}
+	f.mu.Lock()
+	filled := f.filled
+	f.mu.Unlock()
+
+	size := min64(f.chunkSize, fillBudget(f.dir, filled))
+	free, err := freeSpaceBytes(f.dir)
+	if err != nil {
+		return 0
+	}
+	return min64(size, free)
+}
+
+// writeTempFile fills the next temp file with up to chunkSize bytes of
+// fake header data. It returns false when filling should stop.
+func (f *freeSpaceFill) writeTempFile() bool {
+	filename := f.nextFile()
+	if filename == "" {
+		return false
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		printVerboseWarning(verboseActions, "cannot create temp file: %s\n", getSimpleError(err))
+		f.stopWithError(err)
+		return false
+	}
+	defer file.Close()
+
+	// Preallocate as much of the chunk as there is free space, so the last
+	// chunk is sized from statfs rather than by running into ENOSPC. Writes
+	// still go on past it until ENOSPC, which lets root fill the blocks
+	// reserved for it as well.
+	if size := f.preallocSize(); size > 0 {
+		if err := preallocate(file, size); err != nil && !errors.Is(err, syscall.EOPNOTSUPP) {
+			printVerboseWarning(verboseDetails, "cannot preallocate '%s': %s\n", filename, getSimpleError(err))
+		}
+	}
+
+	written := int64(0)
+	buffer := getBlock()
+	defer putBlock(buffer)
+	// Zeros let thin-provisioned and VM disks reclaim the space afterwards
+	var fakeData *fakeReader
+	if *zeroFill {
+		for i := range buffer {
+			buffer[i] = 0
+		}
+	} else {
+		fakeData = newFakeReader()
+		defer fakeData.Close()
+	}
+	sinceSync := int64(0)
+	for written < f.chunkSize {
+		length := f.reserve(int64(len(buffer)))
+		if length == 0 {
+			break
+		}
+		if fakeData != nil {
+			fakeData.Read(buffer[:length])
+		}
+		writeLimiter.wait(int(length))
+		n, err := writeAtWithRetry(file, buffer[:length], written)
+		written += int64(n)
+		if err != nil {
+			printVerbose(verboseDetails, "disk full, stopping freespace wipe\n")
+			f.stopWithError(err)
+			break
+		}
+
+		// Without syncing, the data may sit in the page cache until the
+		// temp file is deleted and never reach the disk at all
+		sinceSync += int64(n)
+		if syncIntervalBytes > 0 && sinceSync >= syncIntervalBytes {
+			if err := syncFile(file); err != nil {
+				printVerboseWarning(verboseActions, "cannot sync '%s': %s\n", filename, getSimpleError(err))
+			}
+			sinceSync = 0
+		}
+	}
+	if err := syncFile(file); err != nil {
+		printError("cannot sync '%s': %s\n", filename, getSimpleError(err))
+	}
+
+	f.addWritten(written)
+	printVerbose(verboseDetails, "created temp file %s (%d MB)\n", filepath.Base(filename), written/(1024*1024))
+	return written == f.chunkSize
+}
+
+// scrubInodeTable creates tiny files with random names in dir until no
+// more can be created, or inodeScrubMaxFiles, then removes them. Each one
+// takes a free inode or MFT record, overwriting what a deleted file left
+// there, such as resident data, and the long random names overwrite
+// stale directory entries.
+func scrubInodeTable(dir string) {
+	scrubDir := filepath.Join(dir, randomName(16))
+	if err := os.Mkdir(scrubDir, 0700); err != nil {
+		printVerboseWarning(verboseActions, "cannot create inode scrub directory: %s\n", getSimpleError(err))
+		return
+	}
+
+	nameLength := nameLimit(scrubDir)
+	data := make([]byte, inodeScrubMaxSize)
+	fakeData := newFakeReader()
+	created := 0
+	for created < inodeScrubMaxFiles && !isInterrupted() {
+		fakeData.Read(data)
+		name := randomName(nameLength/2 + rand.Intn(nameLength/2+1))
+		err := os.WriteFile(filepath.Join(scrubDir, name), data[:1+rand.Intn(len(data))], 0600)
+		if isNameTooLong(err) && len(name) > minRandomName {
+			shorterName(scrubDir, len(name))
+			nameLength = nameLimit(scrubDir)
+			continue
+		}
+		if err != nil {
+			// Out of inodes, or no room left for the MFT to grow
+			break
+		}
+		created++
+	}
+	fakeData.Close()
+
+	printVerbose(verboseDetails, "created %d tiny files to overwrite free inodes, removing them...\n", created)
+	if err := os.RemoveAll(scrubDir); err != nil {
+		printVerboseWarning(verboseActions, "cannot remove inode scrub directory: %s\n", getSimpleError(err))
+	}
+}
+
+// churnName renames path to churnRenames random names of random length in
+// turn, so the journal and directory blocks end up holding those instead
+// of the original name. It returns the path's last name.
+func churnName(path string) string {
+	dir := filepath.Dir(path)
+	maxLength := nameLimit(dir)
+	for i := 0; i < churnRenames; i++ {
+		newPath := filepath.Join(dir, randomName(1+rand.Intn(maxLength)))
+		err := renameNoReplace(path, newPath)
+		if isNameTooLong(err) {
+			shorterName(dir, len(filepath.Base(newPath)))
+			maxLength = nameLimit(dir)
+			continue
+		}
+		if errors.Is(err, os.ErrExist) {
+			continue // Never replace an existing entry
+		} else if err != nil {
+			break
+		}
+		path = newPath
+	}
+	return path
+}
+
+// churnDirectory creates churnEntries empty files with random names in
+// dir, then removes them again, so they take the directory slots of
+// earlier deleted entries and overwrite the names left there.
+func churnDirectory(dir string) {
+	maxLength := nameLimit(dir)
+	var dummies []string
+	for i := 0; i < churnEntries; i++ {
+		dummy := filepath.Join(dir, randomName(maxLength/2+rand.Intn(maxLength/2+1)))
+		file, err := os.OpenFile(dummy, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if isNameTooLong(err) {
+			shorterName(dir, len(filepath.Base(dummy)))
+			maxLength = nameLimit(dir)
+		}
+		if err != nil {
+			continue
+		}
+		file.Close()
+		dummies = append(dummies, dummy)
+	}
+	for _, dummy := range dummies {
+		os.Remove(dummy)
+	}
+	printVerbose(verboseDetails, "churned %d directory entries in '%s'\n", len(dummies), dir)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestFreeSpaceFillReserve tests that concurrent workers stay within the fill limit together
+func TestFreeSpaceFillReserve(t *testing.T) {
+	defer func() { fillLimitBytes = 0 }()
+	fillLimitBytes = 10*bufferSize + 7
+
+	fill := &freeSpaceFill{dir: t.TempDir()}
+	var total int64
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				n := fill.reserve(bufferSize)
+				if n == 0 {
+					return
+				}
+				atomic.AddInt64(&total, n)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if total != fillLimitBytes {
+		t.Errorf("Workers should reserve exactly the fill limit of %d bytes, got %d", fillLimitBytes, total)
+	}
+	if fill.nextFile() != "" {
+		t.Error("No more temp files should be handed out once the budget is used up")
+	}
+}
+
+// TestPreallocSize tests that temp files are preallocated up to the fill
+// limit, but not with -leave-free
+func TestPreallocSize(t *testing.T) {
+	defer func() { fillLimitBytes, leaveFreeBytes = 0, 0 }()
+	fillLimitBytes = 10 * bufferSize
+
+	fill := &freeSpaceFill{dir: t.TempDir(), chunkSize: freeSpaceChunkSize}
+	if size := fill.preallocSize(); size != fillLimitBytes {
+		t.Errorf("Expected to preallocate the fill limit of %d bytes, got %d", fillLimitBytes, size)
+	}
+	leaveFreeBytes = 1
+	if size := fill.preallocSize(); size != 0 {
+		t.Errorf("Expected no preallocation with -leave-free, got %d", size)
+	}
+}
+
+// TestChurnName tests that churning renames the file and leaves nothing else behind
+func TestChurnName(t *testing.T) {
+	dir := t.TempDir()
+	tempFile := filepath.Join(dir, "wipe_0.tmp")
+	if err := os.WriteFile(tempFile, []byte("data"), 0600); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	newPath := churnName(tempFile)
+	if newPath == tempFile {
+		t.Error("File should be renamed")
+	}
+	if content, err := os.ReadFile(newPath); err != nil || string(content) != "data" {
+		t.Errorf("Renamed file should keep its content, got %q (%v)", content, err)
+	}
+
+	churnDirectory(dir)
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Only the renamed file should remain, found %d entries", len(entries))
+	}
+}
+
+// TestWriteTempFileZero tests that -zero fills temp files with zeros only
+func TestWriteTempFileZero(t *testing.T) {
+	defer func() { fillLimitBytes, *zeroFill = 0, false }()
+	fillLimitBytes = 2*bufferSize + 10
+	*zeroFill = true
+
+	fill := &freeSpaceFill{dir: t.TempDir(), chunkSize: freeSpaceChunkSize}
+	fill.writeTempFile()
+
+	content, err := os.ReadFile(filepath.Join(fill.dir, "wipe_0.tmp"))
+	if err != nil {
+		t.Fatalf("Failed to read temp file: %v", err)
+	}
+	if int64(len(content)) != fillLimitBytes {
+		t.Errorf("Temp file should hold the fill limit of %d bytes, got %d", fillLimitBytes, len(content))
+	}
+	for i, b := range content {
+		if b != 0 {
+			t.Fatalf("Byte %d should be zero, got 0x%02x", i, b)
+		}
+	}
+}
+
+// TestFreeSpaceChunkFor tests the -chunk-size override and the automatic bounds
+func TestFreeSpaceChunkFor(t *testing.T) {
+	dir := t.TempDir()
+
+	chunk := freeSpaceChunkFor(dir)
+	if chunk < freeSpaceMinChunkSize || chunk > freeSpaceChunkSize {
+		t.Errorf("Automatic chunk size should be between %d and %d, got %d", int64(freeSpaceMinChunkSize), int64(freeSpaceChunkSize), chunk)
+	}
+
+	defer func() { freeSpaceChunkBytes = 0 }()
+	freeSpaceChunkBytes = 8 * 1024 * 1024
+	if chunk := freeSpaceChunkFor(dir); chunk != freeSpaceChunkBytes {
+		t.Errorf("-chunk-size should override the default, got %d", chunk)
+	}
+}
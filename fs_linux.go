@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// maxNameLength returns the maximum filename length of the filesystem
+// holding dir, as reported by statfs.
+func maxNameLength(dir string) int {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil || stat.Namelen <= 0 {
+		return defaultMaxNameLength
+	}
+	return int(stat.Namelen)
+}
+
+// isRotational reports whether the block device dev is a spinning disk,
+// according to sysfs. Partitions use the queue of their parent disk.
+func isRotational(dev uint64) bool {
+	sysPath, err := sysBlockPath(dev)
+	if err != nil {
+		return false
+	}
+
+	for _, queue := range []string{filepath.Join(sysPath, "queue"), filepath.Join(filepath.Dir(sysPath), "queue")} {
+		data, err := os.ReadFile(filepath.Join(queue, "rotational"))
+		if err == nil {
+			return strings.TrimSpace(string(data)) == "1"
+		}
+	}
+	return false
+}
+
+// sysBlockPath returns the resolved sysfs directory of block device dev.
+func sysBlockPath(dev uint64) (string, error) {
+	return filepath.EvalSymlinks("/sys/dev/block/" + deviceNumber(dev))
+}
+
+// blockDevicePath returns the /dev node of block device dev.
+func blockDevicePath(dev uint64) (string, error) {
+	sysPath, err := sysBlockPath(dev)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(sysPath, "uevent"))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "DEVNAME=") {
+			return "/dev/" + strings.TrimPrefix(line, "DEVNAME="), nil
+		}
+	}
+	return "", fmt.Errorf("no device node for %s", sysPath)
+}
+
+// deviceSerial returns what identifies the disk behind block device
+// devicePath across reboots and renames, from sysfs: its WWN or serial
+// number, and for a partition also its number. It returns "" if the disk
+// reports neither, as virtual and USB disks may not.
+func deviceSerial(devicePath string) string {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(devicePath, &stat); err != nil || stat.Mode&syscall.S_IFMT != syscall.S_IFBLK {
+		return ""
+	}
+	sysPath, err := sysBlockPath(uint64(stat.Rdev))
+	if err != nil {
+		return ""
+	}
+	disk, part := sysPath, ""
+	if data, err := os.ReadFile(filepath.Join(sysPath, "partition")); err == nil {
+		disk, part = filepath.Dir(sysPath), "-part"+strings.TrimSpace(string(data))
+	}
+	for _, name := range []string{"wwid", "device/wwid", "serial", "device/serial"} {
+		data, err := os.ReadFile(filepath.Join(disk, name))
+		if serial := strings.TrimSpace(string(data)); err == nil && serial != "" {
+			return serial + part
+		}
+	}
+	return ""
+}
+
+// activeSwaps returns the swap devices and files listed in /proc/swaps.
+func activeSwaps() []string {
+	data, err := os.ReadFile("/proc/swaps")
+	if err != nil {
+		return nil
+	}
+	var swaps []string
+	for _, line := range strings.Split(string(data), "\n")[1:] { // Skip header
+		if fields := strings.Fields(line); len(fields) > 0 {
+			swaps = append(swaps, fields[0])
+		}
+	}
+	return swaps
+}
+
+// deviceUsers returns what uses block device devicePath or one of its
+// partitions: mounted filesystems, active swap, and holders such as LVM,
+// dm-crypt or md. Devices are compared by number, so a mount of
+// /dev/mapper/root or /dev/disk/by-uuid/... counts, and /dev/sda10 doesn't
+// for /dev/sda1.
+func deviceUsers(devicePath string) []string {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(devicePath, &stat); err != nil || stat.Mode&syscall.S_IFMT != syscall.S_IFBLK {
+		return nil
+	}
+	sysPath, err := sysBlockPath(uint64(stat.Rdev))
+	if err != nil {
+		return nil
+	}
+
+	// The device and its partitions, by major:minor
+	devices := map[string]string{deviceNumber(uint64(stat.Rdev)): filepath.Base(sysPath)}
+	dirs := []string{sysPath}
+	entries, _ := os.ReadDir(sysPath)
+	for _, entry := range entries {
+		dir := filepath.Join(sysPath, entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, "partition")); err != nil {
+			continue
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, "dev")); err == nil {
+			devices[strings.TrimSpace(string(data))] = entry.Name()
+			dirs = append(dirs, dir)
+		}
+	}
+
+	var users []string
+	for _, dir := range dirs {
+		holders, _ := os.ReadDir(filepath.Join(dir, "holders"))
+		for _, holder := range holders {
+			name := holder.Name()
+			if data, err := os.ReadFile(filepath.Join("/sys/class/block", name, "dm", "name")); err == nil {
+				name += " (" + strings.TrimSpace(string(data)) + ")"
+			}
+			users = append(users, fmt.Sprintf("'%s' is held by %s", filepath.Base(dir), name))
+		}
+	}
+	if data, err := os.ReadFile("/proc/self/mountinfo"); err == nil {
+		users = append(users, mountUsers(string(data), devices)...)
+	}
+	for _, swap := range activeSwaps() {
+		if name, ok := devices[blockDeviceNumber(swap)]; ok {
+			users = append(users, fmt.Sprintf("'%s' is active swap", name))
+		}
+	}
+	return users
+}
+
+// mountUsers returns the mounts in mountinfo of one of devices, which maps
+// major:minor to the device name. Filesystems such as btrfs report an
+// anonymous device number, so the mount source is checked as well.
+func mountUsers(mountinfo string, devices map[string]string) []string {
+	var users []string
+	for _, line := range strings.Split(mountinfo, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		name, ok := devices[fields[2]]
+		if !ok {
+			for i := 6; i+2 < len(fields); i++ {
+				if fields[i] == "-" { // Ends the optional fields
+					name, ok = devices[blockDeviceNumber(fields[i+2])]
+					break
+				}
+			}
+		}
+		if ok {
+			users = append(users, fmt.Sprintf("'%s' is mounted on %s", name, fields[4]))
+		}
+	}
+	return users
+}
+
+// blockDeviceNumber returns the major:minor of the block device at path,
+// or "" if it isn't one.
+func blockDeviceNumber(path string) string {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil || stat.Mode&syscall.S_IFMT != syscall.S_IFBLK {
+		return ""
+	}
+	return deviceNumber(uint64(stat.Rdev))
+}
+
+// deviceNumber formats dev as major:minor, as sysfs and mountinfo do.
+func deviceNumber(dev uint64) string {
+	major := (dev>>8)&0xfff | (dev>>32)&0xfffff000
+	minor := dev&0xff | (dev>>12)&0xffffff00
+	return fmt.Sprintf("%d:%d", major, minor)
+}
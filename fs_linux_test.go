@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestMountUsers tests that mounts are matched by device number, not by a
+// prefix of the device name
+func TestMountUsers(t *testing.T) {
+	mountinfo := `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+23 22 8:10 / /data rw,relatime shared:2 - ext4 /dev/sda10 rw
+24 22 253:0 / /home rw,relatime - ext4 /dev/mapper/home rw
+25 22 0:45 / /srv rw,relatime - btrfs /dev/nonexistent rw
+`
+	devices := map[string]string{"8:1": "sda1", "253:0": "dm-0"}
+	expected := []string{"'sda1' is mounted on /", "'dm-0' is mounted on /home"}
+	if users := mountUsers(mountinfo, devices); !reflect.DeepEqual(users, expected) {
+		t.Errorf("Expected %v, got %v", expected, users)
+	}
+}
+
+// TestDeviceNumber tests formatting device numbers as major:minor
+func TestDeviceNumber(t *testing.T) {
+	for dev, expected := range map[uint64]string{0x801: "8:1", 0x80a: "8:10", 0xfd00: "253:0", 0x10300: "259:0", 0x100000000000: "4096:0", 0x100000: "0:256"} {
+		if number := deviceNumber(dev); number != expected {
+			t.Errorf("deviceNumber(%#x): expected %s, got %s", dev, expected, number)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !linux
+
+package main
+
+// maxNameLength returns the maximum filename length of the filesystem
+// holding dir. APFS, HFS+, NTFS and most others use 255.
+func maxNameLength(dir string) int {
+	return defaultMaxNameLength
+}
+
+// isRotational reports whether dev is a spinning disk. Only Linux exposes
+// this cheaply, so other platforms assume solid-state storage.
+func isRotational(dev uint64) bool {
+	return false
+}
+
+// deviceSerial returns the WWN or serial number of the disk behind block
+// device devicePath. Only Linux exposes it without an ioctl per platform,
+// so elsewhere no device is known well enough to resume a wipe of it.
+func deviceSerial(devicePath string) string {
+	return ""
+}
+
+// activeSwaps returns the active swap areas. Only Linux lists them.
+func activeSwaps() []string {
+	return nil
+}
+
+// deviceUsers returns what uses block device devicePath. Only Linux lists
+// mounts, swap and holders by device, elsewhere opening a disk in use for
+// writing fails instead.
+func deviceUsers(devicePath string) []string {
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// cowFilesystems never overwrite data in place, so old extents survive
+var cowFilesystems = map[string]bool{
+	"btrfs":    true,
+	"zfs":      true,
+	"bcachefs": true,
+	"apfs":     true,
+	"refs":     true,
+}
+
+// networkFilesystems are network or FUSE mounts, where an overwrite goes
+// through layers that may cache, dedupe or snapshot the data server-side
+var networkFilesystems = map[string]bool{
+	"nfs":     true,
+	"smb":     true,
+	"smb2":    true,
+	"cifs":    true,
+	"smbfs":   true,
+	"afpfs":   true,
+	"webdav":  true,
+	"9p":      true,
+	"ceph":    true,
+	"afs":     true,
+	"fuse":    true,
+	"osxfuse": true,
+	"macfuse": true,
+	"fusefs":  true,
+	"remote":  true,
+}
+
+// memoryFilesystems keep file data in RAM, and map to whether it can be
+// paged out to swap from there; ramfs pins its pages
+var memoryFilesystems = map[string]bool{
+	"tmpfs": true,
+	"ramfs": false,
+}
+
+var (
+	fsCheckMu      sync.Mutex
+	fsCheckResults = make(map[uint64]bool)
+)
+
+// checkNetworkPolicy applies -network-policy to path on a filesystem of
+// fsType, and returns false if it refuses to wipe there. Filesystems that
+// aren't network or FUSE mounts are always allowed.
+func checkNetworkPolicy(path, fsType string) bool {
+	if !networkFilesystems[fsType] {
+		return true
+	}
+	switch {
+	case *networkPolicy == "refuse":
+		printError("refusing to wipe '%s' on network filesystem %s (-network-policy refuse)\n", path, fsType)
+		return false
+	case *networkPolicy == "require" && !*allowNetwork:
+		printError("refusing to wipe '%s' on network filesystem %s (use -allow-network)\n", path, fsType)
+		return false
+	}
+	printWarning("'%s' is on %s, a network/FUSE filesystem: the overwrite may never reach the underlying disk, and server-side snapshots can keep the data.\n", path, fsType)
+	return true
+}
+
+// checkFilesystem looks at the filesystem holding path and warns about
+// properties that undermine an overwrite. It returns false if the
+// configured policy refuses to wipe there. The check runs once per device.
+func checkFilesystem(path string) bool {
+	dev, err := deviceOf(path)
+	if err != nil {
+		return true
+	}
+
+	fsCheckMu.Lock()
+	defer fsCheckMu.Unlock()
+	if allowed, ok := fsCheckResults[dev]; ok {
+		return allowed
+	}
+
+	allowed := true
+	fsType, err := filesystemType(path)
+	if err == nil && cowFilesystems[fsType] {
+		printWarning("'%s' is on %s, a copy-on-write filesystem: overwriting in place does not destroy earlier copies of the data. Consider a free-space wipe (-s) or crypto-erase instead.\n", path, fsType)
+		if *cowPolicy == "refuse" && !*forceCow {
+			printError("refusing to wipe on %s (use -force-cow)\n", fsType)
+			allowed = false
+		}
+	}
+
+	if err == nil && !checkNetworkPolicy(path, fsType) {
+		allowed = false
+	}
+
+	if swappable, inMemory := memoryFilesystems[fsType]; err == nil && inMemory {
+		if swaps := activeSwaps(); swappable && len(swaps) > 0 {
+			printWarning("'%s' is on %s, so its data lived in RAM and may have been paged out to swap (%s). Wipe the swap area too (swapoff, overwrite, mkswap), or use encrypted swap.\n", path, fsType, strings.Join(swaps, ", "))
+		} else if swappable {
+			printVerbose(verboseActions, "'%s' is on %s with no swap active, data only lived in RAM\n", path, fsType)
+		} else {
+			printVerbose(verboseActions, "'%s' is on %s, which is never swapped out, data only lived in RAM\n", path, fsType)
+		}
+	}
+
+	if allowed {
+		checkSnapshots(path)
+	}
+
+	fsCheckResults[dev] = allowed
+	return allowed
+}
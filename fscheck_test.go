@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+// TestCheckNetworkPolicy tests the decisions of -network-policy and
+// -allow-network on network and local filesystems
+func TestCheckNetworkPolicy(t *testing.T) {
+	oldPolicy, oldAllow := *networkPolicy, *allowNetwork
+	defer func() { *networkPolicy, *allowNetwork = oldPolicy, oldAllow }()
+
+	tests := []struct {
+		policy  string
+		allow   bool
+		fsType  string
+		allowed bool
+	}{
+		{"warn", false, "nfs", true},
+		{"warn", false, "ext4", true},
+		{"require", false, "cifs", false},
+		{"require", true, "cifs", true},
+		{"require", false, "ext4", true},
+		{"refuse", false, "fuse", false},
+		{"refuse", true, "fuse", false},
+		{"refuse", false, "xfs", true},
+	}
+	for _, test := range tests {
+		*networkPolicy, *allowNetwork = test.policy, test.allow
+		if allowed := checkNetworkPolicy("/mnt/share/file", test.fsType); allowed != test.allowed {
+			t.Errorf("-network-policy %s, -allow-network %v on %s: allowed %v, want %v", test.policy, test.allow, test.fsType, allowed, test.allowed)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+//go:build darwin || freebsd
+
+package main
+
+import (
+	"strings"
+	"syscall"
+)
+
+// filesystemType returns the name of the filesystem holding path, e.g.
+// "apfs", "smbfs" or "zfs".
+func filesystemType(path string) (string, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return "", err
+	}
+	var name strings.Builder
+	for _, c := range stat.Fstypename {
+		if c == 0 {
+			break
+		}
+		name.WriteByte(byte(c))
+	}
+	return strings.ToLower(name.String()), nil
+}
+
+// freeSpaceBytes returns the bytes available to unprivileged users on the
+// filesystem holding path.
+func freeSpaceBytes(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
+
+// totalFreeBytes returns all free bytes on the filesystem holding path,
+// including those reserved for root.
+func totalFreeBytes(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bfree) * int64(stat.Bsize), nil
+}
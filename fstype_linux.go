@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// filesystemMagics maps statfs f_type values to filesystem names
+var filesystemMagics = map[int64]string{
+	0x9123683e: "btrfs",
+	0x2fc12fc1: "zfs",
+	0xca451a4e: "bcachefs",
+	0x58465342: "xfs",
+	0xef53:     "ext4",
+	0xf2f52010: "f2fs",
+	0x4d44:     "vfat",
+	0x2011bab0: "exfat",
+	0x5346544e: "ntfs",
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x65735546: "fuse",
+	0x01021997: "9p",
+	0x00c36400: "ceph",
+	0x5346414f: "afs",
+	0x01021994: "tmpfs",
+	0x858458f6: "ramfs",
+	0xf15f:     "ecryptfs",
+	0x794c7630: "overlayfs",
+}
+
+// filesystemType returns the name of the filesystem holding path.
+func filesystemType(path string) (string, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return "", err
+	}
+	if name, ok := filesystemMagics[int64(stat.Type)&0xffffffff]; ok {
+		return name, nil
+	}
+	return fmt.Sprintf("0x%x", stat.Type), nil
+}
+
+// freeSpaceBytes returns the bytes available to unprivileged users on the
+// filesystem holding path.
+func freeSpaceBytes(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
+
+// totalFreeBytes returns all free bytes on the filesystem holding path,
+// including those reserved for root.
+func totalFreeBytes(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bfree) * int64(stat.Bsize), nil
+}
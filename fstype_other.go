@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package main
+
+import "errors"
+
+func filesystemType(path string) (string, error) {
+	return "", errors.New("filesystem type detection not supported")
+}
+
+func freeSpaceBytes(path string) (int64, error) {
+	return 0, errors.New("free space lookup not supported")
+}
+
+func totalFreeBytes(path string) (int64, error) {
+	return 0, errors.New("free space lookup not supported")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const driveRemote = 4
+
+var (
+	procGetVolumeInformationW = kernel32.NewProc("GetVolumeInformationW")
+	procGetDriveTypeW         = kernel32.NewProc("GetDriveTypeW")
+	procGetDiskFreeSpaceExW   = kernel32.NewProc("GetDiskFreeSpaceExW")
+)
+
+// filesystemType returns the lower-cased file system name of the volume
+// holding path ("ntfs", "refs", "fat32", ...), or "remote" for network drives.
+func filesystemType(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	root := filepath.VolumeName(absPath) + `\`
+	rootPtr, err := syscall.UTF16PtrFromString(root)
+	if err != nil {
+		return "", err
+	}
+
+	if driveType, _, _ := procGetDriveTypeW.Call(uintptr(unsafe.Pointer(rootPtr))); driveType == driveRemote {
+		return "remote", nil
+	}
+
+	var fsName [syscall.MAX_PATH + 1]uint16
+	ret, _, err := procGetVolumeInformationW.Call(uintptr(unsafe.Pointer(rootPtr)), 0, 0, 0, 0, 0,
+		uintptr(unsafe.Pointer(&fsName[0])), uintptr(len(fsName)))
+	if ret == 0 {
+		return "", err
+	}
+	return strings.ToLower(syscall.UTF16ToString(fsName[:])), nil
+}
+
+// freeSpaceBytes returns the bytes available to the current user on the
+// volume holding path, which honors disk quotas.
+func freeSpaceBytes(path string) (int64, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var available uint64
+	if ret, _, err := procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(pathPtr)), uintptr(unsafe.Pointer(&available)), 0, 0); ret == 0 {
+		return 0, err
+	}
+	return int64(available), nil
+}
+
+// totalFreeBytes returns all free bytes on the volume holding path,
+// regardless of disk quotas.
+func totalFreeBytes(path string) (int64, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var free uint64
+	if ret, _, err := procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(pathPtr)), 0, 0, uintptr(unsafe.Pointer(&free))); ret == 0 {
+		return 0, err
+	}
+	return int64(free), nil
+}
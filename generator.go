@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	maxGeneratorWorkers  = 8
+	generatorReseedBytes = 1024 * 1024 * 1024
+)
+
+var (
+	generatorOnce sync.Once
+	fakeBlocks    chan []byte
+
+	// blockPool recycles write-sized blocks, so multi-GB overwrites don't
+	// allocate a new buffer for every write
+	blockPool = sync.Pool{
+		New: func() interface{} {
+			block := make([]byte, writeBlockSize)
+			return &block
+		},
+	}
+)
+
+func getBlock() []byte {
+	return *blockPool.Get().(*[]byte)
+}
+
+func putBlock(block []byte) {
+	if cap(block) == writeBlockSize {
+		block = block[:writeBlockSize]
+		blockPool.Put(&block)
+	}
+}
+
+// startGenerator starts the producer goroutines that fill blocks with fake
+// headers, so pattern parsing and random reads run alongside disk writes
+// instead of between them.
+func startGenerator() {
+	workers := runtime.NumCPU()
+	if workers > maxGeneratorWorkers {
+		workers = maxGeneratorWorkers
+	}
+
+	if generatorSeeded {
+		// One producer, so the seeded stream comes out in a fixed order
+		workers = 1
+	}
+
+	fakeBlocks = make(chan []byte, workers*2)
+	for i := 0; i < workers; i++ {
+		go func() {
+			// Each producer has its own keystream, reseeded periodically
+			stream := newPaddingStream()
+			generated := 0
+			file := newFakeFile()
+			for {
+				if generated >= generatorReseedBytes {
+					stream = newPaddingStream()
+					generated = 0
+				}
+				block := getBlock()
+				if file != nil {
+					file.fill(block, stream)
+				} else {
+					fillFakeHeaders(block, stream)
+				}
+				generated += len(block)
+				fakeBlocks <- block
+			}
+		}()
+	}
+}
+
+// fakeReader is an io.Reader over the generated fake-header stream. As
+// long as reads are multiples of 4K, every 4K of output starts with a header
+// (with -structured or -entropy low, every fake file does).
+type fakeReader struct {
+	block []byte
+	rest  []byte
+}
+
+func newFakeReader() *fakeReader {
+	generatorOnce.Do(startGenerator)
+	return &fakeReader{}
+}
+
+func (r *fakeReader) Read(buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		if len(r.rest) == 0 {
+			if r.block != nil {
+				putBlock(r.block)
+			}
+			r.block = <-fakeBlocks
+			r.rest = r.block
+		}
+		copied := copy(buf[n:], r.rest)
+		r.rest = r.rest[copied:]
+		n += copied
+	}
+	return n, nil
+}
+
+// Close hands the block currently being read back to the pool.
+func (r *fakeReader) Close() error {
+	putBlock(r.block)
+	r.block, r.rest = nil, nil
+	return nil
+}
+
+var (
+	// generatorRand picks the templates, alternatives and random characters
+	// of fake headers, from the clock or, with -seed, from a keystream
+	generatorRand = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano())})
+
+	// generatorSeed is the parsed -seed, used if generatorSeeded is set
+	generatorSeed   int64
+	generatorSeeded bool
+
+	// paddingStreams counts the seeded padding streams handed out
+	paddingStreams uint64
+)
+
+// setGeneratorSeed makes the fake data reproducible: template choices and
+// filler bytes come from AES-CTR keystreams derived from seed, generated by
+// a single producer
+func setGeneratorSeed(seed int64) {
+	generatorSeed, generatorSeeded = seed, true
+	atomic.StoreUint64(&paddingStreams, 0)
+	generatorRand = rand.New(&lockedSource{src: &streamSource{stream: seededStream("choices")}})
+}
+
+// seededStream returns the AES-256-CTR keystream for purpose derived from
+// generatorSeed
+func seededStream(purpose string) cipher.Stream {
+	key := sha256.Sum256([]byte(fmt.Sprintf("wipefile seed %d %s", generatorSeed, purpose)))
+	block, _ := aes.NewCipher(key[:])
+	return cipher.NewCTR(block, make([]byte, aes.BlockSize))
+}
+
+// streamSource is a math/rand source that reads a keystream, so choices
+// made with it are reproducible from the keystream's seed
+type streamSource struct {
+	stream cipher.Stream
+	buf    [8]byte
+}
+
+func (s *streamSource) Int63() int64 {
+	s.buf = [8]byte{}
+	s.stream.XORKeyStream(s.buf[:], s.buf[:])
+	return int64(binary.LittleEndian.Uint64(s.buf[:]) >> 1)
+}
+
+func (s *streamSource) Seed(int64) {}
+
+// lockedSource makes a math/rand source safe for the producers to share
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// TestFakeReader tests that the generator stream fills buffers of any size
+func TestFakeReader(t *testing.T) {
+	reader := newFakeReader()
+
+	for _, size := range []int{bufferSize, 3 * bufferSize, 10000} {
+		buffer := make([]byte, size)
+		n, err := reader.Read(buffer)
+		if err != nil {
+			t.Fatalf("Read returned error: %v", err)
+		}
+		if n != size {
+			t.Errorf("Expected %d bytes, got %d", size, n)
+		}
+
+		entropy := calculateEntropy(buffer)
+		if entropy < 6.0 {
+			t.Errorf("Stream entropy too low: %.2f", entropy)
+		}
+	}
+}
+
+// TestFillFakeHeadersAllocations tests that filling a block reuses its memory
+func TestFillFakeHeadersAllocations(t *testing.T) {
+	block := make([]byte, 16*bufferSize)
+	stream := newPaddingStream()
+
+	allocs := testing.AllocsPerRun(10, func() {
+		fillFakeHeaders(block, stream)
+	})
+	// Only small per-header bookkeeping may allocate, never whole buffers
+	if allocs > 16*2 {
+		t.Errorf("Too many allocations filling a block: %.0f", allocs)
+	}
+}
+
+// TestGeneratorSeed tests that -seed makes the fake data reproducible
+func TestGeneratorSeed(t *testing.T) {
+	defer func() {
+		generatorSeeded = false
+		generatorRand = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano())})
+	}()
+
+	generate := func(seed int64) []byte {
+		setGeneratorSeed(seed)
+		block := make([]byte, 8*bufferSize)
+		fillFakeHeaders(block, newPaddingStream())
+		return block
+	}
+	first := generate(42)
+	if !bytes.Equal(first, generate(42)) {
+		t.Error("The same seed should generate the same data")
+	}
+	if bytes.Equal(first, generate(43)) {
+		t.Error("Different seeds should generate different data")
+	}
+}
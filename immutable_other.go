@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+// Immutable/append-only inode flags are only handled on Linux.
+func protectedFlags(path string) (int, error) {
+	return 0, nil
+}
+
+func clearProtectedFlags(path string) error {
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// Mandatory file locks are a Windows thing; elsewhere open files can
+// always be overwritten and unlinked.
+func isLockedError(err error) bool {
+	return false
+}
+
+func scheduleDeleteOnReboot(path string) error {
+	return errors.New("only supported on Windows")
+}
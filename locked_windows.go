@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	errorSharingViolation    = syscall.Errno(32)
+	errorLockViolation       = syscall.Errno(33)
+	moveFileDelayUntilReboot = 0x4
+	moveFileReplaceExisting  = 0x1
+)
+
+var procMoveFileExW = kernel32.NewProc("MoveFileExW")
+
+// isLockedError reports whether err means another process holds the file.
+func isLockedError(err error) bool {
+	return errors.Is(err, errorSharingViolation) || errors.Is(err, errorLockViolation)
+}
+
+// scheduleDeleteOnReboot registers path for deletion by the session manager
+// at the next boot, before any other process can open it. Needs admin rights.
+func scheduleDeleteOnReboot(path string) error {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	if ret, _, err := procMoveFileExW.Call(uintptr(unsafe.Pointer(pathPtr)), 0, moveFileDelayUntilReboot); ret == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logBackups is how many rotated log files are kept, as .1 (newest) to .3
+const logBackups = 3
+
+// Severities of log records
+const (
+	logInfo = iota
+	logWarning
+	logError
+)
+
+// logPrefixes mark warnings and errors in the log file
+var logPrefixes = [...]string{logInfo: "", logWarning: "warning: ", logError: "error: "}
+
+// logPath and logMaxSize are set by -log-file and -log-max-size
+var logPath, logMaxSize string
+
+// logFile records every action and error of a run whatever the console
+// verbosity, so unattended wipes leave a trace
+var logFile struct {
+	sync.Mutex
+	file    *os.File
+	path    string
+	size    int64
+	maxSize int64
+}
+
+// registerLogFlags adds -log-file, -log-max-size and -syslog to flags
+func registerLogFlags(flags *flag.FlagSet) {
+	flags.StringVar(&logPath, "log-file", "", "Append a timestamped record of every action and error to this file, whatever -v or -q say")
+	flags.StringVar(&logMaxSize, "log-max-size", "10M", "Rotate the -log-file once it grows past this size, keeping 3 old logs")
+	flags.BoolVar(&useSyslog, "syslog", false, "Send actions and errors to syslog, or journald where it runs, with path, bytes and result fields for each target")
+}
+
+// startLog opens the -log-file and syslog, if asked for
+func startLog() {
+	if useSyslog {
+		writer, err := openSyslog()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot open syslog: %s\n", getSimpleError(err))
+			os.Exit(1)
+		}
+		systemLog = writer
+	}
+	if logPath != "" {
+		maxSize, err := parseSize(logMaxSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -log-max-size: %s\n", err)
+			os.Exit(1)
+		}
+		if err := openLogFile(logPath, maxSize); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot open log file '%s': %s\n", logPath, getSimpleError(err))
+			os.Exit(1)
+		}
+	}
+	writeLog(logInfo, "started: %s\n", strings.Join(os.Args, " "))
+}
+
+// openLogFile opens path for appending log records, rotating it once it
+// grows past maxSize bytes (never, if 0)
+func openLogFile(path string, maxSize int64) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	logFile.Lock()
+	defer logFile.Unlock()
+	logFile.file, logFile.path, logFile.size, logFile.maxSize = file, path, info.Size(), maxSize
+	return nil
+}
+
+// writeLog appends a timestamped record to the log file and sends it to
+// syslog, if either is open
+func writeLog(severity int, format string, args ...interface{}) {
+	if systemLog != nil {
+		systemLog.write(severity, strings.TrimRight(fmt.Sprintf(format, args...), "\n"), nil)
+	}
+
+	logFile.Lock()
+	defer logFile.Unlock()
+	if logFile.file == nil {
+		return
+	}
+
+	record := time.Now().Format(time.RFC3339) + " " + logPrefixes[severity] + strings.TrimRight(fmt.Sprintf(format, args...), "\n") + "\n"
+	if logFile.maxSize > 0 && logFile.size > 0 && logFile.size+int64(len(record)) > logFile.maxSize {
+		rotateLog()
+		if logFile.file == nil {
+			return
+		}
+	}
+	n, _ := logFile.file.WriteString(record)
+	logFile.size += int64(n)
+}
+
+// rotateLog moves the log file to .1, the older ones one number up, and
+// starts a new one. The caller holds the lock.
+func rotateLog() {
+	logFile.file.Close()
+	for i := logBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", logFile.path, i), fmt.Sprintf("%s.%d", logFile.path, i+1))
+	}
+	os.Rename(logFile.path, logFile.path+".1")
+
+	file, err := os.OpenFile(logFile.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		// Can't go through printError, which would take the lock again
+		fmt.Fprintf(os.Stderr, "wipefile: cannot reopen log file '%s': %s\n", logFile.path, getSimpleError(err))
+		logFile.file = nil
+		return
+	}
+	logFile.file, logFile.size = file, 0
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLogFile tests that the log records messages whatever the verbosity,
+// and rotates once it grows past its maximum size
+func TestLogFile(t *testing.T) {
+	stdout := os.Stdout
+	defer func() {
+		os.Stdout = stdout
+		logFile.file.Close()
+		logFile.file = nil
+	}()
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+
+	path := filepath.Join(t.TempDir(), "wipefile.log")
+	if err := openLogFile(path, 200); err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	printVerbose(verboseActions, "wiping file: %s\n", "a.txt")
+	printVerbose(verboseWrites, "wrote %d bytes\n", 4096)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(lines) != 1 || !strings.HasSuffix(lines[0], " wiping file: a.txt") {
+		t.Fatalf("Expected only the action to be logged, got %q", content)
+	}
+	timestamp, _, _ := strings.Cut(lines[0], " ")
+	if _, err := time.Parse(time.RFC3339, timestamp); err != nil {
+		t.Errorf("Expected a timestamp, got %q", lines[0])
+	}
+
+	for i := 0; i < 20; i++ {
+		printVerbose(verboseActions, "removed '%s'\n", "some/longer/path/name.txt")
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Errorf("Expected %s after rotation: %v", filepath.Base(name), err)
+		} else if info.Size() > 200 {
+			t.Errorf("%s has %d bytes, more than the maximum size", filepath.Base(name), info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".4"); err == nil {
+		t.Errorf("Expected at most %d rotated logs", logBackups)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+const (
+	luksSectorSize     = 512
+	luks2BinaryHdrSize = 4096
+	luks2MaxHdrSize    = 4 * 1024 * 1024
+)
+
+var (
+	luksMagic        = []byte("LUKS\xba\xbe")
+	luks2SecondMagic = []byte("SKUL\xba\xbe")
+
+	// luks2SecondaryOffsets are where LUKS2 may put its secondary header,
+	// one per allowed header size
+	luks2SecondaryOffsets = []int64{0x4000, 0x8000, 0x10000, 0x20000, 0x40000, 0x80000, 0x100000, 0x200000, 0x400000}
+)
+
+// luks2Metadata is the part of the LUKS2 JSON area that tells where the
+// keyslot areas and the encrypted data are
+type luks2Metadata struct {
+	Keyslots map[string]struct {
+		Area struct {
+			Offset string `json:"offset"`
+			Size   string `json:"size"`
+		} `json:"area"`
+	} `json:"keyslots"`
+	Segments map[string]struct {
+		Offset string `json:"offset"`
+	} `json:"segments"`
+}
+
+// luksHeaderArea returns the LUKS version of the volume in file and the
+// end of its header and keyslot areas, which is where the data begins.
+func luksHeaderArea(file *os.File) (int, int64, error) {
+	header := make([]byte, luks2BinaryHdrSize)
+	if _, err := file.ReadAt(header, 0); err != nil && err != io.EOF {
+		return 0, 0, err
+	}
+
+	if bytes.HasPrefix(header, luksMagic) && binary.BigEndian.Uint16(header[6:]) == 1 {
+		// LUKS1 keeps the header and all keyslots before the payload
+		payloadOffset := int64(binary.BigEndian.Uint32(header[104:])) * luksSectorSize
+		if payloadOffset == 0 {
+			return 0, 0, errors.New("LUKS1 header has no payload offset")
+		}
+		return 1, payloadOffset, nil
+	}
+
+	// LUKS2 has two copies of the header, so fall back to the secondary
+	// one if the primary is already damaged
+	if !bytes.HasPrefix(header, luksMagic) {
+		found := false
+		for _, offset := range luks2SecondaryOffsets {
+			if _, err := file.ReadAt(header, offset); err == nil && bytes.HasPrefix(header, luks2SecondMagic) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, 0, errors.New("no LUKS header found")
+		}
+	}
+	if version := binary.BigEndian.Uint16(header[6:]); version != 2 {
+		return 0, 0, fmt.Errorf("unsupported LUKS version %d", version)
+	}
+
+	hdrSize := int64(binary.BigEndian.Uint64(header[8:]))
+	hdrOffset := int64(binary.BigEndian.Uint64(header[256:]))
+	if hdrSize <= luks2BinaryHdrSize || hdrSize > luks2MaxHdrSize {
+		return 0, 0, fmt.Errorf("invalid LUKS2 header size %d", hdrSize)
+	}
+	jsonArea := make([]byte, hdrSize-luks2BinaryHdrSize)
+	if _, err := file.ReadAt(jsonArea, hdrOffset+luks2BinaryHdrSize); err != nil {
+		return 0, 0, err
+	}
+	var metadata luks2Metadata
+	if err := json.Unmarshal(bytes.TrimRight(jsonArea, "\x00"), &metadata); err != nil {
+		return 0, 0, fmt.Errorf("invalid LUKS2 metadata: %w", err)
+	}
+
+	// Both header copies, then every keyslot area, but never the data
+	end := 2 * hdrSize
+	for _, keyslot := range metadata.Keyslots {
+		offset, err1 := strconv.ParseInt(keyslot.Area.Offset, 10, 64)
+		size, err2 := strconv.ParseInt(keyslot.Area.Size, 10, 64)
+		if err1 == nil && err2 == nil && offset+size > end {
+			end = offset + size
+		}
+	}
+	for _, segment := range metadata.Segments {
+		if offset, err := strconv.ParseInt(segment.Offset, 10, 64); err == nil && offset > 0 && offset < end {
+			return 0, 0, fmt.Errorf("keyslot area overlaps the data segment at %d", offset)
+		}
+	}
+	return 2, end, nil
+}
+
+// destroyLuksHeader overwrites the LUKS header, its secondary copy and all
+// keyslots of the volume at path with fake header data. Without them the
+// master key is gone, so the encrypted data can't be recovered.
+func destroyLuksHeader(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	version, end, err := luksHeaderArea(file)
+	if err != nil {
+		return err
+	}
+	printVerbose(verboseDetails, "'%s': LUKS%d, header and keyslots take %d KB\n", path, version, end/1024)
+
+	buffer := getBlock()
+	defer putBlock(buffer)
+	fakeData := newFakeReader()
+	defer fakeData.Close()
+	for offset := int64(0); offset < end; offset += int64(len(buffer)) {
+		length := int64(len(buffer))
+		if remaining := end - offset; remaining < length {
+			length = remaining
+		}
+		fakeData.Read(buffer[:length])
+		if _, err := writeAtWithRetry(file, buffer[:length], offset); err != nil {
+			return err
+		}
+	}
+	return syncFile(file)
+}
+
+// wipeLuksHeader destroys the LUKS header of the device at devicePath,
+// after the user confirms by typing the path again.
+func wipeLuksHeader(devicePath string) {
+	if !confirmDevice(devicePath) {
+		countFailure(devicePath, nil)
+		return
+	}
+	if err := destroyLuksHeader(devicePath); err != nil {
+		printError("cannot destroy LUKS header of '%s': %s\n", devicePath, getSimpleError(err))
+		countFailure(devicePath, err)
+		return
+	}
+	printSummary("destroyed LUKS header and keyslots of '%s'\n", devicePath)
+}
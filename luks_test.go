@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeLuksImage writes an image of size bytes filled with 0xaa, with the
+// given headers placed at their offsets
+func writeLuksImage(t *testing.T, size int, headers map[int][]byte) string {
+	image := bytes.Repeat([]byte{0xaa}, size)
+	for offset, header := range headers {
+		copy(image[offset:], header)
+	}
+	path := filepath.Join(t.TempDir(), "luks.img")
+	if err := os.WriteFile(path, image, 0644); err != nil {
+		t.Fatalf("Failed to create image: %v", err)
+	}
+	return path
+}
+
+// luks2Header returns a LUKS2 binary header followed by its JSON area
+func luks2Header(magic []byte, hdrSize, hdrOffset uint64, metadata string) []byte {
+	header := make([]byte, hdrSize)
+	copy(header, magic)
+	binary.BigEndian.PutUint16(header[6:], 2)
+	binary.BigEndian.PutUint64(header[8:], hdrSize)
+	binary.BigEndian.PutUint64(header[256:], hdrOffset)
+	copy(header[luks2BinaryHdrSize:], metadata)
+	return header
+}
+
+// TestLuksHeaderArea tests finding the header and keyslot area of LUKS1 and LUKS2 volumes
+func TestLuksHeaderArea(t *testing.T) {
+	luks1 := make([]byte, 592)
+	copy(luks1, luksMagic)
+	binary.BigEndian.PutUint16(luks1[6:], 1)
+	binary.BigEndian.PutUint32(luks1[104:], 4096) // Payload at sector 4096
+
+	metadata := `{"keyslots":{"0":{"area":{"offset":"32768","size":"258048"}}},"segments":{"0":{"offset":"16777216"}}}`
+	primary := luks2Header(luksMagic, 0x4000, 0, metadata)
+	secondary := luks2Header(luks2SecondMagic, 0x4000, 0x4000, metadata)
+
+	tests := []struct {
+		name    string
+		headers map[int][]byte
+		version int
+		end     int64
+	}{
+		{"LUKS1", map[int][]byte{0: luks1}, 1, 4096 * 512},
+		{"LUKS2", map[int][]byte{0: primary, 0x4000: secondary}, 2, 32768 + 258048},
+		{"LUKS2 secondary only", map[int][]byte{0x4000: secondary}, 2, 32768 + 258048},
+	}
+
+	for _, test := range tests {
+		path := writeLuksImage(t, 4*1024*1024, test.headers)
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("Failed to open image: %v", err)
+		}
+		version, end, err := luksHeaderArea(file)
+		file.Close()
+		if err != nil {
+			t.Errorf("%s: luksHeaderArea failed: %v", test.name, err)
+			continue
+		}
+		if version != test.version || end != test.end {
+			t.Errorf("%s: expected LUKS%d ending at %d, got LUKS%d ending at %d", test.name, test.version, test.end, version, end)
+		}
+	}
+
+	path := writeLuksImage(t, 64*1024, nil)
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open image: %v", err)
+	}
+	defer file.Close()
+	if _, _, err := luksHeaderArea(file); err == nil {
+		t.Error("Image without a LUKS header should be rejected")
+	}
+}
+
+// TestDestroyLuksHeader tests that the header area is overwritten and the data is not
+func TestDestroyLuksHeader(t *testing.T) {
+	luks1 := make([]byte, 592)
+	copy(luks1, luksMagic)
+	binary.BigEndian.PutUint16(luks1[6:], 1)
+	binary.BigEndian.PutUint32(luks1[104:], 8) // Payload at 4K
+	path := writeLuksImage(t, 16*1024, map[int][]byte{0: luks1})
+
+	if err := destroyLuksHeader(path); err != nil {
+		t.Fatalf("destroyLuksHeader failed: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read image: %v", err)
+	}
+	if bytes.Equal(content[200:592], luks1[200:592]) {
+		t.Error("Keyslots should be overwritten")
+	}
+	if !bytes.Equal(content[4096:], bytes.Repeat([]byte{0xaa}, 12*1024)) {
+		t.Error("Data area should be left as is")
+	}
+}
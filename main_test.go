@@ -1,11 +1,18 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"io"
 	"math"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
+	"unsafe"
 )
 
 // TestGetFakeHeader tests that buffers are exactly 4K and have sufficient entropy
@@ -66,6 +73,65 @@ func TestBufferEntropy(t *testing.T) {
 	}
 }
 
+// TestRenderTemplateRepetition tests that {N} repeats the directive or byte before it
+func TestRenderTemplateRepetition(t *testing.T) {
+	stream := newPaddingStream()
+	for template, expected := range map[string]string{
+		"\\00{3}x": "\x00\x00\x00x",
+		"a\\41{2}": "aAA",
+		"%d{0}b":   "b",
+		"{3}%z{2}": "{3}%z{2}",
+		"\\41{x}":  "A{x}",
+		"\\41{2":   "A{2",
+	} {
+		if got := string(renderTemplate(nil, template, stream)); got != expected {
+			t.Errorf("Template %q rendered %q, expected %q", template, got, expected)
+		}
+	}
+
+	rendered := renderTemplate(nil, "id=%h{32};", stream)
+	if len(rendered) != 3+32+1 || rendered[len(rendered)-1] != ';' {
+		t.Errorf("Expected 32 hex digits, got %q", rendered)
+	}
+	for _, c := range rendered[3:35] {
+		if !strings.ContainsRune("0123456789abcdef", rune(c)) {
+			t.Errorf("Expected hex digit, got %q", c)
+		}
+	}
+	if rendered := renderTemplate(nil, "%l{100000}", stream); len(rendered) != bufferSize {
+		t.Errorf("Repetition should be capped at a block, got %d bytes", len(rendered))
+	}
+}
+
+// TestRenderTemplateChoice tests that groups render one of their alternatives
+func TestRenderTemplateChoice(t *testing.T) {
+	stream := newPaddingStream()
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		rendered := string(renderTemplate(nil, "vids(H264|XVID|)!", stream))
+		switch rendered {
+		case "vidsH264!", "vidsXVID!", "vids!":
+			seen[rendered] = true
+		default:
+			t.Fatalf("Unexpected rendering %q", rendered)
+		}
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected all three alternatives, got %v", seen)
+	}
+
+	for template, expected := range map[string]string{
+		"pam_unix(cron:session)": "pam_unix(cron:session)",
+		"f(x) (a(b|b)c|a(b|b)c)": "f(x) abc",
+		"unclosed (a|b":          "unclosed (a|b",
+		"(\\41{2}|\\41\\41)":     "AA",
+	} {
+		if got := string(renderTemplate(nil, template, stream)); got != expected {
+			t.Errorf("Template %q rendered %q, expected %q", template, got, expected)
+		}
+	}
+}
+
 // TestTruncateFile tests the truncateFile function separately
 func TestTruncateFile(t *testing.T) {
 	tempDir := t.TempDir()
@@ -110,8 +176,8 @@ func TestTruncateFile(t *testing.T) {
 	}
 }
 
-// TestOverwriteWithRandomData tests file overwriting and truncation
-func TestOverwriteWithRandomData(t *testing.T) {
+// TestOverwriteAndTruncate tests file overwriting and truncation
+func TestOverwriteAndTruncate(t *testing.T) {
 	// Create temp directory
 	tempDir := t.TempDir()
 	testFile := filepath.Join(tempDir, "test.txt")
@@ -124,9 +190,9 @@ func TestOverwriteWithRandomData(t *testing.T) {
 	}
 
 	// Test overwrite function
-	success := overwriteWithRandomData(testFile)
+	success := overwriteAndTruncate(testFile)
 	if !success {
-		t.Error("overwriteWithRandomData should succeed")
+		t.Error("overwriteAndTruncate should succeed")
 	}
 
 	// Check file exists and is truncated (size 0)
@@ -150,6 +216,146 @@ func TestOverwriteWithRandomData(t *testing.T) {
 	}
 }
 
+// TestOverwriteFileExactSize tests that overwriting never grows the file
+func TestOverwriteFileExactSize(t *testing.T) {
+	tempDir := t.TempDir()
+
+	for _, size := range []int{1, 4095, 5000, 3*bufferSize + 17} {
+		testFile := filepath.Join(tempDir, "test.bin")
+		original := []byte(strings.Repeat("A", size))
+		if err := os.WriteFile(testFile, original, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		if !overwriteFile(testFile) {
+			t.Fatalf("overwriteFile should succeed for %d bytes", size)
+		}
+
+		content, err := os.ReadFile(testFile)
+		if err != nil {
+			t.Fatalf("Failed to read test file: %v", err)
+		}
+		if len(content) != size {
+			t.Errorf("File of %d bytes should keep its size, got %d", size, len(content))
+		}
+		if size > 16 && string(content) == string(original) {
+			t.Errorf("File of %d bytes should be overwritten", size)
+		}
+	}
+}
+
+// TestIsTransientError tests which write errors are retried
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{syscall.EINTR, true},
+		{syscall.EAGAIN, true},
+		{&os.PathError{Op: "write", Path: "f", Err: syscall.ETIMEDOUT}, true},
+		{syscall.ENOSPC, false},
+		{os.ErrPermission, false},
+	}
+
+	for _, test := range tests {
+		if result := isTransientError(test.err); result != test.expected {
+			t.Errorf("isTransientError(%v) = %v, want %v", test.err, result, test.expected)
+		}
+	}
+}
+
+// TestMakeWritable tests that read-only files become writable
+func TestMakeWritable(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "readonly.txt")
+
+	if err := os.WriteFile(testFile, []byte("test"), 0444); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	info, err := os.Lstat(testFile)
+	if err != nil {
+		t.Fatalf("Failed to stat test file: %v", err)
+	}
+
+	makeWritable(testFile, info)
+
+	info, err = os.Lstat(testFile)
+	if err != nil {
+		t.Fatalf("Failed to stat test file: %v", err)
+	}
+	if info.Mode().Perm()&0200 == 0 {
+		t.Errorf("File should be owner-writable, mode is %v", info.Mode().Perm())
+	}
+}
+
+// TestWaitBusyFlag tests the optional-value -wait-busy flag
+func TestWaitBusyFlag(t *testing.T) {
+	var f waitBusyFlag
+
+	if err := f.Set("true"); err != nil || !f.enabled || f.timeout != defaultWaitBusyTimeout {
+		t.Errorf("Bare -wait-busy should enable the default timeout, got %v %v", f.enabled, f.timeout)
+	}
+	if err := f.Set("30s"); err != nil || !f.enabled || f.timeout != 30*time.Second {
+		t.Errorf("-wait-busy=30s should set a 30s timeout, got %v %v", f.enabled, f.timeout)
+	}
+	if err := f.Set("false"); err != nil || f.enabled {
+		t.Error("-wait-busy=false should disable waiting")
+	}
+	if err := f.Set("soon"); err == nil {
+		t.Error("Invalid timeout should be rejected")
+	}
+}
+
+// TestVerbosityFlags tests that -v, -vv and -vvv only ever raise the level
+func TestVerbosityFlags(t *testing.T) {
+	defer func() { verbosity = 0 }()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	registerOutputFlags(flags)
+
+	if err := flags.Parse([]string{"-vv"}); err != nil || verbosity != verboseDetails {
+		t.Errorf("-vv should set level %d, got %d", verboseDetails, verbosity)
+	}
+	if err := flags.Parse([]string{"-v"}); err != nil || verbosity != verboseDetails {
+		t.Errorf("-v after -vv should keep level %d, got %d", verboseDetails, verbosity)
+	}
+	if err := flags.Parse([]string{"-vvv=true"}); err != nil || verbosity != verboseWrites {
+		t.Errorf("-vvv should set level %d, got %d", verboseWrites, verbosity)
+	}
+	if err := flags.Parse([]string{"-v=maybe"}); err == nil {
+		t.Error("Invalid -v value should be rejected")
+	}
+}
+
+// TestCheckHardLinks tests that hard linked files need -force-hardlinked
+func TestCheckHardLinks(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.txt")
+	linkFile := filepath.Join(tempDir, "link.txt")
+
+	if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	info, _ := os.Lstat(testFile)
+	if !checkHardLinks(testFile, info) {
+		t.Error("File without other links should pass")
+	}
+
+	if err := os.Link(testFile, linkFile); err != nil {
+		t.Skipf("Hard links not supported: %v", err)
+	}
+	info, _ = os.Lstat(testFile)
+	if checkHardLinks(testFile, info) {
+		t.Error("Hard linked file should be refused without -force-hardlinked")
+	}
+
+	*forceLinked = true
+	defer func() { *forceLinked = false }()
+	if !checkHardLinks(testFile, info) {
+		t.Error("Hard linked file should pass with -force-hardlinked")
+	}
+}
+
 // TestCollectPathsRecursive tests recursive vs non-recursive behavior
 func TestCollectPathsRecursive(t *testing.T) {
 	// Create temp directory structure
@@ -210,6 +416,103 @@ func TestCollectPathsRecursive(t *testing.T) {
 	}
 }
 
+// TestGroupByDevice tests that files on one filesystem share a queue
+func TestGroupByDevice(t *testing.T) {
+	tempDir := t.TempDir()
+	var files []string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		file := filepath.Join(tempDir, name)
+		os.WriteFile(file, []byte("content"), 0644)
+		files = append(files, file)
+	}
+
+	groups := groupByDevice(files)
+	if len(groups) != 1 {
+		t.Fatalf("Files in one directory should share 1 device group, got %d", len(groups))
+	}
+	for i, file := range groups[0].files {
+		if file != files[i] {
+			t.Errorf("Group should keep file order, got %v", groups[0].files)
+		}
+	}
+}
+
+// TestUniqueTargets tests that uniqueTargets drops names reached twice through symlinks
+func TestUniqueTargets(t *testing.T) {
+	tempDir := t.TempDir()
+	dir := filepath.Join(tempDir, "dir")
+	os.Mkdir(dir, 0755)
+	file := filepath.Join(dir, "a.txt")
+	other := filepath.Join(tempDir, "b.txt")
+	os.WriteFile(file, []byte("content"), 0644)
+	os.WriteFile(other, []byte("content"), 0644)
+	link := filepath.Join(tempDir, "link")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Skip(err)
+	}
+
+	hardLink := filepath.Join(tempDir, "c.txt")
+	if err := os.Link(other, hardLink); err != nil {
+		t.Skip(err)
+	}
+
+	// Other hard links are names of their own, for checkHardLinks to refuse
+	paths := []string{file, other, hardLink, filepath.Join(link, "a.txt"), link, dir + "/./a.txt", filepath.Join(tempDir, "missing")}
+	got := uniqueTargets(paths)
+	want := []string{file, other, hardLink, link, filepath.Join(tempDir, "missing")}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("uniqueTargets = %q, want %q", got, want)
+	}
+}
+
+// TestResolveTargets tests that resolveTargets resolves symlinked parents but keeps a symlink argument itself
+func TestResolveTargets(t *testing.T) {
+	tempDir, _ := filepath.EvalSymlinks(t.TempDir())
+	release := filepath.Join(tempDir, "releases", "v2")
+	os.MkdirAll(filepath.Join(release, "data"), 0755)
+	os.WriteFile(filepath.Join(release, "secret.txt"), []byte("content"), 0644)
+	current := filepath.Join(tempDir, "current")
+	if err := os.Symlink(release, current); err != nil {
+		t.Skip(err)
+	}
+
+	args := []string{
+		filepath.Join(release, "data"),
+		filepath.Join(current, "data"),
+		filepath.Join(current, "..", "current", "secret.txt"),
+		current,
+		current + string(os.PathSeparator),
+		filepath.Join(tempDir, "missing", "file"),
+	}
+	got := resolveTargets(args)
+	want := []string{
+		filepath.Join(release, "data"),
+		filepath.Join(release, "secret.txt"),
+		current, // The symlink itself
+		release,
+		filepath.Join(tempDir, "missing", "file"),
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("resolveTargets = %q, want %q", got, want)
+	}
+}
+
+// TestFilesystemType tests filesystem detection for the temp directory
+func TestFilesystemType(t *testing.T) {
+	fsType, err := filesystemType(t.TempDir())
+	if err != nil {
+		t.Skipf("Filesystem detection not supported: %v", err)
+	}
+	if fsType == "" {
+		t.Error("Filesystem type should not be empty")
+	}
+	t.Logf("Temp directory filesystem: %s", fsType)
+
+	if !checkFilesystem(t.TempDir()) && !cowFilesystems[fsType] {
+		t.Error("Non copy-on-write filesystem should be allowed")
+	}
+}
+
 // TestGetSimpleError tests error message simplification
 func TestGetSimpleError(t *testing.T) {
 	tests := []struct {
@@ -220,6 +523,7 @@ func TestGetSimpleError(t *testing.T) {
 		{"mkdir test: permission denied", "Permission denied"},
 		{"remove file.txt: is a directory", "Is a directory"},
 		{"read dir: not a directory", "Not a directory"},
+		{"open f.txt: The process cannot access the file because it is being used by another process.", "Locked by another process"},
 		{"some other error", "some other error"},
 	}
 
@@ -303,10 +607,246 @@ func TestRenameToRandomName(t *testing.T) {
 			len(originalName), len(newName))
 	}
 
-	// Check filename contains only hex characters
+	// Check filename contains only alphanumeric characters
 	for _, c := range newName {
-		if !strings.ContainsRune("0123456789abcdef", c) {
-			t.Errorf("New filename should only contain hex characters, found %c in %s", c, newName)
+		if !strings.ContainsRune("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ", c) {
+			t.Errorf("New filename should only contain alphanumeric characters, found %c in %s", c, newName)
+		}
+	}
+}
+
+// TestRenameNoReplace tests that random renames never replace an existing
+// file and that short names get longer random ones
+func TestRenameNoReplace(t *testing.T) {
+	tempDir := t.TempDir()
+	file := filepath.Join(tempDir, "a")
+	taken := filepath.Join(tempDir, "b")
+	os.WriteFile(file, []byte("wiped"), 0644)
+	os.WriteFile(taken, []byte("unrelated"), 0644)
+
+	if err := renameNoReplace(file, taken); !errors.Is(err, os.ErrExist) {
+		t.Errorf("renameNoReplace onto an existing file: %v, want ErrExist", err)
+	}
+	if data, _ := os.ReadFile(taken); string(data) != "unrelated" {
+		t.Errorf("existing file was replaced, has %q", data)
+	}
+
+	newPath := renameToRandomName(file)
+	if len(filepath.Base(newPath)) != minRandomName {
+		t.Errorf("short name renamed to '%s', want %d characters", filepath.Base(newPath), minRandomName)
+	}
+}
+
+// TestNameCharset tests the characters and length of random names with
+// -name-charset and -name-length
+func TestNameCharset(t *testing.T) {
+	defer func() { *nameCharset, *nameLength = "mixed", 0 }()
+	for charset, chars := range map[string]string{
+		"mixed": "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ",
+		"lower": "0123456789abcdefghijklmnopqrstuvwxyz",
+		"hex":   "0123456789abcdef",
+	} {
+		*nameCharset = charset
+		name := randomName(1000)
+		if strings.Trim(name, chars) != "" {
+			t.Errorf("-name-charset %s: name has characters outside %s: %s", charset, chars, name)
+		}
+	}
+
+	*nameLength = 20
+	file := filepath.Join(t.TempDir(), "report.pdf")
+	os.WriteFile(file, []byte("content"), 0644)
+	if newPath := renameToRandomName(file); len(filepath.Base(newPath)) != 20 {
+		t.Errorf("-name-length 20 renamed to '%s'", filepath.Base(newPath))
+	}
+}
+
+// TestRenameToMaxName tests renaming to the filesystem's maximum name length
+func TestRenameToMaxName(t *testing.T) {
+	tempDir := t.TempDir()
+	originalFile := filepath.Join(tempDir, "test.txt")
+
+	err := os.WriteFile(originalFile, []byte("test content"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	*maxName = true
+	defer func() { *maxName = false }()
+
+	newPath := renameToRandomName(originalFile)
+
+	if _, err := os.Stat(newPath); err != nil {
+		t.Fatalf("New file should exist at %s: %v", newPath, err)
+	}
+
+	newName := filepath.Base(newPath)
+	if len(newName) != maxNameLength(tempDir) {
+		t.Errorf("New filename should have max length %d, got %d", maxNameLength(tempDir), len(newName))
+	}
+}
+
+// TestRenameNameTooLong tests that a name refused as too long is retried
+// shorter, as on eCryptfs, which reports 255 but takes 143
+func TestRenameNameTooLong(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("NTFS names are limited by the path length")
+	}
+	tempDir := t.TempDir()
+	file := filepath.Join(tempDir, "test.txt")
+	os.WriteFile(file, []byte("content"), 0644)
+
+	nameLimits.Store(tempDir, 1000) // More than any filesystem takes
+	defer nameLimits.Delete(tempDir)
+	*maxName = true
+	defer func() { *maxName = false }()
+
+	newPath := renameToRandomName(file)
+	if _, err := os.Stat(newPath); err != nil || newPath == file {
+		t.Fatalf("rename with too long names failed: %v", err)
+	}
+	if length := len(filepath.Base(newPath)); length <= len("test.txt") || length > maxNameLength(tempDir) {
+		t.Errorf("renamed to %d characters, want up to %d", length, maxNameLength(tempDir))
+	}
+	if limit := nameLimit(tempDir); limit >= 1000 || limit < maxNameLength(tempDir) {
+		t.Errorf("learned name limit %d, want below 1000 and at least %d", limit, maxNameLength(tempDir))
+	}
+}
+
+// TestScrubFileTimes tests that -scrub-times resets modification time
+func TestScrubFileTimes(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.txt")
+
+	err := os.WriteFile(testFile, []byte("test content"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	*scrubEpoch = 1000000000
+	defer func() { *scrubEpoch = -1 }()
+
+	scrubFileTimes(testFile)
+
+	stat, err := os.Stat(testFile)
+	if err != nil {
+		t.Fatalf("Failed to stat test file: %v", err)
+	}
+	if stat.ModTime().Unix() != 1000000000 {
+		t.Errorf("Modification time should be 1000000000, got %d", stat.ModTime().Unix())
+	}
+}
+
+// TestSplitXattrNames tests parsing of NUL-separated attribute lists
+func TestSplitXattrNames(t *testing.T) {
+	names := splitXattrNames([]byte("user.a\x00security.selinux\x00system.posix_acl_access\x00"))
+
+	expected := []string{"user.a", "security.selinux", "system.posix_acl_access"}
+	if len(names) != len(expected) {
+		t.Fatalf("Expected %d names, got %d: %v", len(expected), len(names), names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("Name %d = %q, want %q", i, names[i], expected[i])
+		}
+	}
+
+	if len(splitXattrNames(nil)) != 0 {
+		t.Error("Empty list should have no names")
+	}
+}
+
+// TestAlignedBuffer tests that O_DIRECT buffers are block aligned
+func TestAlignedBuffer(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		buf := alignedBuffer(bufferSize)
+		if len(buf) != bufferSize {
+			t.Errorf("Expected buffer size %d, got %d", bufferSize, len(buf))
+		}
+		if uintptr(unsafe.Pointer(&buf[0]))%directIOAlignment != 0 {
+			t.Errorf("Buffer is not aligned to %d bytes", directIOAlignment)
+		}
+	}
+}
+
+// TestUniqueFilesystems tests that directories on one filesystem are filled once
+func TestUniqueFilesystems(t *testing.T) {
+	tempDir := t.TempDir()
+	sub := filepath.Join(tempDir, "sub")
+	link := filepath.Join(tempDir, "link")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Symlink(sub, link); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+
+	unique := uniqueFilesystems([]string{tempDir, sub, link})
+	if len(unique) != 1 || unique[0] != tempDir {
+		t.Errorf("Expected only %s, got %v", tempDir, unique)
+	}
+}
+
+// TestFillBudget tests the -fill-limit and -leave-free bounds of the free-space wipe
+func TestFillBudget(t *testing.T) {
+	defer func() { fillLimitBytes, leaveFreeBytes = 0, 0 }()
+	dir := t.TempDir()
+
+	if budget := fillBudget(dir, 1<<40); budget != math.MaxInt64 {
+		t.Errorf("Without limits the budget should be unbounded, got %d", budget)
+	}
+
+	fillLimitBytes = 1024 * 1024
+	if budget := fillBudget(dir, 1000); budget != 1024*1024-1000 {
+		t.Errorf("Expected the rest of the fill limit, got %d", budget)
+	}
+	if budget := fillBudget(dir, 2*1024*1024); budget != 0 {
+		t.Errorf("Exhausted fill limit should leave nothing, got %d", budget)
+	}
+
+	fillLimitBytes = 0
+	leaveFreeBytes = math.MaxInt64 / 2
+	if budget := fillBudget(dir, 0); budget != 0 {
+		t.Errorf("Reserve larger than the disk should leave nothing, got %d", budget)
+	}
+
+	// A limited wipe writes exactly that much and cleans up after itself
+	defer func(workers int) { *parallel = workers }(*parallel)
+	*parallel = 2
+	leaveFreeBytes = 0
+	fillLimitBytes = 3*bufferSize + 100
+	wipeFreeSpace(dir)
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Errorf("Free-space wipe should remove its temp files, found %d entries", len(entries))
+	}
+}
+
+// TestParseSize tests size parsing with unit suffixes
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"4096", 4096},
+		{"4K", 4096},
+		{"1m", 1024 * 1024},
+		{"4MiB", 4 * 1024 * 1024},
+		{"200G", 200 * 1024 * 1024 * 1024},
+		{"1TB", 1024 * 1024 * 1024 * 1024},
+	}
+
+	for _, test := range tests {
+		result, err := parseSize(test.input)
+		if err != nil {
+			t.Errorf("parseSize(%q) returned error: %v", test.input, err)
+		} else if result != test.expected {
+			t.Errorf("parseSize(%q) = %d, want %d", test.input, result, test.expected)
+		}
+	}
+
+	for _, input := range []string{"", "M", "-1K", "1X", "abc"} {
+		if _, err := parseSize(input); err == nil {
+			t.Errorf("parseSize(%q) should fail", input)
 		}
 	}
 }
@@ -340,6 +880,29 @@ func (e *mockError) Error() string {
 	return e.msg
 }
 
+// TestPrintStatusQuiet tests that -q silences status messages
+func TestPrintStatusQuiet(t *testing.T) {
+	defer func(stdout *os.File) {
+		os.Stdout = stdout
+		*quiet = false
+	}(os.Stdout)
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = writer
+
+	printStatus("shown %d\n", 1)
+	*quiet = true
+	printStatus("hidden %d\n", 2)
+	writer.Close()
+
+	output, _ := io.ReadAll(reader)
+	if string(output) != "shown 1\n" {
+		t.Errorf("Expected only the message printed before -q, got %q", output)
+	}
+}
+
 // calculateEntropy calculates Shannon entropy of byte data
 // Returns value between 0 (no randomness) and 8 (perfect randomness for bytes)
 func calculateEntropy(data []byte) float64 {
@@ -365,4 +928,4 @@ func calculateEntropy(data []byte) float64 {
 	}
 
 	return entropy
-}
\ No newline at end of file
+}
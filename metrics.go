@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+// metrics counts what the target counts don't: bytes written and targets
+// waiting for a worker
+var metrics struct {
+	bytesOverwritten atomic.Int64
+	queueDepth       atomic.Int64
+}
+
+// metric is a value served on /metrics
+type metric struct {
+	name  string
+	kind  string // Prometheus type: counter or gauge
+	help  string
+	value func() int64
+}
+
+// servedMetrics are the metrics on /metrics, in order
+var servedMetrics = []metric{
+	{"wipefile_targets_wiped_total", "counter", "Targets wiped.", wipeResults.wiped.Load},
+	{"wipefile_targets_failed_total", "counter", "Targets that could not be wiped, including those denied.", wipeResults.failed.Load},
+	{"wipefile_targets_denied_total", "counter", "Targets that could not be wiped for lack of permission.", wipeResults.denied.Load},
+	{"wipefile_targets_missing_total", "counter", "Targets that did not exist.", wipeResults.missing.Load},
+	{"wipefile_errors_total", "counter", "Errors reported.", wipeResults.errors.Load},
+	{"wipefile_bytes_overwritten_total", "counter", "Bytes written over files, devices and free space.", metrics.bytesOverwritten.Load},
+	{"wipefile_queue_depth", "gauge", "Targets waiting for a worker.", metrics.queueDepth.Load},
+}
+
+// serveMetrics serves /metrics in the Prometheus text format on addr for
+// as long as wipefile runs
+func serveMetrics(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", writeMetrics)
+	go http.Serve(listener, mux)
+	printVerbose(verboseDetails, "serving metrics on http://%s/metrics\n", listener.Addr())
+	return nil
+}
+
+// writeMetrics answers a scrape of /metrics
+func writeMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, m := range servedMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", m.name, m.help, m.name, m.kind, m.name, m.value())
+	}
+}
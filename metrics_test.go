@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestWriteMetrics tests the Prometheus text format of /metrics
+func TestWriteMetrics(t *testing.T) {
+	bytesOverwritten := metrics.bytesOverwritten.Swap(8192)
+	metrics.queueDepth.Store(3)
+	defer func() {
+		metrics.bytesOverwritten.Store(bytesOverwritten)
+		metrics.queueDepth.Store(0)
+	}()
+
+	recorder := httptest.NewRecorder()
+	writeMetrics(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+
+	for _, expected := range []string{
+		"# TYPE wipefile_bytes_overwritten_total counter\nwipefile_bytes_overwritten_total 8192\n",
+		"# TYPE wipefile_queue_depth gauge\nwipefile_queue_depth 3\n",
+		"# HELP wipefile_targets_wiped_total Targets wiped.\n",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, body)
+		}
+	}
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Unexpected content type %q", recorder.Header().Get("Content-Type"))
+	}
+}
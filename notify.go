@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// notifyTimeout is how long the -notify-url webhook gets to answer
+const notifyTimeout = 30 * time.Second
+
+// notifyFinished sends the summary of a finished run, with its mode
+// (files, device or free-space) added, to the -notify-url and
+// -notify-cmd. Failures are reported but don't change the exit code.
+func notifyFinished(mode string, summary map[string]interface{}) {
+	if *notifyURL == "" && *notifyCmd == "" {
+		return
+	}
+	host, _ := os.Hostname()
+	summary["mode"] = mode
+	summary["host"] = host
+	summary["started"] = runStarted.UTC().Format(time.RFC3339)
+	summary["finished"] = time.Now().UTC().Format(time.RFC3339)
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return
+	}
+
+	if *notifyURL != "" {
+		if err := postNotification(*notifyURL, body); err != nil {
+			printError("cannot notify '%s': %s\n", *notifyURL, getSimpleError(err))
+		}
+	}
+	if *notifyCmd != "" {
+		if err := runNotifyCommand(*notifyCmd, body, summary["exit_code"]); err != nil {
+			printError("notify command failed: %s\n", getSimpleError(err))
+		}
+	}
+}
+
+// postNotification POSTs body as JSON to url
+func postNotification(url string, body []byte) error {
+	client := &http.Client{Timeout: notifyTimeout}
+	response, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("server answered %s", response.Status)
+	}
+	return nil
+}
+
+// runNotifyCommand runs command in the shell with body on its standard
+// input and the exit code in WIPEFILE_EXIT_CODE
+func runNotifyCommand(command string, body []byte, code interface{}) error {
+	cmd := shellCommand(command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "WIPEFILE_EXIT_CODE="+fmt.Sprint(code))
+	return cmd.Run()
+}
+
+// freeSpaceSummary returns the summary of a free-space wipe of
+// filesystems, which counts errors rather than targets
+func freeSpaceSummary(filesystems int, code int) map[string]interface{} {
+	return map[string]interface{}{
+		"filesystems": filesystems, "errors": wipeResults.errors.Load(), "exit_code": code,
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestNotifyFinished tests that the summary reaches the webhook and the
+// command
+func TestNotifyFinished(t *testing.T) {
+	var posted map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON POST, got %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		json.Unmarshal(body, &posted)
+	}))
+	defer server.Close()
+
+	output := filepath.Join(t.TempDir(), "summary.json")
+	*notifyURL = server.URL
+	*notifyCmd = `cat > "` + output + `"`
+	if runtime.GOOS == "windows" {
+		*notifyCmd = ""
+	}
+	defer func() {
+		*notifyURL = ""
+		*notifyCmd = ""
+	}()
+	notifyFinished("files", map[string]interface{}{"wiped": 3, "exit_code": 2})
+
+	if posted["mode"] != "files" || posted["wiped"] != 3.0 || posted["exit_code"] != 2.0 || posted["finished"] == nil {
+		t.Errorf("Unexpected summary posted: %v", posted)
+	}
+	if *notifyCmd == "" {
+		return
+	}
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Expected the command to get the summary: %v", err)
+	}
+	var summary map[string]interface{}
+	if err := json.Unmarshal(content, &summary); err != nil || summary["exit_code"] != 2.0 {
+		t.Errorf("Unexpected summary on the command's input: %q", content)
+	}
+}
+
+// TestPostNotificationStatus tests that a webhook answering with an error
+// status is reported
+func TestPostNotificationStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := postNotification(server.URL, []byte("{}")); err == nil {
+		t.Errorf("Expected an error for status 500")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build unix
+
+package main
+
+import "os/exec"
+
+// shellCommand returns command run by sh
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("/bin/sh", "-c", command)
+}
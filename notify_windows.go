@@ -0,0 +1,8 @@
+package main
+
+import "os/exec"
+
+// shellCommand returns command run by cmd.exe
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("cmd", "/C", command)
+}
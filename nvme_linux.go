@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	nvmeIoctlID       = 0x4e40     // _IO('N', 0x40)
+	nvmeIoctlAdminCmd = 0xc0484e41 // _IOWR('N', 0x41, struct nvme_admin_cmd)
+
+	nvmeAdminGetLogPage = 0x02
+	nvmeAdminIdentify   = 0x06
+	nvmeAdminFormatNVM  = 0x80
+	nvmeAdminSanitize   = 0x84
+
+	nvmeLogSanitize    = 0x81
+	nvmeNamespaceAll   = 0xffffffff
+	nvmeSanitizePoll   = progressInterval
+	nvmeFormatTimeout  = 12 * time.Hour
+	nvmeCommandTimeout = 30 * time.Second
+
+	// Sanitize status (SSTAT bits 2:0)
+	nvmeSanitizeNever      = 0
+	nvmeSanitizeDone       = 1
+	nvmeSanitizeInProgress = 2
+	nvmeSanitizeFailed     = 3
+	nvmeSanitizeDoneNoDeal = 4
+)
+
+// nvmeSanitizeActions maps -sanitize-action names to SANACT values
+var nvmeSanitizeActions = map[string]uint32{
+	"block":     2,
+	"overwrite": 3,
+	"crypto":    4,
+}
+
+// nvmeAdminCmd is struct nvme_admin_cmd from <linux/nvme_ioctl.h>
+type nvmeAdminCmd struct {
+	Opcode      uint8
+	Flags       uint8
+	Rsvd1       uint16
+	Nsid        uint32
+	Cdw2        uint32
+	Cdw3        uint32
+	Metadata    uint64
+	Addr        uint64
+	MetadataLen uint32
+	DataLen     uint32
+	Cdw10       uint32
+	Cdw11       uint32
+	Cdw12       uint32
+	Cdw13       uint32
+	Cdw14       uint32
+	Cdw15       uint32
+	TimeoutMs   uint32
+	Result      uint32
+}
+
+// nvmeAdmin sends an admin command to the controller of device. A positive
+// return from the ioctl is the NVMe status code of a failed command.
+func nvmeAdmin(device *os.File, cmd *nvmeAdminCmd, data []byte) error {
+	if data != nil {
+		cmd.Addr = uint64(uintptr(unsafe.Pointer(&data[0])))
+		cmd.DataLen = uint32(len(data))
+	}
+	status, _, errno := syscall.Syscall(syscall.SYS_IOCTL, device.Fd(), nvmeIoctlAdminCmd, uintptr(unsafe.Pointer(cmd)))
+	if errno != 0 {
+		return errno
+	}
+	if status != 0 {
+		return fmt.Errorf("NVMe admin command 0x%02x failed with status 0x%x", cmd.Opcode, status)
+	}
+	return nil
+}
+
+// nvmeNamespaces returns the device nodes of all namespaces of the NVMe
+// controller of devicePath, which is the controller itself (/dev/nvme0),
+// or one of its namespaces or their partitions.
+func nvmeNamespaces(devicePath string) ([]string, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(devicePath, &stat); err != nil {
+		return nil, err
+	}
+	var controller string
+	switch stat.Mode & syscall.S_IFMT {
+	case syscall.S_IFCHR:
+		sysPath, err := filepath.EvalSymlinks("/sys/dev/char/" + deviceNumber(uint64(stat.Rdev)))
+		if err != nil {
+			return nil, err
+		}
+		controller = filepath.Base(sysPath)
+	case syscall.S_IFBLK:
+		sysPath, err := sysBlockPath(uint64(stat.Rdev))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(filepath.Join(sysPath, "partition")); err == nil {
+			sysPath = filepath.Dir(sysPath)
+		}
+		// The device of a namespace is its controller
+		device, err := filepath.EvalSymlinks(filepath.Join(sysPath, "device"))
+		if err != nil {
+			return nil, err
+		}
+		controller = filepath.Base(device)
+	default:
+		return nil, fmt.Errorf("'%s' is not a device", devicePath)
+	}
+	if !strings.HasPrefix(controller, "nvme") {
+		return nil, fmt.Errorf("'%s' is not an NVMe device", devicePath)
+	}
+	return controllerNamespaces("/sys/class/nvme", controller), nil
+}
+
+// controllerNamespaces returns the /dev nodes of the namespaces sysfs lists
+// in classDir for controller, such as nvme0n1 and nvme0n2 for nvme0.
+func controllerNamespaces(classDir, controller string) []string {
+	matches, _ := filepath.Glob(filepath.Join(classDir, controller, controller+"n*"))
+	var namespaces []string
+	for _, match := range matches {
+		namespaces = append(namespaces, "/dev/"+filepath.Base(match))
+	}
+	return namespaces
+}
+
+// nvmeSanitize starts a sanitize operation on the controller of devicePath
+// and polls the sanitize log until it finishes.
+func nvmeSanitize(devicePath string, action string) error {
+	sanact, ok := nvmeSanitizeActions[action]
+	if !ok {
+		return fmt.Errorf("unknown sanitize action '%s'", action)
+	}
+	device, err := os.OpenFile(devicePath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer device.Close()
+
+	cmd := nvmeAdminCmd{
+		Opcode:    nvmeAdminSanitize,
+		Cdw10:     sanact,
+		TimeoutMs: uint32(nvmeCommandTimeout / time.Millisecond),
+	}
+	if action == "overwrite" {
+		cmd.Cdw10 |= 1 << 4 // One overwrite pass
+	}
+	if err := nvmeAdmin(device, &cmd, nil); err != nil {
+		return err
+	}
+
+	// The command returns right away, the controller sanitizes in the
+	// background and reports progress in the sanitize log
+	for {
+		time.Sleep(nvmeSanitizePoll)
+		progress, status, err := nvmeSanitizeStatus(device)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case nvmeSanitizeDone, nvmeSanitizeDoneNoDeal:
+			return nil
+		case nvmeSanitizeFailed:
+			return errors.New("controller reports the sanitize operation failed")
+		case nvmeSanitizeNever:
+			return errors.New("controller did not start the sanitize operation")
+		}
+		printStatus("sanitizing '%s': %d%%\n", devicePath, progress*100/65536)
+	}
+}
+
+// nvmeSanitizeStatus reads the sanitize log, returning the progress out of
+// 65536 and the status of the most recent sanitize operation.
+func nvmeSanitizeStatus(device *os.File) (uint32, uint16, error) {
+	data := make([]byte, 512)
+	cmd := nvmeAdminCmd{
+		Opcode:    nvmeAdminGetLogPage,
+		Nsid:      nvmeNamespaceAll,
+		Cdw10:     nvmeLogSanitize | uint32(len(data)/4-1)<<16,
+		TimeoutMs: uint32(nvmeCommandTimeout / time.Millisecond),
+	}
+	if err := nvmeAdmin(device, &cmd, data); err != nil {
+		return 0, 0, err
+	}
+	progress := uint32(binary.LittleEndian.Uint16(data[0:]))
+	status := binary.LittleEndian.Uint16(data[2:]) & 0x7
+	return progress, status, nil
+}
+
+// nvmeFormat reformats the namespace at devicePath in its current LBA
+// format with secure erase setting ses (1 user data erase, 2 crypto erase).
+func nvmeFormat(devicePath string, ses int) error {
+	device, err := os.OpenFile(devicePath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer device.Close()
+
+	nsid, _, errno := syscall.Syscall(syscall.SYS_IOCTL, device.Fd(), nvmeIoctlID, 0)
+	if errno != 0 {
+		return fmt.Errorf("not an NVMe namespace: %w", errno)
+	}
+
+	// Keep the LBA format in use, found in FLBAS of Identify Namespace
+	identify := make([]byte, 4096)
+	cmd := nvmeAdminCmd{
+		Opcode:    nvmeAdminIdentify,
+		Nsid:      uint32(nsid),
+		TimeoutMs: uint32(nvmeCommandTimeout / time.Millisecond),
+	}
+	if err := nvmeAdmin(device, &cmd, identify); err != nil {
+		return fmt.Errorf("identify namespace: %w", err)
+	}
+	flbas := uint32(identify[26])
+	lbaf := flbas&0xf | (flbas>>5&0x3)<<12 // FLBAS bits 6:5 are the upper format bits
+
+	cmd = nvmeAdminCmd{
+		Opcode:    nvmeAdminFormatNVM,
+		Nsid:      uint32(nsid),
+		Cdw10:     lbaf | uint32(ses)<<9,
+		TimeoutMs: uint32(nvmeFormatTimeout / time.Millisecond),
+	}
+	return nvmeAdmin(device, &cmd, nil)
+}
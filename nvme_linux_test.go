@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestControllerNamespaces tests that all namespaces of an NVMe controller
+// are listed, and none of a controller with a longer number
+func TestControllerNamespaces(t *testing.T) {
+	classDir := t.TempDir()
+	for _, name := range []string{"nvme0/nvme0n1", "nvme0/nvme0n2", "nvme0/power", "nvme1/nvme1n1", "nvme10/nvme10n1"} {
+		os.MkdirAll(filepath.Join(classDir, name), 0755)
+	}
+	want := []string{"/dev/nvme0n1", "/dev/nvme0n2"}
+	if namespaces := controllerNamespaces(classDir, "nvme0"); !reflect.DeepEqual(namespaces, want) {
+		t.Errorf("controllerNamespaces(nvme0) = %q, want %q", namespaces, want)
+	}
+	want = []string{"/dev/nvme1n1"}
+	if namespaces := controllerNamespaces(classDir, "nvme1"); !reflect.DeepEqual(namespaces, want) {
+		t.Errorf("controllerNamespaces(nvme1) = %q, want %q", namespaces, want)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// NVMe admin pass-through needs the Linux NVMe ioctls.
+func nvmeSanitize(devicePath string, action string) error {
+	return errors.New("only supported on Linux")
+}
+
+func nvmeNamespaces(devicePath string) ([]string, error) {
+	return nil, errors.New("only supported on Linux")
+}
+
+func nvmeFormat(devicePath string, ses int) error {
+	return errors.New("only supported on Linux")
+}
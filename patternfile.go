@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// customPatternClass is the class of user-defined patterns that don't name one
+const customPatternClass = "custom"
+
+// patternFile is a user-defined pattern file, a small YAML subset:
+//
+//	replace: false
+//	weights:
+//	  media: 60
+//	  acme-invoice: 5
+//	patterns:
+//	  - name: acme-invoice
+//	    class: documents
+//	    templates:
+//	      - 'ACME Corp. Invoice %d%d%d%d%d\0a'
+type patternFile struct {
+	replace  bool
+	weights  map[string]float64
+	patterns []headerPattern
+	// positions are where each template of each pattern starts in the file
+	positions [][]filePosition
+}
+
+// filePosition is a 1-based line and column in a pattern file
+type filePosition struct {
+	line   int
+	column int
+}
+
+// patternsDir returns the directory pattern files are loaded from by
+// default, ~/.config/wipefile/patterns.d on Linux
+func patternsDir() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "wipefile", "patterns.d")
+}
+
+// loadPatternFiles merges the patterns of the files in patternsDir, then of
+// extra if set, into fakeHeaderPatterns, and their weights into
+// patternWeights. A pattern replaces an earlier one of the same name, and a
+// file with "replace: true" drops the built-ins.
+func loadPatternFiles(extra string) error {
+	paths := patternFilePaths()
+	if extra != "" {
+		paths = append(paths, extra)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	var custom []headerPattern
+	replace := false
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		file, err := parsePatternFile(string(data))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		replace = replace || file.replace
+		for name, weight := range file.weights {
+			patternWeights[name] = weight
+		}
+		for _, pattern := range file.patterns {
+			custom = mergePattern(custom, pattern)
+		}
+	}
+
+	var patterns []headerPattern
+	if !replace {
+		patterns = append(patterns, fakeHeaderPatterns...)
+	}
+	for _, pattern := range custom {
+		patterns = mergePattern(patterns, pattern)
+	}
+	if len(patterns) == 0 {
+		return fmt.Errorf("pattern files replace the built-in patterns but define none")
+	}
+	fakeHeaderPatterns = patterns
+	headerTemplates, headerWeights = patternTemplates(patterns), nil
+	return nil
+}
+
+// patternFilePaths returns the pattern files in patternsDir
+func patternFilePaths() []string {
+	dir := patternsDir()
+	if dir == "" {
+		return nil
+	}
+	var paths []string
+	for _, glob := range []string{"*.yaml", "*.yml"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, glob))
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// mergePattern adds pattern to patterns, in place of one of the same name
+func mergePattern(patterns []headerPattern, pattern headerPattern) []headerPattern {
+	for i := range patterns {
+		if patterns[i].name == pattern.name {
+			patterns[i] = pattern
+			return patterns
+		}
+	}
+	return append(patterns, pattern)
+}
+
+// parsePatternFile parses the YAML subset described at patternFile
+func parsePatternFile(data string) (patternFile, error) {
+	file := patternFile{weights: make(map[string]float64)}
+	var pattern *headerPattern
+	var positions []filePosition
+	inPatterns, inWeights, inTemplates := false, false, false
+	itemIndent := -1
+
+	finish := func() error {
+		if pattern == nil {
+			return nil
+		}
+		if pattern.name == "" {
+			return fmt.Errorf("pattern without a name")
+		}
+		if len(pattern.templates) == 0 {
+			return fmt.Errorf("pattern '%s' has no templates", pattern.name)
+		}
+		if pattern.class == "" {
+			pattern.class = customPatternClass
+		}
+		file.patterns = append(file.patterns, *pattern)
+		file.positions = append(file.positions, positions)
+		pattern, positions = nil, nil
+		return nil
+	}
+
+	for number, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		content := strings.TrimLeft(line, " ")
+		if content == "" || content[0] == '#' {
+			continue
+		}
+		indent := len(line) - len(content)
+		lineErr := func(format string, args ...interface{}) error {
+			return fmt.Errorf("line %d: %s", number+1, fmt.Sprintf(format, args...))
+		}
+
+		if indent == 0 {
+			if err := finish(); err != nil {
+				return file, err
+			}
+			inPatterns, inWeights, inTemplates = false, false, false
+			key, value, err := splitKeyValue(content)
+			if err != nil {
+				return file, lineErr("%s", err)
+			}
+			switch key {
+			case "replace":
+				if file.replace, err = strconv.ParseBool(value); err != nil {
+					return file, lineErr("replace must be true or false")
+				}
+			case "weights":
+				if value != "" {
+					return file, lineErr("weights must be a map")
+				}
+				inWeights = true
+			case "patterns":
+				if value != "" {
+					return file, lineErr("patterns must be a list")
+				}
+				inPatterns = true
+			default:
+				return file, lineErr("unknown key '%s'", key)
+			}
+			continue
+		}
+		if inWeights {
+			name, value, err := splitKeyValue(content)
+			if err != nil {
+				return file, lineErr("%s", err)
+			}
+			weight, err := strconv.ParseFloat(value, 64)
+			if err != nil || weight < 0 {
+				return file, lineErr("invalid weight '%s'", value)
+			}
+			file.weights[strings.ToLower(name)] = weight
+			continue
+		}
+		if !inPatterns {
+			return file, lineErr("unexpected indentation")
+		}
+
+		isItem := content == "-" || strings.HasPrefix(content, "- ")
+		if isItem && inTemplates && indent > itemIndent {
+			raw := strings.TrimSpace(content[1:])
+			template, err := parseScalar(raw)
+			if err != nil {
+				return file, lineErr("%s", err)
+			}
+			pattern.templates = append(pattern.templates, template)
+			positions = append(positions, filePosition{number + 1, scalarColumn(line, raw)})
+			continue
+		}
+		if isItem {
+			if itemIndent >= 0 && indent != itemIndent {
+				return file, lineErr("unexpected indentation")
+			}
+			if err := finish(); err != nil {
+				return file, lineErr("%s", err)
+			}
+			itemIndent = indent
+			pattern = &headerPattern{}
+			inTemplates = false
+			content = strings.TrimSpace(content[1:])
+			if content == "" {
+				continue
+			}
+		} else if pattern == nil || indent <= itemIndent {
+			return file, lineErr("expected a list item")
+		}
+
+		key, value, err := splitKeyValue(content)
+		if err != nil {
+			return file, lineErr("%s", err)
+		}
+		inTemplates = false
+		switch key {
+		case "name":
+			pattern.name = strings.ToLower(value)
+		case "class":
+			pattern.class = strings.ToLower(value)
+		case "templates":
+			if value != "" {
+				return file, lineErr("templates must be a list")
+			}
+			inTemplates = true
+		case "template":
+			pattern.templates = append(pattern.templates, value)
+			_, raw, _ := strings.Cut(content, ":")
+			positions = append(positions, filePosition{number + 1, scalarColumn(line, strings.TrimSpace(raw))})
+		default:
+			return file, lineErr("unknown key '%s'", key)
+		}
+	}
+	if err := finish(); err != nil {
+		return file, err
+	}
+	return file, nil
+}
+
+// scalarColumn returns the column in line where the value of the scalar
+// raw starts, after its opening quote
+func scalarColumn(line, raw string) int {
+	column := strings.LastIndex(line, raw) + 1
+	if strings.HasPrefix(raw, "'") || strings.HasPrefix(raw, "\"") {
+		column++
+	}
+	return column
+}
+
+// splitKeyValue splits a "key: value" line, unquoting the value
+func splitKeyValue(content string) (string, string, error) {
+	key, value, found := strings.Cut(content, ":")
+	if !found || strings.ContainsAny(key, " \t\"'") {
+		return "", "", fmt.Errorf("expected 'key: value'")
+	}
+	if value != "" && value[0] != ' ' && value[0] != '\t' {
+		return "", "", fmt.Errorf("expected a space after '%s:'", key)
+	}
+	value, err := parseScalar(strings.TrimSpace(value))
+	return key, value, err
+}
+
+// parseScalar unquotes a YAML scalar and drops a trailing comment. Single
+// quotes keep backslashes as written, so they are best for templates;
+// double quotes only unescape \\ and \".
+func parseScalar(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	var rest string
+	switch value[0] {
+	case '\'':
+		var unquoted strings.Builder
+		i := 1
+		for ; i < len(value); i++ {
+			if value[i] == '\'' {
+				if i+1 < len(value) && value[i+1] == '\'' {
+					unquoted.WriteByte('\'')
+					i++
+					continue
+				}
+				break
+			}
+			unquoted.WriteByte(value[i])
+		}
+		if i >= len(value) {
+			return "", fmt.Errorf("unterminated quoted string")
+		}
+		value, rest = unquoted.String(), value[i+1:]
+	case '"':
+		var unquoted strings.Builder
+		i := 1
+		for ; i < len(value); i++ {
+			if value[i] == '"' {
+				break
+			}
+			if value[i] == '\\' && i+1 < len(value) && (value[i+1] == '\\' || value[i+1] == '"') {
+				i++
+			}
+			unquoted.WriteByte(value[i])
+		}
+		if i >= len(value) {
+			return "", fmt.Errorf("unterminated quoted string")
+		}
+		value, rest = unquoted.String(), value[i+1:]
+	default:
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		return value, nil
+	}
+
+	if rest = strings.TrimSpace(rest); rest != "" && rest[0] != '#' {
+		return "", fmt.Errorf("unexpected text after quoted string")
+	}
+	return value, nil
+}
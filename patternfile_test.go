@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestParsePatternFile tests parsing the YAML subset of pattern files
+func TestParsePatternFile(t *testing.T) {
+	file, err := parsePatternFile(`# ACME decoys
+replace: true
+weights:
+  media: 60
+  acme-invoice: 5.5
+patterns:
+  - name: acme-invoice
+    class: documents  # shows up with the PDFs
+    templates:
+      - 'ACME Corp. Invoice %d%d%d%d\0a'
+      - "ACME \"Ltd\" \\0a"
+  - name: ACME-Log
+    template: plain %l%l text
+`)
+	if err != nil {
+		t.Fatalf("parsePatternFile failed: %v", err)
+	}
+	if file.weights["media"] != 60 || file.weights["acme-invoice"] != 5.5 {
+		t.Errorf("Unexpected weights: %v", file.weights)
+	}
+	if !file.replace || len(file.patterns) != 2 {
+		t.Fatalf("Expected replace and 2 patterns, got %v and %d", file.replace, len(file.patterns))
+	}
+	invoice := file.patterns[0]
+	if invoice.name != "acme-invoice" || invoice.class != "documents" || len(invoice.templates) != 2 {
+		t.Errorf("Unexpected first pattern: %+v", invoice)
+	}
+	if invoice.templates[0] != `ACME Corp. Invoice %d%d%d%d\0a` || invoice.templates[1] != `ACME "Ltd" \0a` {
+		t.Errorf("Templates not unquoted as expected: %q", invoice.templates)
+	}
+	if log := file.patterns[1]; log.name != "acme-log" || log.class != customPatternClass || log.templates[0] != "plain %l%l text" {
+		t.Errorf("Unexpected second pattern: %+v", log)
+	}
+
+	for _, invalid := range []string{
+		"colors: blue\n",
+		"patterns:\n  - class: media\n    template: x\n",
+		"patterns:\n  - name: empty\n",
+		"patterns:\n  - name: x\n    template: 'unterminated\n",
+		"replace: maybe\n",
+		"weights:\n  media: lots\n",
+	} {
+		if _, err := parsePatternFile(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+// TestLoadPatternFiles tests merging pattern files with the built-ins
+func TestLoadPatternFiles(t *testing.T) {
+	builtins := fakeHeaderPatterns
+	defer func() {
+		fakeHeaderPatterns = builtins
+		headerTemplates = patternTemplates(builtins)
+	}()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	dir := patternsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create patterns dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "acme.yaml"), []byte("patterns:\n  - name: jpg\n    template: 'not a jpeg'\n"), 0644); err != nil {
+		t.Fatalf("Failed to write pattern file: %v", err)
+	}
+	extra := filepath.Join(t.TempDir(), "mine.yaml")
+	if err := os.WriteFile(extra, []byte("patterns:\n  - name: mine\n    template: 'mine'\n"), 0644); err != nil {
+		t.Fatalf("Failed to write pattern file: %v", err)
+	}
+
+	if err := loadPatternFiles(extra); err != nil {
+		t.Fatalf("loadPatternFiles failed: %v", err)
+	}
+	if len(fakeHeaderPatterns) != len(builtins)+1 {
+		t.Errorf("Expected the built-ins plus one pattern, got %d", len(fakeHeaderPatterns))
+	}
+	if jpg, _ := findPattern("jpg"); len(jpg.templates) != 1 || jpg.templates[0] != "not a jpeg" {
+		t.Errorf("jpg should be replaced by the user-defined pattern, got %q", jpg.templates)
+	}
+	if err := selectPatterns("", customPatternClass); err != nil || len(headerTemplates) != 2 {
+		t.Errorf("Expected the two custom templates, got %d (%v)", len(headerTemplates), err)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// templateIssue is a problem lintTemplate found at offset of a template
+type templateIssue struct {
+	offset  int
+	message string
+}
+
+// lintTemplate reports the parts of template that renderTemplate would
+// silently render as literal bytes, and templates that render to more than
+// a block
+func lintTemplate(template string) []templateIssue {
+	issues, length := lintSpan(template, 0, len(template))
+	if length > bufferSize {
+		issues = append(issues, templateIssue{0, fmt.Sprintf("renders up to %d bytes, more than the %d-byte block", length, bufferSize)})
+	}
+	return issues
+}
+
+// lintSpan checks template[start:end], returning its issues and the most
+// bytes it can render to
+func lintSpan(template string, start, end int) ([]templateIssue, int) {
+	var issues []templateIssue
+	length := 0
+	// repetition checks a {N} following a directive or escape at pos
+	repetition := func(pos int) (int, int) {
+		if pos >= end || template[pos] != '{' {
+			return 1, pos
+		}
+		count, next := repeatCount(template[:end], pos)
+		if next == pos {
+			issues = append(issues, templateIssue{pos, "malformed repetition, expected {N}"})
+			return 1, pos
+		}
+		if number, _ := strconv.Atoi(template[pos+1 : next-1]); number > bufferSize {
+			issues = append(issues, templateIssue{pos, fmt.Sprintf("repetition of %d is capped at %d", number, bufferSize)})
+		}
+		return count, next
+	}
+
+	i := start
+	for i < end {
+		switch template[i] {
+		case '(':
+			alternatives, next := choiceGroup(nil, template[:end], i)
+			if alternatives == nil {
+				if strings.IndexByte(template[i:end], '|') >= 0 && strings.IndexByte(template[i:end], ')') < 0 {
+					issues = append(issues, templateIssue{i, "unclosed choice group, rendered as literal text"})
+				}
+				length++
+				i++
+				continue
+			}
+			longest := 0
+			from := i + 1
+			for _, alternative := range alternatives {
+				altIssues, altLength := lintSpan(template, from, from+len(alternative))
+				issues = append(issues, altIssues...)
+				longest = max(longest, altLength)
+				from += len(alternative) + 1
+			}
+			length += longest
+			i = next
+		case '%':
+			if i+1 >= end {
+				issues = append(issues, templateIssue{i, "truncated % directive, rendered as a literal %"})
+				length++
+				i++
+				continue
+			}
+			if strings.IndexByte(templateDirectives, template[i+1]) < 0 {
+				issues = append(issues, templateIssue{i, fmt.Sprintf("unknown directive %%%c, rendered literally (use \\25 for a literal %%)", template[i+1])})
+				length++
+				i++
+				continue
+			}
+			count, next := repetition(i + 2)
+			length += count
+			i = next
+		case '\\':
+			if i+3 > end {
+				issues = append(issues, templateIssue{i, "truncated \\hh escape, rendered literally"})
+				length++
+				i++
+				continue
+			}
+			if _, err := strconv.ParseUint(template[i+1:i+3], 16, 8); err != nil {
+				issues = append(issues, templateIssue{i, fmt.Sprintf("malformed escape '%s', rendered literally (use \\5c for a literal backslash)", template[i:i+3])})
+				length++
+				i++
+				continue
+			}
+			count, next := repetition(i + 3)
+			length += count
+			i = next
+		case '?':
+			i++
+		default:
+			length++
+			i++
+		}
+	}
+	return issues, length
+}
+
+// runPatternsLint implements "wipefile patterns lint [file]", which checks
+// the templates of a pattern file, or of the built-in patterns and the
+// files in patternsDir
+func runPatternsLint(args []string) {
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s patterns lint [file]\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	problems := 0
+	if len(args) == 0 {
+		for _, pattern := range fakeHeaderPatterns {
+			for i, template := range pattern.templates {
+				for _, issue := range lintTemplate(template) {
+					fmt.Printf("built-in pattern %s, template %d, column %d: %s\n", pattern.name, i+1, issue.offset+1, issue.message)
+					problems++
+				}
+			}
+		}
+	}
+
+	var paths []string
+	if len(args) == 1 {
+		paths = args
+	} else {
+		paths = patternFilePaths()
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("%s: %s\n", path, getSimpleError(err))
+			problems++
+			continue
+		}
+		file, err := parsePatternFile(string(data))
+		if err != nil {
+			fmt.Printf("%s: %s\n", path, err)
+			problems++
+			continue
+		}
+		for p, pattern := range file.patterns {
+			for i, template := range pattern.templates {
+				position := file.positions[p][i]
+				for _, issue := range lintTemplate(template) {
+					fmt.Printf("%s:%d:%d: %s: %s\n", path, position.line, position.column+issue.offset, pattern.name, issue.message)
+					problems++
+				}
+			}
+		}
+	}
+
+	if problems > 0 {
+		fmt.Printf("%d problems found\n", problems)
+		os.Exit(1)
+	}
+	fmt.Println("no problems found")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestLintTemplate tests the problems lintTemplate reports and where
+func TestLintTemplate(t *testing.T) {
+	for template, expected := range map[string]struct {
+		offset  int
+		message string
+	}{
+		"RIFF\\hdrl":        {4, "malformed escape"},
+		"abc\\4":            {3, "truncated \\hh escape"},
+		"%PDF-1.7":          {0, "unknown directive %P"},
+		"id=%":              {3, "truncated % directive"},
+		"%h{x}":             {2, "malformed repetition"},
+		"%h{5000}":          {2, "repetition of 5000"},
+		"vids(H264|XVID":    {4, "unclosed choice group"},
+		"(a|%q)":            {3, "unknown directive %q"},
+		"%b{4000}\\00{200}": {0, "renders up to 4200 bytes"},
+	} {
+		issues := lintTemplate(template)
+		if len(issues) != 1 {
+			t.Errorf("Template %q: expected 1 issue, got %v", template, issues)
+			continue
+		}
+		if issues[0].offset != expected.offset || !strings.Contains(issues[0].message, expected.message) {
+			t.Errorf("Template %q: expected %q at %d, got %q at %d", template, expected.message, expected.offset, issues[0].message, issues[0].offset)
+		}
+	}
+
+	for _, valid := range []string{"\\25PDF-1.7\\0a", "%h{8}-%h{4}", "pam_unix(cron:session)", "(a|b(c|d))?", ""} {
+		if issues := lintTemplate(valid); len(issues) != 0 {
+			t.Errorf("Template %q should be valid, got %v", valid, issues)
+		}
+	}
+}
+
+// TestBuiltinTemplatesLint tests that every built-in template is free of lint
+func TestBuiltinTemplatesLint(t *testing.T) {
+	var templates []string
+	for _, pattern := range fakeHeaderPatterns {
+		templates = append(templates, pattern.templates...)
+	}
+	for _, format := range append(append([]structuredFormat{}, structuredFormats...), textFormats...) {
+		templates = append(templates, format.header)
+		templates = append(templates, format.records...)
+	}
+	for _, kind := range decoyTypes {
+		templates = append(templates, kind.header)
+	}
+	for _, template := range templates {
+		for _, issue := range lintTemplate(template) {
+			t.Errorf("Template %.40q, column %d: %s", template, issue.offset+1, issue.message)
+		}
+	}
+}
+
+// TestPatternFilePositions tests that templates are located in their file
+func TestPatternFilePositions(t *testing.T) {
+	file, err := parsePatternFile("patterns:\n  - name: a\n    templates:\n      - 'x%q'\n  - name: b\n    template: \\4\n")
+	if err != nil {
+		t.Fatalf("parsePatternFile failed: %v", err)
+	}
+	if position := file.positions[0][0]; position != (filePosition{4, 10}) {
+		t.Errorf("Expected first template at 4:10, got %v", position)
+	}
+	if position := file.positions[1][0]; position != (filePosition{6, 15}) {
+		t.Errorf("Expected second template at 6:15, got %v", position)
+	}
+}
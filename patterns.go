@@ -0,0 +1,627 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// headerPattern is a named family of fake file header templates, see
+// generateBuffer for the template syntax. Its class groups it with related
+// families for -pattern-class.
+type headerPattern struct {
+	name      string
+	class     string
+	templates []string
+}
+
+// fakeHeaderPatterns are the built-in fake file header families
+var fakeHeaderPatterns = []headerPattern{
+	// .7z
+	{"7z", "archives", []string{
+		"7z\\bc\\af\\27\\1c\\00\\04",
+	}},
+
+	// .avi
+	{"avi", "media", []string{
+		"RIFF%x{4}AVI LIST&\\01\\00\\00hdrlavih8\\00\\00\\00%x%x%x\\00{9}\\10\\01\\00\\00%x\\00{7}\\02\\00{8}\\05\\00\\00\\d0\\02\\00{18}LISTt\\00\\00\\00strlstrh8\\00\\00\\00vids(H264|XVID|DIVX|x264)\\00{12}@B\\0f\\00%x%x\\0f\\00{5}",
+		"RIFF%x{4}AVI LIST\\7e\\22\\00\\00hdrlavih8\\00\\00\\00%x%x%x\\00{9}\\10\\01\\00\\00%x%x%x00\\00{4}\\02\\00{7}\\70\\02\\00\\00\\00\\01\\00{18}LIST\\94\\10\\00\\00strlstrh8\\00\\00\\00vidsxvid\\00{12}",
+		"RIFF%x{4}AVI LIST\\54\\01\\00\\00hdrlavih8\\00\\00\\00\\35\\82\\00\\00\\20\\a1\\07\\00{5}\\10\\00\\01\\00\\83\\04\\00{6}\\02\\00{4}\\ee\\02\\00\\80\\02\\00\\00\\e0\\01\\00{18}LIST\\a2\\00\\00\\00strlstrh8\\00\\00\\00vidsmjpg\\00{12}\\35\\82\\00\\00\\40\\42\\0f\\00{5}",
+	}},
+
+	// .bat
+	{"bat", "source", []string{
+		"@echo off\\0a\\0a?%t{5}?\\0a\\0a?%t{5}?",
+	}},
+
+	// Berkeley DB (Btree, version 9, native byte-order)
+	{"berkeley-db", "databases", []string{
+		"\\00{4}\\01\\00{7}b1\\05\\00\\09\\00{4}\\10\\00\\00\\00\\09\\00\\00%x\\00\\00\\00\\14\\00{15}\\20\\00\\00\\00",
+	}},
+
+	// bitlocker
+	{"bitlocker", "disk-images", []string{
+		"\\eb\\58\\90-FVE-FS-\\02\\00\\00\\08\\00{5}%x%x%x\\00\\00\\3f\\00%x{6}\\00{4}\\e0\\1f\\00{6}%x{4}\\01\\00\\06\\00{13}\\80\\00\\29%x{4}%b{11}FAT32   ",
+	}},
+
+	// Blockchain wallet backup
+	{"blockchain-wallet", "wallets", []string{
+		"{\"pbkdf2_iterations\":5000,\"version\":2,\"payload\":\"%b{18}",
+		"{\\0a        \"guid\" : \"%h{8}-%h{4}-%h{4}-%h{4}-%h{12}\",\\0a        \"sharedKey\" : \"%h{8}-%h{4}-%h{4}-%h{4}-%h{12}\",\\0a        \"options\" : {\"pbkdf2_iterations\":10,\"fee_policy\":0,\"",
+	}},
+
+	// bootsector
+	{"bootsector", "disk-images", []string{
+		"\\eb\\63\\90\\10\\8e\\d0\\bc\\00\\b0\\b8\\00\\00\\8e\\d8\\8e\\c0\\fb\\be",
+	}},
+
+	// .c
+	{"c", "source", []string{
+		"int main(void) { %t{5}?",
+	}},
+
+	// .deb
+	{"deb", "archives", []string{
+		"\\21\\3c\\61\\72\\63\\68\\3e\\0a\\64\\65\\62\\69\\61\\6e\\2d\\62\\69\\6e\\61\\72\\79\\2f\\20\\20\\30\\20{11}\\30\\20{5}\\30\\20{5}\\36\\34\\34\\20{5}\\34\\20{9}\\60\\0a\\32\\2e\\30\\0a",
+	}},
+
+	// Dockerfile
+	{"dockerfile", "source", []string{
+		"FROM python:3.10-alpine\\0a\\0a?EXPOSE %d{4}?\\0a\\0a?COPY \"%l{5}?",
+		"FROM node:latest\\0a\\0a?RUN wget -q -O - https://%l{5}?",
+	}},
+
+	// Electrum wallet
+	{"electrum-wallet", "wallets", []string{
+		"{\\0a    \"accounts_expanded\": {},\\0a    \"addr_history\": {\\0a        \"1%b{33}\": [],\\0a        \"1%b{33}\": [],\\0a",
+		"{\\0a    \"accounts_expanded\": {},\\0a    \"addr_history\": {\\0a        \"1%b{33}\": [],\\0a        \"1%b{33}\": [],\\0a        \"1%b{33}\": [],\\0a",
+		"{\\0a    \"accounts\": {\\0a        \"0\": {\\0a            \"change\": [\\0a                \"0%h{65}\",\\0a                \"0%h{65}\",\\0a",
+		"{\\0a    \"addr_history\": {\\0a        \"1%b{33}\": [],\\0a        \"1%b{33}\": [],\\0a",
+		"{\\0a    \"addr_history\": {\\0a        \"1%b{33}\": [],\\0a        \"1%b{33}\": [],\\0a        \"1%b{33}\": [],\\0a",
+	}},
+
+	// .elf
+	{"elf", "executables", []string{
+		"\\7fELF\\02\\01\\01\\00{9}\\03\\00\\3e\\00\\01\\00\\00\\00",
+	}},
+
+	// .gz
+	{"gz", "archives", []string{
+		"\\1f\\8b\\08\\08%x{4}\\00\\03",
+	}},
+
+	// Go
+	{"go", "source", []string{
+		"module %c{5}",
+		"package main\\0a\\0a",
+	}},
+
+	// hosts
+	{"hosts", "text", []string{
+		"127.0.0.1\\09localhost\\0a\\0a?%d%d.%d%d.%d%d.%d%d\\09%l{6}",
+	}},
+
+	// ISO 9660 primary volume descriptor, as at 32K into an .iso
+	{"iso", "disk-images", []string{
+		"\\01CD001\\01\\00(LINUX\\20{27}|Win32\\20{27})(Ubuntu 22.04.3 LTS amd64\\20{8}|CDROM\\20{27}|DATA_%d{6}\\20{21})\\00{8}%x{3}\\00\\00%x{3}\\00{32}\\01\\00\\00\\01\\01\\00\\00\\01\\00\\08\\08\\00%x%x\\00{4}%x%x",
+	}},
+
+	// Java keystore
+	{"java-keystore", "keys", []string{
+		"\\fe\\ed\\fe\\ed\\00\\02",
+	}},
+
+	// .jpg
+	{"jpg", "media", []string{
+		"\\ff\\d8\\ff\\e0\\00\\10\\4a\\46\\49\\46\\00\\01\\01\\01\\00%x\\00%x\\00\\00\\ff\\e1\\00\\68\\45\\78\\69\\66\\00\\00",
+		"\\ff\\d8\\ff\\e0\\00\\10\\4a\\46\\49\\46\\00\\01\\01\\00\\00\\01\\00\\01\\00\\00\\ff\\fe\\00\\3b%b{4}",
+	}},
+
+	// .json
+	{"json", "documents", []string{
+		"{\\0a  \"%l{6}?",
+	}},
+
+	// LUKS
+	{"luks", "disk-images", []string{
+		"LUKS\\ba\\be\\00\\02\\00{6}\\40\\00{8}\\03\\00{48}sha256\\00{26}",
+	}},
+
+	// Mach-O 64-bit x86_64 executable
+	{"macho", "executables", []string{
+		"\\cf\\fa\\ed\\fe\\07\\00\\00\\01\\03\\00\\00\\00\\02\\00\\00\\00\\0e\\00\\00\\00%x%x\\00\\00\\04\\00\\20\\00{5}\\19\\00\\00\\00\\48\\00\\00\\00__PAGEZERO\\00{17}\\01\\00\\00",
+	}},
+
+	// .mp3 with an ID3v2 tag
+	{"mp3", "media", []string{
+		"ID3\\04\\00{4}(\\01|\\02|\\21)(\\0a|\\37|\\76)TSSE\\00\\00\\00\\0f\\00\\00\\03Lavf5(8.76|9.27|8.29).100\\00",
+		"ID3\\03\\00{3}(\\00|\\01)(\\1f|\\4c|\\76)TIT2\\00\\00\\00\\0b\\00{3}%c%l{9}TPE1\\00\\00\\00\\09\\00{3}%c%l{7}",
+	}},
+
+	// .mp4
+	{"mp4", "media", []string{
+		"\\00\\00\\00 ftypiso5\\00\\00\\00\\01iso5dsmsmsixdash\\00\\00\\00",
+		"\\00\\00\\00\\18ftypisom\\00{4}isom3gp4\\02\\fb\\4f\\edmdat\\20\\00\\0c\\41\\f9\\00\\00\\c4\\1f\\90\\e0\\20\\00\\0c\\41\\f9\\00\\00\\c4\\1f\\90\\e0\\20\\00",
+		"\\00\\00\\00 ftypisom\\00\\00\\02\\00isomiso2avc1mp4100%x%x%xmoov\\00\\00\\00lmvhd\\00{14}\\03\\e8\\00",
+	}},
+
+	// MySQL dump
+	{"mysql-dump", "databases", []string{
+		"-- MySQL dump 10.1%d  Distrib (8.%d.%d%d, for Linux|1%d.%d.%d%d-MariaDB, for debian-linux-gnu) (x86_64)\\0a",
+		"CREATE TABLE `%l{5}?%l?` (\\0a  `%l{6}?",
+		"INSERT INTO `%l{5}?%l?` (`%l{5}?",
+	}},
+
+	// MySQL replication log
+	{"mysql-binlog", "databases", []string{
+		"\\fe\\62\\69\\6e%x{4}\\0f\\01\\00\\00\\00\\7a\\00\\00\\00\\7e\\00{5}\\04\\00",
+	}},
+
+	// .docx/.xlsx: a zip starting with the Office Open XML content types
+	{"ooxml", "documents", []string{
+		"\\50\\4b\\03\\04\\14\\00\\06\\00\\08\\00\\00\\00!\\00%x{4}%x\\01\\00\\00%x(\\04|\\05)\\00\\00\\13\\00\\08\\02[Content_Types].xml \\a2\\04\\02(\\28|\\20)\\a0\\00\\02",
+		"\\50\\4b\\03\\04\\14\\00\\08\\00\\08\\00%x{4}\\00{12}\\0b\\00\\00\\00_rels/.rels",
+	}},
+
+	// Apache ORC
+	{"orc", "databases", []string{
+		"ORC%x\\00\\00\\e3\\12\\8a\\10",
+	}},
+
+	// Apache Parquet: magic, then the header of the first data page
+	{"parquet", "databases", []string{
+		"PAR1\\15\\00\\15%x%x\\15%x%x\\2c\\15%x%x?\\15\\00\\15(\\06|\\10)\\15(\\06|\\08)\\00\\00",
+	}},
+
+	// .pdf
+	{"pdf", "documents", []string{
+		"\\25PDF-1.7\\0a1 0 obj\\0a<< /Type /Catalog >>\\0aendobj\\0a2 0 obj\\0a<< /Filter /FlateDecode\\0a/Length %d{5}? >>\\0astream\\0a",
+	}},
+
+	// Windows PE/MZ executable: DOS header and stub, then the PE header
+	// right away or after a Rich header
+	{"pe", "executables", []string{
+		"MZ\\90\\00\\03\\00{3}\\04\\00{3}\\ff\\ff\\00\\00\\b8\\00{7}\\40\\00{35}\\80\\00{3}\\0e\\1f\\ba\\0e\\00\\b4\\09\\cd\\21\\b8\\01\\4c\\cd\\21This program cannot be run in DOS mode.\\0d\\0d\\0a$\\00{7}" +
+			"PE\\00\\00(\\64\\86%x\\00%x{4}\\00{8}\\f0\\00\\22\\00\\0b\\02|\\4c\\01%x\\00%x{4}\\00{8}\\e0\\00\\02\\01\\0b\\01)",
+		"MZ\\90\\00\\03\\00{3}\\04\\00{3}\\ff\\ff\\00\\00\\b8\\00{7}\\40\\00{35}(\\e8|\\f0|\\f8)\\00{3}\\0e\\1f\\ba\\0e\\00\\b4\\09\\cd\\21\\b8\\01\\4c\\cd\\21This program cannot be run in DOS mode.\\0d\\0d\\0a$\\00{7}%x{16}",
+	}},
+
+	// .php
+	{"php", "source", []string{
+		"<?php\\0a\\0a?%c{8}",
+	}},
+
+	// pgp/ssh
+	{"pgp-ssh", "keys", []string{
+		"---BEGIN PGP PRIVATE KEY BLOCK---\\0a\\0a%b{6}",
+		"-----BEGIN (PGP SIGNED MESSAGE|OPENSSH PRIVATE KEY|RSA PRIVATE KEY|EC PRIVATE KEY)-----\\0a\\0a%b{6}",
+		"---- BEGIN SSH2 PUBLIC KEY ----\\0a\\0a%b{6}",
+		"-----BEGIN CERTIFICATE-----\\0a\\0a%b{6}",
+		"ssh-(rsa|ed25519|dss) %b{6}",
+	}},
+
+	// .png
+	{"png", "media", []string{
+		"\\89\\50\\4e\\47\\0d\\0a\\1a\\0a\\00\\00\\00\\0d\\49\\48\\44\\52\\00\\00%x%x\\00\\00%x%x%b",
+	}},
+
+	// .py
+	{"py", "source", []string{
+		"#!/usr/bin/env python3\\0a",
+	}},
+
+	// QEMU QCOW
+	{"qcow", "disk-images", []string{
+		"QFI\\fb\\00\\00\\00\\03\\00{15}\\10\\00\\00\\00\\03",
+	}},
+
+	// .rar
+	{"rar", "archives", []string{
+		"\\52\\61\\72\\21\\1A\\07\\01\\00",
+	}},
+
+	// sector formats
+	{"sector", "disk-images", []string{
+		"RRaA\\00{12}",
+		"\\f0\\ff\\ff\\0f\\ff\\ff\\ff\\0f\\ff\\ff\\ff\\0f",
+	}},
+
+	// shell script
+	{"shell", "source", []string{
+		"#!/bin/(sh|bash)\\0a\\0a?",
+		"#!/bin/bash\\0a\\0a?%t{8}?%t?%t?\\0a\\0a?%t{7}?%t?",
+	}},
+
+	// SQLite 3 database: file header, then the b-tree header of page 1
+	{"sqlite", "databases", []string{
+		"SQLite format 3\\00\\10\\00(\\01\\01|\\02\\02)\\00\\40\\20\\20\\00\\00%x%x\\00\\00\\00%x\\00{11}%x\\00\\00\\00\\04\\00{11}\\01\\00{32}\\00\\00%x%x\\00\\2e(\\76\\89|\\63\\01|\\57\\4a)\\0d\\00\\00\\00%x\\0f%x\\00",
+	}},
+
+	// systemd journal
+	{"systemd-journal", "shell-history", []string{
+		"LPKSHHRH(\\00|\\02)\\00{3}(\\1e|\\0a|\\02)\\00{3}(\\01|\\02)\\00{7}%x{64}(\\10\\01|\\f0\\00)\\00{6}%x{3}\\00{5}",
+	}},
+
+	// ustar tar: the 512-byte header of a directory or file
+	{"tar", "archives", []string{
+		"(home/%l{6}/\\00{88}|etc/\\00{96}|backup/%l{6}.sql\\00{83})0000(755|644)\\00000(1750|0000)\\00000(1750|0000)\\00" +
+			"000000(1|2|3|4|5|6|7)(0|1|2|3|4|5|6|7)(0|4)(0|4)0\\00" +
+			"14(5|6|7)(1|3|6)(0|2|4)(0|1|2|3)(4|5|6|7)(0|1|2|3)(4|5|6|7)(0|1|2|3)(4|5|6|7)\\00" +
+			"01(0|1|2|3)(0|1|2|3)(4|5|6|7)(0|1|2|3)\\00 (5|0)\\00{100}(ustar\\0000|ustar  \\00)(root\\00{28}|%l{6}\\00{26})(root\\00{28}|%l{6}\\00{26})\\00{183}",
+	}},
+
+	// VDI
+	{"vdi", "disk-images", []string{
+		"<<< Oracle VM VirtualBox Disk Image >>>\\0a\\00{24}\\7f\\10\\da\\be\\01\\00\\01\\00\\90\\01\\00\\00\\01\\00\\00\\00",
+	}},
+
+	// VMware VMDK
+	{"vmdk", "disk-images", []string{
+		"# Disk DescriptorFile\\0aversion=3\\0aencoding=\"UTF-8\"\\0aCID=%h{8}\\0aparentCID=ffffffff\\0a",
+	}},
+
+	// wallet - generic encrypted
+	{"encrypted-wallet", "wallets", []string{
+		"{\"encrypted\":\"",
+	}},
+
+	// .xml
+	{"xml", "documents", []string{
+		"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\\0a<(!DOCTYPE |)%t{7}?",
+	}},
+
+	// .zip
+	{"zip", "archives", []string{
+		"\\50\\4b\\03\\04\\14\\00\\08\\00\\08\\00",
+	}},
+
+	// zlib
+	{"zlib", "archives", []string{
+		"\\78\\da\\b9\\f7\\00{5}\\01",
+	}},
+
+	// extra: command and log leftovers
+	{"shell-history", "shell-history", []string{
+		".bash_history\\0a%t{4}",
+		"exit\\0a\\0a?%t{4}",
+		"ls -al\\0a%t{4}",
+		"sudo su -\\0a%t{4}",
+		"cd ..\\0a%t{4}?",
+		"sudo rm *\\0a%t{4}",
+		"df -h\\0a%t{4}?",
+		"whoami\\0a%t{4}",
+		"uname -a\\0a%t{4}",
+		"$ ls -al\\0atotal 2096\\0adrwxrwxr-x\\092%t{4}",
+		"Enter passphrase for key 'id_rsa': %t{4}",
+		"sudo: 1 incorrect password attempt",
+		"sudo: pam_unix(sudo:auth): (conversation failed|auth could not identify password)",
+		"CRON[1%d{4}?]: pam_unix(cron:session): session closed for user %t{4}",
+		"CRON[1%d{4}?]: pam_unix(cron:session): session opened for user root(uid=0) by (uid=0)",
+		"USER=root ; COMMAND=/usr/bin/vim %t{4}",
+		"gpgv: Signature made ",
+		"using RSA key %H{16}",
+		"Adding user %l{4}? to group (adm|sudo|%l{4})",
+		"kernel: [%d{7}.%d{6}] usb 1-%d: New USB device found, idVendor=%h{4}, idProduct=01%h%h, bcdDevice= 1.%d%d",
+	}},
+
+	// extra: encrypted text
+	{"encrypted-text", "text", []string{
+		"(Encrypted|enc|data): ",
+	}},
+
+	// extra: guid
+	{"guid", "text", []string{
+		"%h{8}-%h{4}-%h{4}-%h{4}-%h{12}",
+		"{%h{8}-%h{4}-%h{4}-%h{4}-%h{12}}",
+	}},
+
+	// extra: pw-text
+	{"password", "keys", []string{
+		"(Password|pw): %b{8}",
+	}},
+
+	// extra: text
+	{"text", "text", []string{
+		"%b{11}",
+		"%b{16}",
+		"%b{32}",
+		"%t{60}",
+		"%t{87}",
+	}},
+
+	// all random
+	{"random", "random", []string{
+		"",
+	}},
+}
+
+// headerTemplates are the templates fake headers are picked from, all of
+// them unless -pattern narrows it down
+var headerTemplates = patternTemplates(fakeHeaderPatterns)
+
+// headerWeights are the cumulative weights of headerTemplates, or nil to
+// pick every template equally often
+var headerWeights []float64
+
+// patternWeights are the weights of classes and patterns from pattern
+// files and -pattern-weights
+var patternWeights = make(map[string]float64)
+
+func patternTemplates(patterns []headerPattern) []string {
+	var templates []string
+	for _, pattern := range patterns {
+		templates = append(templates, pattern.templates...)
+	}
+	return templates
+}
+
+// selectPatterns restricts fake headers to the comma-separated pattern
+// names in names (all if empty), then to the classes in classes. A class
+// prefixed with '-' is excluded instead.
+func selectPatterns(names, classes string) error {
+	selected := fakeHeaderPatterns
+	if names != "" {
+		selected = nil
+		for _, name := range strings.Split(names, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			pattern, ok := findPattern(name)
+			if !ok {
+				return fmt.Errorf("unknown pattern '%s' (known: %s)", name, strings.Join(patternNames(), ", "))
+			}
+			selected = append(selected, pattern)
+		}
+	}
+
+	if classes != "" {
+		include := make(map[string]bool)
+		exclude := make(map[string]bool)
+		for _, class := range strings.Split(classes, ",") {
+			class = strings.ToLower(strings.TrimSpace(class))
+			excluded := strings.HasPrefix(class, "-")
+			class = strings.TrimPrefix(class, "-")
+			if !isPatternClass(class) {
+				return fmt.Errorf("unknown pattern class '%s' (known: %s)", class, strings.Join(patternClasses(), ", "))
+			}
+			if excluded {
+				exclude[class] = true
+			} else {
+				include[class] = true
+			}
+		}
+
+		var filtered []headerPattern
+		for _, pattern := range selected {
+			if exclude[pattern.class] || (len(include) > 0 && !include[pattern.class]) {
+				continue
+			}
+			filtered = append(filtered, pattern)
+		}
+		selected = filtered
+	}
+
+	if len(selected) == 0 {
+		return fmt.Errorf("no patterns left to use")
+	}
+	return setHeaderTemplates(selected)
+}
+
+// setHeaderTemplates makes fake headers come from patterns, weighted by
+// patternWeights if set. A class's weight is shared by its patterns that
+// have no weight of their own, and patterns without either are not used.
+func setHeaderTemplates(patterns []headerPattern) error {
+	headerTemplates = patternTemplates(patterns)
+	headerWeights = nil
+	if len(patternWeights) == 0 {
+		return nil
+	}
+
+	for name := range patternWeights {
+		if _, ok := findPattern(name); !ok && !isPatternClass(name) {
+			return fmt.Errorf("weight for unknown pattern or class '%s'", name)
+		}
+	}
+	ownWeight := func(pattern headerPattern) (float64, bool) {
+		weight, ok := patternWeights[pattern.name]
+		return weight, ok && !isPatternClass(pattern.name)
+	}
+	classShares := make(map[string]int)
+	for _, pattern := range patterns {
+		if _, ok := ownWeight(pattern); !ok {
+			classShares[pattern.class]++
+		}
+	}
+
+	total := 0.0
+	for _, pattern := range patterns {
+		weight, ok := ownWeight(pattern)
+		if !ok {
+			weight = patternWeights[pattern.class] / float64(classShares[pattern.class])
+		}
+		for range pattern.templates {
+			total += weight / float64(len(pattern.templates))
+			headerWeights = append(headerWeights, total)
+		}
+	}
+	if total == 0 {
+		return fmt.Errorf("none of the patterns in use has a weight")
+	}
+	return nil
+}
+
+// pickHeaderTemplate returns a random template from headerTemplates
+func pickHeaderTemplate() string {
+	if headerWeights == nil {
+		return headerTemplates[generatorRand.Intn(len(headerTemplates))]
+	}
+	x := generatorRand.Float64() * headerWeights[len(headerWeights)-1]
+	return headerTemplates[sort.Search(len(headerWeights), func(i int) bool { return headerWeights[i] > x })]
+}
+
+// parsePatternWeights parses weights like "media=60,text=30,random=10"
+// into patternWeights
+func parsePatternWeights(list string) error {
+	for _, entry := range strings.Split(list, ",") {
+		name, value, found := strings.Cut(entry, "=")
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !found || err != nil || weight < 0 {
+			return fmt.Errorf("invalid weight '%s', expected NAME=WEIGHT", entry)
+		}
+		patternWeights[strings.ToLower(strings.TrimSpace(name))] = weight
+	}
+	return nil
+}
+
+func findPattern(name string) (headerPattern, bool) {
+	for _, pattern := range fakeHeaderPatterns {
+		if pattern.name == name {
+			return pattern, true
+		}
+	}
+	return headerPattern{}, false
+}
+
+func patternNames() []string {
+	names := make([]string, len(fakeHeaderPatterns))
+	for i, pattern := range fakeHeaderPatterns {
+		names[i] = pattern.name
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isPatternClass(class string) bool {
+	for _, pattern := range fakeHeaderPatterns {
+		if pattern.class == class {
+			return true
+		}
+	}
+	return false
+}
+
+func patternClasses() []string {
+	var classes []string
+	seen := make(map[string]bool)
+	for _, pattern := range fakeHeaderPatterns {
+		if !seen[pattern.class] {
+			seen[pattern.class] = true
+			classes = append(classes, pattern.class)
+		}
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+// patternExample renders template for display, escaping unprintable
+// bytes and cutting it off after width characters
+func patternExample(template string, width int) string {
+	var example strings.Builder
+	for _, b := range renderTemplate(nil, template, newPaddingStream()) {
+		if example.Len() >= width {
+			example.WriteString("...")
+			break
+		}
+		switch {
+		case b == '\n':
+			example.WriteString(`\n`)
+		case b == '\t':
+			example.WriteString(`\t`)
+		case b < ' ' || b > '~' || b == '\\':
+			fmt.Fprintf(&example, `\x%02x`, b)
+		default:
+			example.WriteByte(b)
+		}
+	}
+	return example.String()
+}
+
+// templatePattern returns the pattern template belongs to
+func templatePattern(template string) (headerPattern, bool) {
+	for _, pattern := range fakeHeaderPatterns {
+		for _, candidate := range pattern.templates {
+			if candidate == template {
+				return pattern, true
+			}
+		}
+	}
+	return headerPattern{}, false
+}
+
+// writeSamples implements -t: it writes count 4K buffers to out, generated
+// like a wipe with the current options would write them, either raw or as
+// a hex dump headed by the pattern each came from
+func writeSamples(out io.Writer, count int, hexDump bool) error {
+	stream := newPaddingStream()
+	buf := make([]byte, bufferSize)
+	for i := 0; i < count; i++ {
+		var label string
+		if file := newFakeFile(); file != nil {
+			// A new generator per sample, so each shows the start of a file
+			file.fill(buf, stream)
+			label = "structured file"
+			if !*structured {
+				label = "text"
+			}
+		} else {
+			template := pickHeaderTemplate()
+			buf = generateBuffer(buf, template, stream)
+			label = "unknown pattern"
+			if pattern, ok := templatePattern(template); ok {
+				label = fmt.Sprintf("%s (%s)", pattern.name, pattern.class)
+			}
+		}
+
+		if !hexDump {
+			if _, err := out.Write(buf); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(out, "sample %d: %s\n", i+1, label); err != nil {
+			return err
+		}
+		dumper := hex.Dumper(out)
+		dumper.Write(buf)
+		if err := dumper.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPatternsCommand implements "wipefile patterns", which lists the
+// built-in and user-defined patterns that -pattern and -pattern-class accept
+func runPatternsCommand(args []string) {
+	patternsFlags := flag.NewFlagSet("patterns", flag.ExitOnError)
+	patternsFile := patternsFlags.String("patterns-file", "", "Also list the patterns of this YAML file")
+	patternsFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s patterns [options]\n       %s patterns lint [file]\n", os.Args[0], os.Args[0])
+		patternsFlags.PrintDefaults()
+	}
+	if len(args) > 0 && args[0] == "lint" {
+		runPatternsLint(args[1:])
+		return
+	}
+	parseFlags(patternsFlags, "patterns", args)
+	if patternsFlags.NArg() != 0 {
+		patternsFlags.Usage()
+		os.Exit(1)
+	}
+	if err := loadPatternFiles(*patternsFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot load patterns: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%-18s %-14s %s\n", "PATTERN", "CLASS", "EXAMPLE")
+	for _, pattern := range fakeHeaderPatterns {
+		example := "(random data)"
+		if pattern.templates[0] != "" {
+			example = patternExample(pattern.templates[0], 60)
+		}
+		fmt.Printf("%-18s %-14s %s\n", pattern.name, pattern.class, example)
+	}
+}
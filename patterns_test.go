@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// TestPatternNamesUnique tests that every pattern can be selected by its own name
+func TestPatternNamesUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, pattern := range fakeHeaderPatterns {
+		if pattern.name == "" || seen[pattern.name] {
+			t.Errorf("Pattern name %q is empty or used twice", pattern.name)
+		}
+		seen[pattern.name] = true
+		if len(pattern.templates) == 0 {
+			t.Errorf("Pattern %q has no templates", pattern.name)
+		}
+	}
+}
+
+// TestSelectPatterns tests that -pattern restricts the generated headers
+func TestSelectPatterns(t *testing.T) {
+	defer func() { headerTemplates = patternTemplates(fakeHeaderPatterns) }()
+
+	if err := selectPatterns("jpg", ""); err != nil {
+		t.Fatalf("selectPatterns failed: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		if header := getFakeHeader(); !bytes.HasPrefix(header, []byte{0xff, 0xd8, 0xff, 0xe0}) {
+			t.Fatalf("Expected a JPEG header, got % x", header[:8])
+		}
+	}
+
+	if err := selectPatterns("png, Zip", ""); err != nil || len(headerTemplates) != 2 {
+		t.Errorf("Expected the png and zip templates, got %d (%v)", len(headerTemplates), err)
+	}
+	if err := selectPatterns("jpg,nonexistent", ""); err == nil {
+		t.Error("Expected error for unknown pattern")
+	}
+}
+
+// TestSelectPatternClasses tests including and excluding pattern classes
+func TestSelectPatternClasses(t *testing.T) {
+	defer func() { headerTemplates = patternTemplates(fakeHeaderPatterns) }()
+
+	count := func(classes ...string) int {
+		n := 0
+		for _, pattern := range fakeHeaderPatterns {
+			for _, class := range classes {
+				if pattern.class == class {
+					n += len(pattern.templates)
+				}
+			}
+		}
+		return n
+	}
+
+	if err := selectPatterns("", "wallets,keys"); err != nil || len(headerTemplates) != count("wallets", "keys") {
+		t.Errorf("Expected only wallet and key templates, got %d (%v)", len(headerTemplates), err)
+	}
+	all := len(patternTemplates(fakeHeaderPatterns))
+	if err := selectPatterns("", "-wallets"); err != nil || len(headerTemplates) != all-count("wallets") {
+		t.Errorf("Expected all but wallet templates, got %d (%v)", len(headerTemplates), err)
+	}
+	jpg, _ := findPattern("jpg")
+	if err := selectPatterns("jpg,electrum-wallet", "-wallets"); err != nil || len(headerTemplates) != len(jpg.templates) {
+		t.Errorf("Expected only the jpg templates, got %d (%v)", len(headerTemplates), err)
+	}
+	if err := selectPatterns("", "nonexistent"); err == nil {
+		t.Error("Expected error for unknown class")
+	}
+	if err := selectPatterns("jpg", "wallets"); err == nil {
+		t.Error("Expected error when no patterns are left")
+	}
+}
+
+// TestPatternExample tests that examples are printable and cut off
+func TestPatternExample(t *testing.T) {
+	if example := patternExample("7z\\bc\\af\\27\\1c\\00\\04", 60); example != `7z\xbc\xaf'\x1c\x00\x04` {
+		t.Errorf("Unexpected example: %s", example)
+	}
+	if example := patternExample("line\\0a\\09%d", 60); len(example) != len(`line\n\t0`) {
+		t.Errorf("Newlines and tabs should be escaped: %s", example)
+	}
+	if example := patternExample("%b%b%b%b%b%b%b%b%b%b", 4); example[4:] != "..." {
+		t.Errorf("Long example should be cut off: %s", example)
+	}
+}
+
+// TestPatternWeights tests that weights set how often classes and patterns are picked
+func TestPatternWeights(t *testing.T) {
+	defer func() {
+		patternWeights = make(map[string]float64)
+		setHeaderTemplates(fakeHeaderPatterns)
+	}()
+
+	if err := parsePatternWeights("media=60, archives=30,random=10"); err != nil {
+		t.Fatalf("parsePatternWeights failed: %v", err)
+	}
+	if err := selectPatterns("", ""); err != nil {
+		t.Fatalf("selectPatterns failed: %v", err)
+	}
+	counts := make(map[string]int)
+	classOf := make(map[string]string)
+	for _, pattern := range fakeHeaderPatterns {
+		for _, template := range pattern.templates {
+			classOf[template] = pattern.class
+		}
+	}
+	const picks = 20000
+	for i := 0; i < picks; i++ {
+		counts[classOf[pickHeaderTemplate()]]++
+	}
+	for class, share := range map[string]float64{"media": 0.6, "archives": 0.3, "random": 0.1} {
+		if got := float64(counts[class]) / picks; got < share-0.03 || got > share+0.03 {
+			t.Errorf("Expected %s in %.0f%% of headers, got %.1f%%", class, share*100, got*100)
+		}
+	}
+	if len(counts) != 3 {
+		t.Errorf("Classes without a weight should not be picked, got %v", counts)
+	}
+
+	// A pattern's own weight takes it out of its class's share
+	patternWeights = map[string]float64{"media": 1, "jpg": 0}
+	if err := selectPatterns("", ""); err != nil {
+		t.Fatalf("selectPatterns failed: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		if header := pickHeaderTemplate(); bytes.HasPrefix([]byte(header), []byte("\\ff\\d8")) {
+			t.Fatal("jpg has weight 0 and should not be picked")
+		}
+	}
+
+	patternWeights = map[string]float64{"nonexistent": 1}
+	if err := selectPatterns("", ""); err == nil {
+		t.Error("Expected error for weight of unknown pattern")
+	}
+	if err := parsePatternWeights("media"); err == nil {
+		t.Error("Expected error for weight without value")
+	}
+}
+
+// TestFormatHeaderLayout tests that fixed-size format headers put their
+// fields at the offsets parsers read them from
+func TestFormatHeaderLayout(t *testing.T) {
+	stream := newPaddingStream()
+	render := func(name string) [][]byte {
+		pattern, ok := findPattern(name)
+		if !ok {
+			t.Fatalf("Pattern %q not found", name)
+		}
+		var headers [][]byte
+		for _, template := range pattern.templates {
+			headers = append(headers, renderTemplate(nil, template, stream))
+		}
+		return headers
+	}
+
+	for i := 0; i < 20; i++ {
+		for _, header := range render("tar") {
+			if len(header) != 512 || !bytes.HasPrefix(header[257:], []byte("ustar")) {
+				t.Fatalf("Expected a 512-byte tar header with magic at 257, got %d bytes", len(header))
+			}
+		}
+		for _, header := range render("sqlite") {
+			if len(header) != 108 || string(header[:16]) != "SQLite format 3\x00" || header[100] != 0x0d {
+				t.Fatalf("Expected a 100-byte SQLite header and a page header, got % x", header)
+			}
+		}
+		for _, header := range render("pe") {
+			offset := int(binary.LittleEndian.Uint32(header[60:]))
+			if offset < len(header) && !bytes.HasPrefix(header[offset:], []byte("PE\x00\x00")) {
+				t.Fatalf("Expected the PE header at %#x, got % x", offset, header[offset:])
+			}
+		}
+		for _, header := range render("iso") {
+			if !bytes.Equal(header[120:132], []byte{1, 0, 0, 1, 1, 0, 0, 1, 0, 8, 8, 0}) {
+				t.Fatalf("Expected volume set fields at 120, got % x", header[120:132])
+			}
+		}
+	}
+}
+
+// TestWriteSamples tests the raw and hex dump output of -t
+func TestWriteSamples(t *testing.T) {
+	defer func() { headerTemplates = patternTemplates(fakeHeaderPatterns) }()
+	if err := selectPatterns("png", ""); err != nil {
+		t.Fatalf("selectPatterns failed: %v", err)
+	}
+
+	var raw bytes.Buffer
+	if err := writeSamples(&raw, 3, false); err != nil || raw.Len() != 3*bufferSize {
+		t.Fatalf("Expected 3 raw samples, got %d bytes (%v)", raw.Len(), err)
+	}
+	for offset := 0; offset < raw.Len(); offset += bufferSize {
+		if !bytes.HasPrefix(raw.Bytes()[offset:], []byte("\x89PNG")) {
+			t.Errorf("Expected a PNG header at %d", offset)
+		}
+	}
+
+	var dump bytes.Buffer
+	if err := writeSamples(&dump, 2, true); err != nil {
+		t.Fatalf("writeSamples failed: %v", err)
+	}
+	output := dump.String()
+	if !strings.HasPrefix(output, "sample 1: png (media)\n00000000  89 50 4e 47") || !strings.Contains(output, "\nsample 2: png (media)\n") {
+		t.Errorf("Unexpected hex dump:\n%.200s", output)
+	}
+}
@@ -0,0 +1,10 @@
+package main
+
+import "os"
+
+// ownedByMe reports whether the current user owns the file. On Windows the
+// read-only attribute can be cleared by anyone with write-attribute access,
+// so ownership isn't checked.
+func ownedByMe(info os.FileInfo) bool {
+	return true
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cleanupPreset is a standard cleanup target of -preset, with the rules
+// that pick its files. Ages keep files that may still be in use.
+type cleanupPreset struct {
+	name    string
+	options []string // What may follow the name after "="
+	age     bool     // Whether an age may follow too, as in logs=14d
+	rules   func(options []string) []wipeRule
+}
+
+var cleanupPresets = []cleanupPreset{
+	// Temp files of the system and the user, unmodified for a day
+	{"temp", nil, false, func([]string) []wipeRule {
+		var rules []wipeRule
+		for _, dir := range tempDirs() {
+			rules = append(rules, wipeRule{source: "-preset temp", path: dir, age: 24 * time.Hour})
+		}
+		return rules
+	}},
+
+	// Downloads unmodified for 30 days
+	{"downloads", nil, false, func([]string) []wipeRule {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		return []wipeRule{{source: "-preset downloads", path: filepath.Join(home, "Downloads"), age: 30 * 24 * time.Hour}}
+	}},
+
+	// Application caches unmodified for a week
+	{"cache", nil, false, func([]string) []wipeRule {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return nil
+		}
+		return []wipeRule{{source: "-preset cache", path: dir, age: 7 * 24 * time.Hour}}
+	}},
+
+	// Thumbnails, which show what images and videos looked like long after
+	// the originals are gone
+	{"thumbnails", nil, false, func([]string) []wipeRule { return thumbnailRules() }},
+
+	// History, download history and form databases and caches of the
+	// browsers given as options, or of all of them
+	{"browser", browserNames, false, browserRules},
+
+	// Rotated logs unmodified for 30 days or the age given, and with
+	// "journal" the archived systemd journals too
+	{"logs", []string{"journal"}, true, logRules},
+}
+
+// presetFiles returns the files of the comma-separated presets in names,
+// and the dirs they were found in, resolved, for the wipe to check they
+// are still there. Options follow a preset after "=", up to the next
+// preset, as in browser=firefox,chrome,temp.
+func presetFiles(names string) ([]string, []string, error) {
+	var files, roots []string
+	now := time.Now()
+	seen := make(map[string]bool)
+	tokens := strings.Split(strings.ToLower(names), ",")
+	for i := 0; i < len(tokens); i++ {
+		name, option, hasOptions := strings.Cut(strings.TrimSpace(tokens[i]), "=")
+		preset := findPreset(name)
+		if preset == nil {
+			known := make([]string, 0, len(cleanupPresets))
+			for _, preset := range cleanupPresets {
+				known = append(known, preset.name)
+			}
+			return nil, nil, fmt.Errorf("unknown preset '%s' (known: %s)", name, strings.Join(known, ", "))
+		}
+		var options []string
+		if hasOptions {
+			options = append(options, option)
+			for i+1 < len(tokens) && findPreset(strings.TrimSpace(tokens[i+1])) == nil {
+				i++
+				options = append(options, strings.TrimSpace(tokens[i]))
+			}
+		}
+		for _, option := range options {
+			if len(preset.options) == 0 {
+				return nil, nil, fmt.Errorf("preset '%s' takes no options", name)
+			}
+			if containsString(preset.options, option) {
+				continue
+			}
+			if _, err := parseAge(option); err != nil || !preset.age {
+				known := strings.Join(preset.options, ", ")
+				if preset.age {
+					known += " or an age"
+				}
+				return nil, nil, fmt.Errorf("unknown option '%s' of preset '%s' (known: %s)", option, name, known)
+			}
+		}
+
+		// Preset paths are taken as they are, not as patterns. Directories
+		// of other users in shared temp dirs can't be read, which is fine.
+		for _, rule := range preset.rules(options) {
+			expired, err := rule.expiredBelow(rule.path, now, seen)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				printVerboseWarning(verboseActions, "%s: skipping what can't be read: %s\n", rule.source, err)
+			}
+			printVerbose(verboseDetails, "%s: %d files in '%s'\n", rule.source, len(expired), rule.path)
+			if len(expired) == 0 {
+				continue
+			}
+			if root, err := resolveRoot(rule.path); err == nil {
+				roots = append(roots, root)
+			}
+			files = append(files, expired...)
+		}
+	}
+	return files, roots, nil
+}
+
+// rotatedLogs are the names logrotate and newsyslog give the logs they
+// rotate, compressed or numbered
+var rotatedLogs = []string{"*.gz", "*.xz", "*.bz2", "*.zst", "*.[0-9]", "*.old"}
+
+// archivedJournals are the names journald gives the journal files it
+// archives, and those it found corrupt, which journalctl --vacuum-time
+// deletes; the active journals aren't taken
+var archivedJournals = []string{"*@*.journal", "*@*.journal~"}
+
+// logRules returns the rules of -preset logs: rotated logs in the log
+// dirs, and with "journal" archived journals, unmodified for 30 days or
+// the age in options
+func logRules(options []string) []wipeRule {
+	age, journal := 30*24*time.Hour, false
+	for _, option := range options {
+		if option == "journal" {
+			journal = true
+		} else if optionAge, err := parseAge(option); err == nil {
+			age = optionAge
+		}
+	}
+	var rules []wipeRule
+	for _, dir := range logDirs() {
+		rules = append(rules, wipeRule{source: "-preset logs", path: dir, age: age, include: rotatedLogs})
+	}
+	if journal {
+		for _, dir := range journalDirs() {
+			rules = append(rules, wipeRule{source: "-preset logs", path: dir, age: age, include: archivedJournals})
+		}
+	}
+	return rules
+}
+
+// findPreset returns the preset called name, or nil if there is none
+func findPreset(name string) *cleanupPreset {
+	for i := range cleanupPresets {
+		if cleanupPresets[i].name == name {
+			return &cleanupPresets[i]
+		}
+	}
+	return nil
+}
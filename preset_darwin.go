@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// tempDirs returns the temp dir of -preset temp, the per-user $TMPDIR in
+// /var/folders
+func tempDirs() []string {
+	return []string{os.TempDir()}
+}
+
+// thumbnailRules returns the rules of -preset thumbnails: the Quick Look
+// thumbnail cache, in the per-user cache dir next to $TMPDIR, of any age
+func thumbnailRules() []wipeRule {
+	userDir := filepath.Dir(filepath.Clean(os.TempDir()))
+	return []wipeRule{{source: "-preset thumbnails", path: filepath.Join(userDir, "C", "com.apple.QuickLook.thumbnailcache")}}
+}
+
+// logDirs returns the log dirs of -preset logs: the system's, where
+// newsyslog rotates, and those of applications
+func logDirs() []string {
+	dirs := []string{"/var/log", "/Library/Logs"}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, "Library", "Logs"))
+	}
+	return dirs
+}
+
+// journalDirs returns no dirs, as there is no systemd journal
+func journalDirs() []string {
+	return nil
+}
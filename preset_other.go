@@ -0,0 +1,35 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// tempDirs returns the temp dirs of -preset temp: $TMPDIR or /tmp, and
+// /var/tmp, which survives reboots
+func tempDirs() []string {
+	return []string{os.TempDir(), "/var/tmp"}
+}
+
+// thumbnailRules returns the rules of -preset thumbnails: the XDG
+// thumbnail cache, of any age
+func thumbnailRules() []wipeRule {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+	return []wipeRule{{source: "-preset thumbnails", path: filepath.Join(cache, "thumbnails")}}
+}
+
+// logDirs returns the log dirs of -preset logs
+func logDirs() []string {
+	return []string{"/var/log"}
+}
+
+// journalDirs returns the dirs of the systemd journal: the persistent one,
+// and the one in /run when the journal isn't kept across reboots
+func journalDirs() []string {
+	return []string{"/var/log/journal", "/run/log/journal"}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPresetFiles tests that -preset downloads takes files older than its age, and rejects unknown presets
+func TestPresetFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("home is USERPROFILE on Windows")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	downloads := filepath.Join(home, "Downloads")
+	os.MkdirAll(filepath.Join(downloads, "old [1]"), 0755)
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	for _, name := range []string{"old [1]/setup.exe", "statement.pdf", "today.pdf"} {
+		path := filepath.Join(downloads, name)
+		os.WriteFile(path, []byte("data"), 0644)
+		if name != "today.pdf" {
+			os.Chtimes(path, old, old)
+		}
+	}
+
+	files, roots, err := presetFiles("Downloads")
+	want := []string{filepath.Join(downloads, "old [1]", "setup.exe"), filepath.Join(downloads, "statement.pdf")}
+	if err != nil || !reflect.DeepEqual(files, want) {
+		t.Errorf("presetFiles = %q, %v, want %q", files, err, want)
+	}
+	if root, _ := resolveRoot(downloads); !reflect.DeepEqual(roots, []string{root}) {
+		t.Errorf("presetFiles roots = %q, want %q", roots, root)
+	}
+
+	if _, _, err := presetFiles("downloads,windows"); err == nil || !strings.Contains(err.Error(), "known: temp, downloads, cache, thumbnails, browser, logs") {
+		t.Errorf("presetFiles with an unknown preset: %v", err)
+	}
+}
+
+// TestPresetsHavePaths tests that every preset rule has an absolute path
+func TestPresetsHavePaths(t *testing.T) {
+	for _, preset := range cleanupPresets {
+		for _, rule := range preset.rules(nil) {
+			if !filepath.IsAbs(rule.path) {
+				t.Errorf("-preset %s: path '%s' is not absolute", preset.name, rule.path)
+			}
+		}
+	}
+}
+
+// TestLogRules tests the rotated logs and journal files that -preset logs takes at each age
+func TestLogRules(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no log dirs on Windows")
+	}
+	dir := t.TempDir()
+	old := time.Now().Add(-20 * 24 * time.Hour)
+	for _, name := range []string{"syslog", "syslog.1", "syslog.2.gz", "apt/history.log.3.xz", "journal/0a1b/system.journal", "journal/0a1b/system@0005f2-00a3.journal"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("data"), 0644)
+		os.Chtimes(path, old, old)
+	}
+	wiped := func(options []string) []string {
+		var files []string
+		seen := make(map[string]bool)
+		for _, rule := range logRules(options) {
+			rule.path = strings.Replace(rule.path, logDirs()[0], dir, 1)
+			expired, _ := rule.expiredBelow(rule.path, time.Now(), seen)
+			for _, file := range expired {
+				name, _ := filepath.Rel(dir, file)
+				files = append(files, filepath.ToSlash(name))
+			}
+		}
+		return files
+	}
+
+	if files := wiped(nil); len(files) != 0 {
+		t.Errorf("-preset logs took %q, younger than 30 days", files)
+	}
+	want := []string{"apt/history.log.3.xz", "syslog.1", "syslog.2.gz"}
+	if files := wiped([]string{"14d"}); !reflect.DeepEqual(files, want) {
+		t.Errorf("-preset logs=14d = %q, want %q", files, want)
+	}
+	if runtime.GOOS == "darwin" {
+		return
+	}
+	want = append(want, "journal/0a1b/system@0005f2-00a3.journal")
+	if files := wiped([]string{"journal", "2w"}); !reflect.DeepEqual(files, want) {
+		t.Errorf("-preset logs=journal,2w = %q, want %q", files, want)
+	}
+
+	if _, _, err := presetFiles("logs=14d,journal,5x"); err == nil || !strings.Contains(err.Error(), "known: journal or an age") {
+		t.Errorf("presetFiles with an invalid age: %v", err)
+	}
+}
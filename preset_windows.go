@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// tempDirs returns the temp dirs of -preset temp: %TEMP% and the Windows
+// temp dir
+func tempDirs() []string {
+	return []string{os.TempDir(), filepath.Join(os.Getenv("SystemRoot"), "Temp")}
+}
+
+// thumbnailRules returns the rules of -preset thumbnails: the thumbnail
+// and icon caches of Explorer, of any age. Explorer keeps them open while
+// it runs, so they are handled like any locked file (see -on-locked).
+func thumbnailRules() []wipeRule {
+	local := os.Getenv("LOCALAPPDATA")
+	if local == "" {
+		return nil
+	}
+	return []wipeRule{{
+		source:  "-preset thumbnails",
+		path:    filepath.Join(local, "Microsoft", "Windows", "Explorer"),
+		include: []string{"thumbcache_*.db", "iconcache_*.db"},
+	}}
+}
+
+// logDirs returns no dirs, as Windows keeps its event logs in use and
+// doesn't rotate text logs
+func logDirs() []string {
+	return nil
+}
+
+// journalDirs returns no dirs, as there is no systemd journal
+func journalDirs() []string {
+	return nil
+}
@@ -0,0 +1,14 @@
+package main
+
+import "syscall"
+
+const (
+	prioDarwinProcess = 4
+	prioDarwinBg      = 0x1000
+)
+
+// setLowPriority moves the process to the background QoS band, which
+// throttles both its CPU and disk I/O.
+func setLowPriority() error {
+	return syscall.Setpriority(prioDarwinProcess, 0, prioDarwinBg)
+}
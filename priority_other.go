@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import "syscall"
+
+// setLowPriority lowers the CPU priority; there is no portable I/O priority.
+func setLowPriority() error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, 19)
+}
@@ -0,0 +1,20 @@
+package main
+
+import "syscall"
+
+const processModeBackgroundBegin = 0x00100000
+
+var procSetPriorityClass = kernel32.NewProc("SetPriorityClass")
+
+// setLowPriority enters background processing mode, which lowers both the
+// CPU and the I/O priority of the process.
+func setLowPriority() error {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return err
+	}
+	if ret, _, err := procSetPriorityClass.Call(uintptr(process), processModeBackgroundBegin); ret == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// optionProfile is a named set of options, so a team can standardize on
+// -profile paranoid instead of a dozen flags
+type optionProfile struct {
+	name    string
+	options map[string]string
+}
+
+// builtinProfiles are the profiles available without a config file
+var builtinProfiles = []optionProfile{
+	// Hide everything about the file, and fail rather than leave one behind
+	{"paranoid", map[string]string{
+		"max-name":         "true",
+		"scrub-times":      "true",
+		"xattrs-overwrite": "true",
+		"scrub-security":   "true",
+		"on-locked":        "fail",
+		"cow-policy":       "refuse",
+	}},
+
+	// Large writes without waiting for the drive's write cache
+	{"fast", map[string]string{
+		"block-size": "4M",
+		"full-sync":  "false",
+	}},
+
+	// Refuse targets an overwrite can't be shown to reach
+	{"compliance", map[string]string{
+		"cow-policy":     "refuse",
+		"network-policy": "refuse",
+		"on-locked":      "fail",
+		"scrub-times":    "true",
+		"xattrs":         "true",
+	}},
+}
+
+// configPath returns the config file profiles are loaded from,
+// ~/.config/wipefile/config.yaml on Linux
+func configPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "wipefile", "config.yaml")
+}
+
+// loadProfiles returns the built-in profiles and those of the config file,
+// which replace built-in ones of the same name
+func loadProfiles() ([]optionProfile, error) {
+	profiles := append([]optionProfile{}, builtinProfiles...)
+	path := configPath()
+	if path == "" {
+		return profiles, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return profiles, nil
+	} else if err != nil {
+		return nil, err
+	}
+	custom, err := parseConfigFile(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, profile := range custom {
+		replaced := false
+		for i := range profiles {
+			if profiles[i].name == profile.name {
+				profiles[i], replaced = profile, true
+			}
+		}
+		if !replaced {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles, nil
+}
+
+// parseConfigFile parses the profiles of a config file, in the YAML subset
+// of pattern files:
+//
+//	profiles:
+//	  team:
+//	    scrub-times: true
+//	    limit-rate: 50M
+func parseConfigFile(data string) ([]optionProfile, error) {
+	sections, err := parseConfigSections(data)
+	return sections["profiles"], err
+}
+
+// configSections are the top-level sections of the config file, each a
+// list of named maps
+var configSections = []string{"profiles", "schedules"}
+
+// parseConfigSections parses the sections of a config file, profiles and
+// schedules, by section name
+func parseConfigSections(data string) (map[string][]optionProfile, error) {
+	sections := make(map[string][]optionProfile)
+	section := ""
+	entryIndent := -1
+	for number, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		content := strings.TrimLeft(line, " ")
+		if content == "" || content[0] == '#' {
+			continue
+		}
+		indent := len(line) - len(content)
+		key, value, err := splitKeyValue(content)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", number+1, err)
+		}
+
+		switch {
+		case indent == 0:
+			known := false
+			for _, name := range configSections {
+				known = known || key == name
+			}
+			if !known || value != "" {
+				return nil, fmt.Errorf("line %d: expected 'profiles:' or 'schedules:'", number+1)
+			}
+			section = key
+			entryIndent = -1
+		case section == "":
+			return nil, fmt.Errorf("line %d: unexpected indentation", number+1)
+		case entryIndent < 0 || indent == entryIndent:
+			if value != "" {
+				return nil, fmt.Errorf("line %d: %s '%s' must be a map", number+1, strings.TrimSuffix(section, "s"), key)
+			}
+			entryIndent = indent
+			sections[section] = append(sections[section], optionProfile{strings.ToLower(key), make(map[string]string)})
+		case indent > entryIndent:
+			entries := sections[section]
+			entries[len(entries)-1].options[strings.TrimLeft(key, "-")] = value
+		default:
+			return nil, fmt.Errorf("line %d: unexpected indentation", number+1)
+		}
+	}
+	return sections, nil
+}
+
+// applyProfile sets the options of the profile name in flags, except those
+// given on the command line or in the environment
+func applyProfile(flags *flag.FlagSet, profiles []optionProfile, name string) error {
+	var profile *optionProfile
+	var names []string
+	for i := range profiles {
+		names = append(names, profiles[i].name)
+		if profiles[i].name == strings.ToLower(name) {
+			profile = &profiles[i]
+		}
+	}
+	if profile == nil {
+		return fmt.Errorf("unknown profile '%s' (known: %s)", name, strings.Join(names, ", "))
+	}
+
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	options := make([]string, 0, len(profile.options))
+	for option := range profile.options {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+	for _, option := range options {
+		if option == "profile" || flags.Lookup(option) == nil {
+			return fmt.Errorf("profile '%s': unknown option '%s'", profile.name, option)
+		}
+		if given[option] {
+			continue
+		}
+		if err := flags.Set(option, profile.options[option]); err != nil {
+			return fmt.Errorf("profile '%s': invalid value '%s' for -%s: %s", profile.name, profile.options[option], option, err)
+		}
+	}
+	return nil
+}
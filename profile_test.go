@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+// TestParseConfigFile tests reading profiles from the config file
+func TestParseConfigFile(t *testing.T) {
+	profiles, err := parseConfigFile("# team defaults\nprofiles:\n  Team:\n    scrub-times: true\n    -limit-rate: '50M'\n  fast:\n    block-size: 1M\n")
+	if err != nil {
+		t.Fatalf("parseConfigFile failed: %v", err)
+	}
+	if len(profiles) != 2 || profiles[0].name != "team" || profiles[0].options["limit-rate"] != "50M" || profiles[1].options["block-size"] != "1M" {
+		t.Errorf("Unexpected profiles: %v", profiles)
+	}
+
+	for _, invalid := range []string{
+		"team:\n  xattrs: true\n",
+		"profiles:\n  team: paranoid\n",
+		"profiles:\n    team:\n      xattrs: true\n  other:\n",
+		"profiles:\n  team:\n    xattrs true\n",
+	} {
+		if _, err := parseConfigFile(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+// TestApplyProfile tests that a profile only sets options not given otherwise
+func TestApplyProfile(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	scrubTimes := flags.Bool("scrub-times", false, "")
+	onLocked := flags.String("on-locked", "skip", "")
+	flags.String("profile", "", "")
+	if err := flags.Parse([]string{"-on-locked", "reboot"}); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles := []optionProfile{{"strict", map[string]string{"scrub-times": "true", "on-locked": "fail"}}}
+	if err := applyProfile(flags, profiles, "Strict"); err != nil {
+		t.Fatalf("applyProfile failed: %v", err)
+	}
+	if !*scrubTimes || *onLocked != "reboot" {
+		t.Errorf("Expected -scrub-times from the profile and -on-locked from the command line, got %v, %q", *scrubTimes, *onLocked)
+	}
+
+	if err := applyProfile(flags, profiles, "lenient"); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+	profiles[0].options["passes"] = "3"
+	if err := applyProfile(flags, profiles, "strict"); err == nil {
+		t.Error("Expected error for unknown option")
+	}
+}
+
+// TestBuiltinProfiles tests that the built-in profiles only set existing options
+func TestBuiltinProfiles(t *testing.T) {
+	for _, profile := range builtinProfiles {
+		for option := range profile.options {
+			if flag.Lookup(option) == nil {
+				t.Errorf("Profile %q sets unknown option %q", profile.name, option)
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import "time"
+
+const progressInterval = 5 * time.Second
+
+// fileProgress prints periodic progress lines while a large file is
+// overwritten, so a slow disk can be told apart from a hung one
+type fileProgress struct {
+	path      string
+	size      int64
+	start     time.Time
+	startDone int64 // Bytes already done at start, by an earlier run
+	lastPrint time.Time
+	lastDone  int64
+}
+
+func newFileProgress(path string, size, startOffset int64) *fileProgress {
+	now := time.Now()
+	return &fileProgress{path: path, size: size, start: now, startDone: startOffset, lastPrint: now, lastDone: startOffset}
+}
+
+// update reports done bytes, printing at most once per progressInterval.
+// A nil fileProgress does nothing.
+func (p *fileProgress) update(done int64) {
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	elapsed := now.Sub(p.lastPrint)
+	if elapsed < progressInterval {
+		return
+	}
+
+	rate := float64(done-p.lastDone) / elapsed.Seconds() / (1024 * 1024)
+	printStatus("progress '%s': %d MB of %d MB (%d%%), %.1f MB/s, %s left\n",
+		p.path, done/(1024*1024), p.size/(1024*1024), done*100/p.size, rate, p.remaining(done, now))
+	p.lastPrint = now
+	p.lastDone = done
+}
+
+// remaining estimates how long the rest of the file takes at the average
+// rate since the start, which evens out bursts of the page cache. It is 0
+// before anything was written.
+func (p *fileProgress) remaining(done int64, now time.Time) time.Duration {
+	written := done - p.startDone
+	if written <= 0 {
+		return 0
+	}
+	elapsed := now.Sub(p.start)
+	return time.Duration(float64(elapsed) * float64(p.size-done) / float64(written)).Round(time.Second)
+}
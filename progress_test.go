@@ -0,0 +1,18 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestFileProgressRemaining tests the estimate of the time left, going by
+// what this run wrote
+func TestFileProgressRemaining(t *testing.T) {
+	progress := newFileProgress("large.img", 1000, 200)
+	if left := progress.remaining(200, progress.start.Add(time.Minute)); left != 0 {
+		t.Errorf("Expected no estimate before anything was written, got %v", left)
+	}
+	if left := progress.remaining(600, progress.start.Add(time.Minute)); left != time.Minute {
+		t.Errorf("Expected 1m0s left after writing 400 of 800 bytes in a minute, got %v", left)
+	}
+}
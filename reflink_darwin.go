@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	attrBitMapCount       = 5
+	attrCmnExtPrivateSize = 0x00000008
+	fsoptNoFollow         = 0x00000001
+	fsoptAttrCmnExtended  = 0x00000020
+)
+
+type attrList struct {
+	BitmapCount uint16
+	Reserved    uint16
+	CommonAttr  uint32
+	VolAttr     uint32
+	DirAttr     uint32
+	FileAttr    uint32
+	ForkAttr    uint32
+}
+
+// hasSharedExtents reports whether path is an APFS clone that still shares
+// data blocks. APFS reports the bytes private to the file; anything less
+// than the file's allocation is shared with a clone.
+func hasSharedExtents(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() == 0 {
+		return false, err
+	}
+
+	pathPtr, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return false, err
+	}
+	attrs := attrList{BitmapCount: attrBitMapCount, ForkAttr: attrCmnExtPrivateSize}
+	// The attributes come packed after their length, a uint32, so the off_t
+	// sits at offset 4, where a Go struct would pad it to 8
+	var buf [12]byte
+	_, _, errno := syscall.Syscall6(syscall.SYS_GETATTRLIST, uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&attrs)), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)),
+		fsoptNoFollow|fsoptAttrCmnExtended, 0)
+	if errno != 0 {
+		return false, errno
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false, nil
+	}
+	privateSize := int64(binary.LittleEndian.Uint64(buf[4:]))
+	return privateSize < stat.Blocks*512, nil
+}
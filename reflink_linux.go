@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	fsIocFiemap       = 0xC020660B // _IOWR('f', 11, struct fiemap)
+	fiemapFlagSync    = 0x1
+	fiemapExtentLast  = 0x1
+	fiemapExtentShare = 0x2000
+	fiemapBatch       = 64
+)
+
+type fiemapExtent struct {
+	Logical    uint64
+	Physical   uint64
+	Length     uint64
+	Reserved64 [2]uint64
+	Flags      uint32
+	Reserved   [3]uint32
+}
+
+type fiemapRequest struct {
+	Start         uint64
+	Length        uint64
+	Flags         uint32
+	MappedExtents uint32
+	ExtentCount   uint32
+	Reserved      uint32
+	Extents       [fiemapBatch]fiemapExtent
+}
+
+// hasSharedExtents reports whether any extent of path is shared with a
+// reflink/clone or snapshot, according to FIEMAP (btrfs, XFS, ...).
+func hasSharedExtents(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	extents, err := fileExtents(file)
+	if err != nil {
+		return false, err
+	}
+	for _, extent := range extents {
+		if extent.Flags&fiemapExtentShare != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fileExtents returns all extents of file as reported by FIEMAP.
+func fileExtents(file *os.File) ([]fiemapExtent, error) {
+	var extents []fiemapExtent
+	var req fiemapRequest
+	start := uint64(0)
+	for {
+		req = fiemapRequest{Start: start, Length: ^uint64(0), Flags: fiemapFlagSync, ExtentCount: fiemapBatch}
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), fsIocFiemap, uintptr(unsafe.Pointer(&req))); errno != 0 {
+			return nil, errno
+		}
+		if req.MappedExtents == 0 {
+			return extents, nil
+		}
+
+		for _, extent := range req.Extents[:req.MappedExtents] {
+			extents = append(extents, extent)
+			if extent.Flags&fiemapExtentLast != 0 {
+				return extents, nil
+			}
+		}
+		last := req.Extents[req.MappedExtents-1]
+		start = last.Logical + last.Length
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHasSharedExtents tests that a plain file is not reported as shared
+func TestHasSharedExtents(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.txt")
+
+	if err := os.WriteFile(testFile, []byte(strings.Repeat("data", 4096)), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	shared, err := hasSharedExtents(testFile)
+	if err != nil {
+		t.Skipf("FIEMAP not supported here: %v", err)
+	}
+	if shared {
+		t.Error("A freshly written file should not share extents")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	atFDCWD        = -2  // AT_FDCWD
+	sysRenameatxNp = 488 // renameatx_np, which renamex_np wraps
+	renameExcl     = 0x4 // RENAME_EXCL
+)
+
+// renameNoReplace renames oldPath to newPath unless newPath exists, in one
+// step with renamex_np(RENAME_EXCL). Filesystems without the flag get the
+// check before the rename instead.
+func renameNoReplace(oldPath, newPath string) error {
+	from, err := syscall.BytePtrFromString(oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := syscall.BytePtrFromString(newPath)
+	if err != nil {
+		return err
+	}
+	cwd := atFDCWD
+	_, _, errno := syscall.Syscall6(sysRenameatxNp, uintptr(cwd), uintptr(unsafe.Pointer(from)),
+		uintptr(cwd), uintptr(unsafe.Pointer(to)), renameExcl, 0)
+	switch {
+	case errno == 0:
+		return nil
+	case errors.Is(errno, syscall.ENOTSUP) || errors.Is(errno, syscall.EINVAL):
+		return renameIfFree(oldPath, newPath)
+	}
+	return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: errno}
+}
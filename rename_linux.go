@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	atFDCWD             = -0x64 // AT_FDCWD
+	renameNoReplaceFlag = 0x1   // RENAME_NOREPLACE
+)
+
+// renameat2Numbers are the renameat2 syscall numbers, which the syscall
+// package lacks for some architectures
+var renameat2Numbers = map[string]uintptr{
+	"386":      353,
+	"amd64":    316,
+	"arm":      382,
+	"arm64":    276,
+	"loong64":  276,
+	"mips":     4351,
+	"mipsle":   4351,
+	"mips64":   5311,
+	"mips64le": 5311,
+	"ppc64":    357,
+	"ppc64le":  357,
+	"riscv64":  276,
+	"s390x":    347,
+}
+
+// renameNoReplace renames oldPath to newPath unless newPath exists, in one
+// step with renameat2(RENAME_NOREPLACE). Kernels before 3.15 and
+// filesystems without the flag get the check before the rename instead.
+func renameNoReplace(oldPath, newPath string) error {
+	trap, ok := renameat2Numbers[runtime.GOARCH]
+	if !ok {
+		return renameIfFree(oldPath, newPath)
+	}
+	from, err := syscall.BytePtrFromString(oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := syscall.BytePtrFromString(newPath)
+	if err != nil {
+		return err
+	}
+	cwd := atFDCWD
+	_, _, errno := syscall.Syscall6(trap, uintptr(cwd), uintptr(unsafe.Pointer(from)),
+		uintptr(cwd), uintptr(unsafe.Pointer(to)), renameNoReplaceFlag, 0)
+	switch {
+	case errno == 0:
+		return nil
+	case errors.Is(errno, syscall.ENOSYS) || errors.Is(errno, syscall.EINVAL):
+		return renameIfFree(oldPath, newPath)
+	}
+	return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: errno}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// TestRenameNoReplaceKernel tests that the kernel refuses the rename onto
+// an existing file, rather than the check before it
+func TestRenameNoReplaceKernel(t *testing.T) {
+	tempDir := t.TempDir()
+	file := filepath.Join(tempDir, "a")
+	taken := filepath.Join(tempDir, "b")
+	os.WriteFile(file, []byte("wiped"), 0644)
+	os.WriteFile(taken, []byte("unrelated"), 0644)
+
+	var linkErr *os.LinkError
+	err := renameNoReplace(file, taken)
+	if !errors.As(err, &linkErr) || linkErr.Err != syscall.EEXIST {
+		t.Errorf("renameNoReplace onto an existing file: %v, want EEXIST from renameat2", err)
+	}
+	if err := renameNoReplace(file, filepath.Join(tempDir, "c")); err != nil {
+		t.Errorf("renameNoReplace onto a free name: %v", err)
+	}
+}
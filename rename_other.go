@@ -0,0 +1,9 @@
+//go:build unix && !linux && !darwin
+
+package main
+
+// renameNoReplace renames oldPath to newPath unless newPath exists. The
+// BSDs have no exclusive rename, so newPath is checked first.
+func renameNoReplace(oldPath, newPath string) error {
+	return renameIfFree(oldPath, newPath)
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// renameIfFree renames oldPath to newPath unless newPath exists, where
+// rename would silently replace it. The check and the rename aren't
+// atomic, but the names are random and only wipefile picks them. It is
+// for systems and filesystems without an exclusive rename.
+func renameIfFree(oldPath, newPath string) error {
+	if _, err := os.Lstat(newPath); err == nil {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: os.ErrExist}
+	}
+	return os.Rename(oldPath, newPath)
+}
+
+// isNameTooLong reports whether err means a name was longer than the
+// filesystem takes.
+func isNameTooLong(err error) bool {
+	return errors.Is(err, syscall.ENAMETOOLONG)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const errorFilenameExcedRange = syscall.Errno(206)
+
+// renameNoReplace renames oldPath to newPath unless newPath exists. Unlike
+// os.Rename, MoveFile without MOVEFILE_REPLACE_EXISTING refuses to replace
+// it, in the same step.
+func renameNoReplace(oldPath, newPath string) error {
+	from, err := syscall.UTF16PtrFromString(oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := syscall.UTF16PtrFromString(newPath)
+	if err != nil {
+		return err
+	}
+	if err := syscall.MoveFile(from, to); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+	}
+	return nil
+}
+
+// isNameTooLong reports whether err means a name or path was longer than
+// the filesystem takes.
+func isNameTooLong(err error) bool {
+	return errors.Is(err, errorFilenameExcedRange)
+}
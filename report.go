@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// reportHeader is the first row of the -report
+var reportHeader = []string{"path", "size", "method", "duration_seconds", "result", "error"}
+
+// report is the -report CSV file, nil writer without it
+var report struct {
+	sync.Mutex
+	file   *os.File
+	writer *csv.Writer
+}
+
+// openReport creates the CSV report at path and writes its header
+func openReport(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	report.file = file
+	report.writer = csv.NewWriter(file)
+	report.writer.Write(reportHeader)
+	report.writer.Flush()
+	return report.writer.Error()
+}
+
+// reportResult adds a row for a target to the report. size is negative if
+// unknown, started zero if the wipe never started.
+func reportResult(path string, size int64, result string, err error, started time.Time) {
+	if report.writer == nil {
+		return
+	}
+	row := []string{path, "", wipeMethod(), "", result, ""}
+	if size >= 0 {
+		row[1] = strconv.FormatInt(size, 10)
+	}
+	if !started.IsZero() {
+		row[3] = strconv.FormatFloat(time.Since(started).Seconds(), 'f', 3, 64)
+	}
+	if err != nil {
+		row[5] = getSimpleError(err)
+	}
+
+	report.Lock()
+	defer report.Unlock()
+	if report.writer == nil {
+		return
+	}
+	// Flushed by row, so an interrupted run still leaves a usable report
+	report.writer.Write(row)
+	report.writer.Flush()
+	if err := report.writer.Error(); err != nil {
+		printError("cannot write report: %s\n", getSimpleError(err))
+	}
+}
+
+// closeReport closes the report
+func closeReport() {
+	report.Lock()
+	defer report.Unlock()
+	if report.writer == nil {
+		return
+	}
+	if err := report.file.Close(); err != nil {
+		printError("cannot write report: %s\n", getSimpleError(err))
+	}
+	report.file = nil
+	report.writer = nil
+}
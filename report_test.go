@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestReport tests that the report gets a header and a row per target
+func TestReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.csv")
+	if err := openReport(path); err != nil {
+		t.Fatalf("Failed to create report: %v", err)
+	}
+	reportResult("/tmp/a, b.txt", 4096, "wiped", nil, time.Now().Add(-1500*time.Millisecond))
+	reportResult("/tmp/c.txt", -1, "denied", os.ErrPermission, time.Time{})
+	closeReport()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Report is not valid CSV: %v", err)
+	}
+	if len(rows) != 3 || rows[0][0] != "path" {
+		t.Fatalf("Expected a header and 2 rows, got %q", rows)
+	}
+	if rows[1][0] != "/tmp/a, b.txt" || rows[1][1] != "4096" || rows[1][2] != wipeMethod() || rows[1][4] != "wiped" {
+		t.Errorf("Unexpected row for a wiped file: %q", rows[1])
+	}
+	if rows[1][3] < "1.5" || rows[1][3] > "1.9" {
+		t.Errorf("Expected a duration of about 1.5 seconds, got %q", rows[1][3])
+	}
+	if rows[2][1] != "" || rows[2][3] != "" || rows[2][5] != "Permission denied" {
+		t.Errorf("Unexpected row for a failure: %q", rows[2])
+	}
+}
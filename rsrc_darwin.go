@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// wipeResourceFork overwrites and truncates the HFS+/APFS resource fork of
+// path, which can hold a full copy of sensitive content.
+func wipeResourceFork(path string) {
+	rsrcPath := filepath.Join(path, "..namedfork", "rsrc")
+	info, err := os.Stat(rsrcPath)
+	if err != nil || info.Size() == 0 {
+		return
+	}
+
+	printVerbose(verboseActions, "wiping resource fork: %s\n", rsrcPath)
+	// Truncating the fork to zero removes it
+	overwriteAndTruncate(rsrcPath)
+}
+
+// appleDoublePath returns the "._name" sidecar macOS writes next to path
+// on volumes without native resource fork/xattr support.
+func appleDoublePath(path string) string {
+	return filepath.Join(filepath.Dir(path), "._"+filepath.Base(path))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWipeAppleDouble tests that wiping a file takes its "._" sidecar too
+func TestWipeAppleDouble(t *testing.T) {
+	oldParallel := *parallel
+	*parallel = 1
+	defer func() {
+		*parallel = oldParallel
+		wipeResults.wiped.Store(0)
+	}()
+
+	dir := t.TempDir()
+	file, sidecar := filepath.Join(dir, "secret.txt"), filepath.Join(dir, "._secret.txt")
+	for _, path := range []string{file, sidecar} {
+		if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wipeTargets([]string{file})
+	for _, path := range []string{file, sidecar} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected '%s' to be wiped", path)
+		}
+	}
+}
+
+// TestWipeResourceFork tests that the resource fork is emptied and the
+// data fork left to the overwrite
+func TestWipeResourceFork(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(file, []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	rsrcPath := filepath.Join(file, "..namedfork", "rsrc")
+	if err := os.WriteFile(rsrcPath, []byte("resource fork"), 0600); err != nil {
+		t.Skipf("Cannot write resource forks here: %v", err)
+	}
+
+	wipeResourceFork(file)
+	if info, err := os.Stat(rsrcPath); err == nil && info.Size() != 0 {
+		t.Errorf("Expected the resource fork to be emptied, has %d bytes", info.Size())
+	}
+	if data, err := os.ReadFile(file); err != nil || string(data) != "content" {
+		t.Errorf("Expected the data fork unchanged, got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !darwin
+
+package main
+
+// Resource forks and AppleDouble sidecars are macOS specific.
+func wipeResourceFork(path string) {}
+
+func appleDoublePath(path string) string {
+	return ""
+}
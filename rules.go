@@ -0,0 +1,344 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rulesDir holds the system-wide wipe rules, in files ending in .conf
+const rulesDir = "/etc/wipefile/rules.d"
+
+// rulesBatchSize is how many files one wipefile run of a rule gets, to
+// stay well below the argument size limit
+const rulesBatchSize = 256
+
+// wipeRule is a line of a rules file, in the style of tmpfiles.d: the
+// files below path whose name matches one of include and that weren't
+// modified for age are wiped with the options of profile.
+//
+//	# Path               Age  Include       Profile
+//	/var/spool/exports   30d  *.csv,*.xlsx  compliance
+//	/srv/uploads/tmp     7d
+type wipeRule struct {
+	source  string // file:line, for messages
+	path    string
+	age     time.Duration
+	include []string
+	profile string
+}
+
+// ageUnits are the units of rule ages, as in tmpfiles.d
+var ageUnits = map[string]time.Duration{
+	"s":   time.Second,
+	"sec": time.Second,
+	"m":   time.Minute,
+	"min": time.Minute,
+	"h":   time.Hour,
+	"d":   24 * time.Hour,
+	"w":   7 * 24 * time.Hour,
+}
+
+// parseAge parses an age like "30d" or "1w2d", where a number without a
+// unit is seconds and "-" is no age at all
+func parseAge(s string) (time.Duration, error) {
+	if s == "-" {
+		return 0, nil
+	}
+	if s == "" {
+		return 0, errors.New("invalid age ''")
+	}
+	var age time.Duration
+	rest := s
+	for rest != "" {
+		digits := 0
+		for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+			digits++
+		}
+		n, err := strconv.ParseInt(rest[:digits], 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age '%s'", s)
+		}
+		rest = rest[digits:]
+		letters := 0
+		for letters < len(rest) && (rest[letters] < '0' || rest[letters] > '9') {
+			letters++
+		}
+		unit := time.Second
+		if letters > 0 {
+			var ok bool
+			if unit, ok = ageUnits[rest[:letters]]; !ok {
+				return 0, fmt.Errorf("invalid age '%s': unknown unit '%s'", s, rest[:letters])
+			}
+		}
+		age += time.Duration(n) * unit
+		rest = rest[letters:]
+	}
+	return age, nil
+}
+
+// parseRules parses the rules of the rules file name. Columns are split at
+// whitespace, quotes keep a path with spaces together, and "-" or a
+// missing column takes the default: any age, every file, no profile.
+func parseRules(name, data string) ([]wipeRule, error) {
+	var rules []wipeRule
+	for number, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields, err := splitArgs(line)
+		if err == nil && len(fields) > 4 {
+			err = errors.New("more than 4 columns")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", name, number+1, err)
+		}
+		for len(fields) < 4 {
+			fields = append(fields, "-")
+		}
+
+		rule := wipeRule{source: fmt.Sprintf("%s:%d", name, number+1), path: fields[0]}
+		if !filepath.IsAbs(rule.path) {
+			return nil, fmt.Errorf("%s: path '%s' is not absolute", rule.source, rule.path)
+		}
+		if _, err := filepath.Match(rule.path, ""); err != nil {
+			return nil, fmt.Errorf("%s: invalid path pattern '%s'", rule.source, rule.path)
+		}
+		if rule.age, err = parseAge(fields[1]); err != nil {
+			return nil, fmt.Errorf("%s: %s", rule.source, err)
+		}
+		if fields[2] != "-" {
+			for _, pattern := range strings.Split(fields[2], ",") {
+				if _, err := filepath.Match(pattern, ""); err != nil || pattern == "" {
+					return nil, fmt.Errorf("%s: invalid include pattern '%s'", rule.source, pattern)
+				}
+				rule.include = append(rule.include, pattern)
+			}
+		}
+		if fields[3] != "-" {
+			rule.profile = fields[3]
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// loadRules returns the rules of files, or of the .conf files in rulesDir
+// in the order of their names if none are given
+func loadRules(files []string) ([]wipeRule, error) {
+	if len(files) == 0 {
+		files, _ = filepath.Glob(filepath.Join(rulesDir, "*.conf"))
+		sort.Strings(files)
+	}
+	var rules []wipeRule
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileRules, err := parseRules(file, string(data))
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, fileRules...)
+	}
+	return rules, nil
+}
+
+// knownProfile reports whether profiles has one called name
+func knownProfile(profiles []optionProfile, name string) bool {
+	for _, profile := range profiles {
+		if profile.name == strings.ToLower(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether the file name matches the include patterns of
+// the rule, which has none if it takes every file
+func (r wipeRule) included(name string) bool {
+	if len(r.include) == 0 {
+		return true
+	}
+	for _, pattern := range r.include {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// expiredFiles returns the regular files the rule applies to at now,
+// leaving out those in seen, which an earlier rule already took. The error
+// is the first directory that couldn't be read, whose files are left out.
+func (r wipeRule) expiredFiles(now time.Time, seen map[string]bool) ([]string, error) {
+	roots, _ := filepath.Glob(r.path)
+	var files []string
+	var firstErr error
+	for _, root := range roots {
+		expired, err := r.expiredBelow(root, now, seen)
+		files = append(files, expired...)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return files, firstErr
+}
+
+// expiredBelow returns the regular files at or below root that the rule
+// applies to at now, and aren't in seen yet. Symlinks are neither wiped
+// nor followed.
+func (r wipeRule) expiredBelow(root string, now time.Time, seen map[string]bool) ([]string, error) {
+	var files []string
+	var walkErr error
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if walkErr == nil {
+				walkErr = err
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || seen[path] || !r.included(entry.Name()) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		if now.Sub(info.ModTime()) >= r.age {
+			seen[path] = true
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, walkErr
+}
+
+// wipeRuleFiles runs wipefile with the profile of rule on files, in
+// batches, and returns the highest exit code of the runs. The dirs the
+// rule's path matches are passed with -below, so a file whose dir was
+// swapped for a symlink since it was found isn't wiped outside of them.
+func wipeRuleFiles(self string, rule wipeRule, files []string) int {
+	var options []string
+	if rule.profile != "" {
+		options = append(options, "-profile", rule.profile)
+	}
+	roots, _ := filepath.Glob(rule.path)
+	for _, root := range roots {
+		options = append(options, "-below", root)
+	}
+	if *quiet {
+		options = append(options, "-q")
+	}
+	if verbosity > 0 {
+		options = append(options, "-"+strings.Repeat("v", min(verbosity, verboseWrites)))
+	}
+
+	code := 0
+	for start := 0; start < len(files); start += rulesBatchSize {
+		batch := files[start:min(start+rulesBatchSize, len(files))]
+		cmd := exec.Command(self, append(append(options, "--"), batch...)...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		switch {
+		case errors.As(err, &exitErr):
+			code = max(code, exitErr.ExitCode())
+		case err != nil:
+			printError("cannot run rule %s: %s\n", rule.source, getSimpleError(err))
+			code = max(code, 1)
+		}
+	}
+	return code
+}
+
+// runApplyRulesCommand implements "wipefile apply-rules", which wipes the
+// files the rules in rulesDir, or the given rules files, say have expired,
+// so a system's retention policy can be kept in one place and applied
+// from a timer
+func runApplyRulesCommand(args []string) {
+	rulesFlags := flag.NewFlagSet("apply-rules", flag.ExitOnError)
+	dryRun := rulesFlags.Bool("dry-run", false, "Only list the files the rules would wipe")
+	registerOutputFlags(rulesFlags)
+	registerLogFlags(rulesFlags)
+	rulesFlags.BoolVar(quiet, "q", false, "Quiet: print nothing but errors")
+	rulesFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s apply-rules [options] [rules-file] ...\n", os.Args[0])
+		rulesFlags.PrintDefaults()
+	}
+	parseFlags(rulesFlags, "apply-rules", args)
+
+	rules, err := loadRules(rulesFlags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	if len(rules) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no rules in %s\n", rulesDir)
+		os.Exit(1)
+	}
+	// A misspelled profile fails before anything is wiped
+	profiles, err := loadProfiles()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	for _, rule := range rules {
+		if rule.profile != "" && !knownProfile(profiles, rule.profile) {
+			fmt.Fprintf(os.Stderr, "Error: %s: unknown profile '%s'\n", rule.source, rule.profile)
+			os.Exit(1)
+		}
+	}
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot find the wipefile executable: %s\n", getSimpleError(err))
+		os.Exit(1)
+	}
+	startLog()
+
+	code := 0
+	total := 0
+	now := time.Now()
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		files, err := rule.expiredFiles(now, seen)
+		if err != nil {
+			printError("rule %s: %s\n", rule.source, getSimpleError(err))
+			code = max(code, 1)
+		}
+		if len(files) == 0 {
+			printVerbose(verboseActions, "rule %s: nothing expired in '%s'\n", rule.source, rule.path)
+			continue
+		}
+		total += len(files)
+		if *dryRun {
+			for _, file := range files {
+				fmt.Printf("%s (rule %s)\n", file, rule.source)
+			}
+			continue
+		}
+		printStatus("rule %s: wiping %d files in '%s'\n", rule.source, len(files), rule.path)
+		code = max(code, wipeRuleFiles(self, rule, files))
+	}
+
+	if *dryRun {
+		printSummary("%d files would be wiped by %d rules\n", total, len(rules))
+	} else {
+		printSummary("applied %d rules to %d files\n", len(rules), total)
+	}
+	writeLog(logInfo, "applied %d rules to %d files, exit code %d\n", len(rules), total, code)
+	if code != 0 {
+		os.Exit(code)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// TestParseAge tests tmpfiles.d style ages, with and without units
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"-":     0,
+		"90":    90 * time.Second,
+		"30d":   30 * 24 * time.Hour,
+		"1w2d":  9 * 24 * time.Hour,
+		"1h30m": 90 * time.Minute,
+		"5min":  5 * time.Minute,
+	}
+	for s, want := range tests {
+		if got, err := parseAge(s); err != nil || got != want {
+			t.Errorf("parseAge(%q) = %s, %v, want %s", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "d", "3y", "1.5d", "-1d"} {
+		if _, err := parseAge(s); err == nil {
+			t.Errorf("parseAge(%q) succeeded", s)
+		}
+	}
+}
+
+// TestParseRules tests parsing rules files, with quoted paths, defaults and bad lines
+func TestParseRules(t *testing.T) {
+	rules, err := parseRules("test.conf", `
+# Path                 Age   Include        Profile
+/var/spool/exports     30d   *.csv,*.xlsx   compliance
+"/srv/shared docs"     7d
+/home/*/tmp            -     -              paranoid
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []wipeRule{
+		{"test.conf:3", "/var/spool/exports", 30 * 24 * time.Hour, []string{"*.csv", "*.xlsx"}, "compliance"},
+		{"test.conf:4", "/srv/shared docs", 7 * 24 * time.Hour, nil, ""},
+		{"test.conf:5", "/home/*/tmp", 0, nil, "paranoid"},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("parseRules = %+v, want %+v", rules, want)
+	}
+
+	for _, line := range []string{
+		"relative/path 1d",
+		"/tmp 1x",
+		"/tmp 1d [ -",
+		"/tmp 1d * fast extra",
+		"'/tmp 1d",
+	} {
+		if _, err := parseRules("test.conf", line); err == nil {
+			t.Errorf("parseRules(%q) succeeded", line)
+		}
+	}
+}
+
+// TestExpiredFiles tests that rules take old matching regular files, once across rules
+func TestExpiredFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"a.csv", "sub/b.csv", "c.txt", "new.csv"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if name != "new.csv" {
+			os.Chtimes(path, old, old)
+		}
+	}
+	os.Symlink(filepath.Join(dir, "c.txt"), filepath.Join(dir, "link.csv"))
+
+	seen := make(map[string]bool)
+	csvRule := wipeRule{path: dir, age: 24 * time.Hour, include: []string{"*.csv"}}
+	files, err := csvRule.expiredFiles(time.Now(), seen)
+	sort.Strings(files)
+	want := []string{filepath.Join(dir, "a.csv"), filepath.Join(dir, "sub", "b.csv")}
+	if err != nil || !reflect.DeepEqual(files, want) {
+		t.Errorf("expiredFiles = %q, %v, want %q", files, err, want)
+	}
+
+	// Files taken by the rule before are left out
+	allRule := wipeRule{path: filepath.Join(dir, "*")}
+	files, _ = allRule.expiredFiles(time.Now(), seen)
+	sort.Strings(files)
+	want = []string{filepath.Join(dir, "c.txt"), filepath.Join(dir, "new.csv")}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("expiredFiles after another rule = %q, want %q", files, want)
+	}
+}
+
+// TestExpiredFilesStayBelow tests that a file a rule found isn't wiped
+// when its dir is swapped for a symlink out of the rule's path before the
+// wipe, as -below makes the wipefile run of the rule check
+func TestExpiredFilesStayBelow(t *testing.T) {
+	oldParallel := *parallel
+	*parallel = 1
+	defer func() {
+		*parallel = oldParallel
+		wipeResults.wiped.Store(0)
+		wipeResults.failed.Store(0)
+		wipeResults.denied.Store(0)
+	}()
+
+	dir, outside := t.TempDir(), t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	for _, path := range []string{filepath.Join(dir, "sub", "a.csv"), filepath.Join(outside, "a.csv")} {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, old, old)
+	}
+	rule := wipeRule{path: dir, age: 24 * time.Hour}
+	files, err := rule.expiredFiles(time.Now(), make(map[string]bool))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expiredFiles = %q, %v", files, err)
+	}
+
+	os.RemoveAll(filepath.Join(dir, "sub"))
+	if err := os.Symlink(outside, filepath.Join(dir, "sub")); err != nil {
+		t.Skipf("Cannot create symlink: %v", err)
+	}
+	var below rootsFlag
+	if err := below.Set(dir); err != nil {
+		t.Fatal(err)
+	}
+	wipeTargetsBelow(files, below)
+	if _, err := os.Stat(filepath.Join(outside, "a.csv")); err != nil {
+		t.Errorf("File outside of the rule's path was wiped: %v", err)
+	}
+	if wipeResults.denied.Load() != 1 {
+		t.Errorf("Expected the file to be denied, got %d", wipeResults.denied.Load())
+	}
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// s3MaxPutSize is the largest object a single PUT can replace
+	s3MaxPutSize = 5 * 1024 * 1024 * 1024
+	// s3EmptyPayload is the SHA-256 of an empty request body
+	s3EmptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// s3UnsignedPayload lets the overwrite stream without hashing it first
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// s3Client talks to AWS S3 or an S3-compatible service, signing each
+// request with AWS Signature Version 4
+type s3Client struct {
+	endpoint     *url.URL
+	pathStyle    bool
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	client       *http.Client
+	now          func() time.Time
+}
+
+// s3Error is an error response from the object store
+type s3Error struct {
+	Status  int    `xml:"-"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// s3VersionList is one page of a ListObjectVersions response
+type s3VersionList struct {
+	IsTruncated         bool
+	NextKeyMarker       string
+	NextVersionIdMarker string
+	Versions            []s3Version `xml:"Version"`
+	DeleteMarkers       []s3Version `xml:"DeleteMarker"`
+}
+
+type s3Version struct {
+	Key       string
+	VersionId string
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status))
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Is makes denied requests and missing objects match fs.ErrPermission and
+// fs.ErrNotExist, so they are counted like files are
+func (e *s3Error) Is(target error) bool {
+	switch target {
+	case fs.ErrPermission:
+		return e.Status == http.StatusForbidden
+	case fs.ErrNotExist:
+		return e.Status == http.StatusNotFound
+	}
+	return false
+}
+
+// parseS3URI splits s3://bucket/key into its bucket and key
+func parseS3URI(uri string) (string, string, error) {
+	if !strings.HasPrefix(uri, "s3://") {
+		return "", "", fmt.Errorf("'%s' is not an s3://bucket/key URI", uri)
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(uri, "s3://"), "/")
+	if bucket == "" || key == "" {
+		return "", "", fmt.Errorf("'%s' is not an s3://bucket/key URI", uri)
+	}
+	return bucket, key, nil
+}
+
+// newS3Client returns a client for endpoint, or AWS S3 if it is empty,
+// with the credentials in AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN. The region defaults to AWS_REGION, then
+// AWS_DEFAULT_REGION, then us-east-1.
+func newS3Client(endpoint, region string) (*s3Client, error) {
+	client := &s3Client{
+		region:       region,
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{},
+		now:          time.Now,
+	}
+	if client.accessKey == "" || client.secretKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if client.region == "" {
+			client.region = os.Getenv(name)
+		}
+	}
+	if client.region == "" {
+		client.region = "us-east-1"
+	}
+
+	// Other services mostly don't do virtual-hosted buckets
+	client.pathStyle = endpoint != ""
+	if endpoint == "" {
+		endpoint = "https://s3." + client.region + ".amazonaws.com"
+	}
+	var err error
+	if client.endpoint, err = url.Parse(endpoint); err != nil {
+		return nil, err
+	}
+	if client.endpoint.Scheme != "http" && client.endpoint.Scheme != "https" || client.endpoint.Host == "" {
+		return nil, fmt.Errorf("'%s' is not an http or https URL", endpoint)
+	}
+	return client, nil
+}
+
+// s3Escape percent-encodes everything but unreserved characters, and
+// slashes if keepSlash is set, the way the signature expects
+func s3Escape(s string, keepSlash bool) string {
+	var escaped strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && keepSlash {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+// objectURL returns the URL of key in bucket, or of the bucket itself if
+// key is empty. Buckets with dots in their name are always addressed by
+// path, since they don't match the wildcard certificate.
+func (c *s3Client) objectURL(bucket, key string, query url.Values) *url.URL {
+	u := *c.endpoint
+	base := strings.TrimSuffix(u.Path, "/")
+	u.Path, u.RawPath = base+"/", s3Escape(base, true)+"/"
+	if c.pathStyle || strings.Contains(bucket, ".") {
+		u.Path += bucket
+		u.RawPath += s3Escape(bucket, false)
+		if key != "" {
+			u.Path += "/"
+			u.RawPath += "/"
+		}
+	} else {
+		u.Host = bucket + "." + u.Host
+	}
+	u.Path += key
+	u.RawPath += s3Escape(key, true)
+
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	params := make([]string, 0, len(names))
+	for _, name := range names {
+		params = append(params, s3Escape(name, false)+"="+s3Escape(query.Get(name), false))
+	}
+	u.RawQuery = strings.Join(params, "&")
+	return &u
+}
+
+// sign adds the AWS Signature Version 4 headers to req, whose body hashes
+// to payloadHash
+func (c *s3Client) sign(req *http.Request, payloadHash string) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + c.region + "/s3/aws4_request"
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") || name == "range" || name == "content-type" || name == "content-md5" {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + c.secretKey)
+	for _, part := range strings.Split(scope, "/") {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// do sends a signed request with size bytes of body and returns the
+// response, or an *s3Error if the service refused it. Bodies are sent
+// unsigned, so they can be streamed.
+func (c *s3Client) do(method, bucket, key string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+	payloadHash := s3EmptyPayload
+	if size == 0 {
+		body = nil
+	} else {
+		payloadHash = s3UnsignedPayload
+	}
+	req, err := http.NewRequest(method, "", body)
+	if err != nil {
+		return nil, err
+	}
+	req.URL = c.objectURL(bucket, key, query)
+	req.Host = req.URL.Host
+	req.ContentLength = size
+	c.sign(req, payloadHash)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		s3Err := &s3Error{}
+		xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(s3Err)
+		s3Err.Status = resp.StatusCode
+		return nil, s3Err
+	}
+	return resp, nil
+}
+
+// objectSize returns the size of key in bucket
+func (c *s3Client) objectSize(bucket, key string) (int64, error) {
+	resp, err := c.do(http.MethodHead, bucket, key, nil, nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("no size given for '%s'", key)
+	}
+	return resp.ContentLength, nil
+}
+
+// overwriteObject replaces key in bucket with size bytes of fakeData, and
+// reports whether the bucket kept the old data as a noncurrent version.
+func (c *s3Client) overwriteObject(bucket, key string, size int64, fakeData *fakeReader) (bool, error) {
+	if size > s3MaxPutSize {
+		return false, errors.New("objects over 5 GB can't be overwritten in a single request")
+	}
+	resp, err := c.do(http.MethodPut, bucket, key, nil, io.LimitReader(fakeData, size), size)
+	if err != nil {
+		return false, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	metrics.bytesOverwritten.Add(size)
+	versionID := resp.Header.Get("X-Amz-Version-Id")
+	return versionID != "" && versionID != "null", nil
+}
+
+// deleteObject deletes versionID of key in bucket, or the current version
+// if versionID is empty
+func (c *s3Client) deleteObject(bucket, key, versionID string) error {
+	var query url.Values
+	if versionID != "" {
+		query = url.Values{"versionId": {versionID}}
+	}
+	resp, err := c.do(http.MethodDelete, bucket, key, query, nil, 0)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// objectVersions returns the IDs of every version and delete marker of key
+// in bucket. The listing is by prefix, so other keys it turns up are left
+// out.
+func (c *s3Client) objectVersions(bucket, key string) ([]string, error) {
+	var versionIDs []string
+	query := url.Values{"versions": {""}, "prefix": {key}}
+	for {
+		resp, err := c.do(http.MethodGet, bucket, "", query, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		var list s3VersionList
+		err = xml.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read version list: %w", err)
+		}
+		for _, version := range append(list.Versions, list.DeleteMarkers...) {
+			if version.Key == key {
+				versionIDs = append(versionIDs, version.VersionId)
+			}
+		}
+		if !list.IsTruncated || list.NextKeyMarker == "" || list.NextKeyMarker > key {
+			return versionIDs, nil
+		}
+		query.Set("key-marker", list.NextKeyMarker)
+		query.Set("version-id-marker", list.NextVersionIdMarker)
+	}
+}
+
+// wipeObject overwrites the object at uri with fake header data of the
+// same size, then deletes it, and every other version of it too if
+// allVersions is set.
+func wipeObject(client *s3Client, uri string, allVersions bool, fakeData *fakeReader) {
+	startTarget(uri)
+	bucket, key, err := parseS3URI(uri)
+	if err != nil {
+		printError("%s\n", err)
+		countFailure(uri, nil)
+		return
+	}
+	size, err := client.objectSize(bucket, key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			printError("cannot wipe '%s': No such object\n", uri)
+			countMissing(uri)
+			return
+		}
+		printError("cannot wipe '%s': %s\n", uri, getSimpleError(err))
+		countFailure(uri, err)
+		return
+	}
+
+	printVerbose(verboseDetails, "overwriting '%s' (%d bytes)\n", uri, size)
+	versioned, err := client.overwriteObject(bucket, key, size, fakeData)
+	if err != nil {
+		printError("cannot overwrite '%s': %s\n", uri, getSimpleError(err))
+		countFailure(uri, err)
+		return
+	}
+
+	if allVersions {
+		versionIDs, err := client.objectVersions(bucket, key)
+		if err != nil {
+			printError("cannot list versions of '%s': %s\n", uri, getSimpleError(err))
+			countFailure(uri, err)
+			return
+		}
+		for _, versionID := range versionIDs {
+			if err := client.deleteObject(bucket, key, versionID); err != nil {
+				printError("cannot delete version %s of '%s': %s\n", versionID, uri, getSimpleError(err))
+				countFailure(uri, err)
+				return
+			}
+		}
+		printVerbose(verboseDetails, "deleted %d versions of '%s'\n", len(versionIDs), uri)
+	} else {
+		if err := client.deleteObject(bucket, key, ""); err != nil {
+			printError("cannot delete '%s': %s\n", uri, getSimpleError(err))
+			countFailure(uri, err)
+			return
+		}
+		if versioned {
+			printWarning("'%s' is in a versioned bucket, its earlier versions still hold the data (see -all-versions)\n", uri)
+		}
+	}
+	printResult("removed '%s'\n", uri)
+	countWiped(uri, size)
+}
+
+// runS3Command implements "wipefile s3", which overwrites objects in S3 or
+// an S3-compatible store before deleting them. The store decides where the
+// new data lands, so this can't reach the old bytes the way a file
+// overwrite does; what it does is leave nothing readable behind, in the
+// object or, with -all-versions, in its history.
+func runS3Command(args []string) {
+	s3Flags := flag.NewFlagSet("s3", flag.ExitOnError)
+	allVersions := s3Flags.Bool("all-versions", false, "Also delete all noncurrent versions and delete markers of each object")
+	endpoint := s3Flags.String("endpoint", "", "URL of an S3-compatible service, addressed path-style (default AWS S3)")
+	region := s3Flags.String("region", "", "Region to sign requests for (default $AWS_REGION, $AWS_DEFAULT_REGION or us-east-1)")
+	registerOutputFlags(s3Flags)
+	registerLogFlags(s3Flags)
+	s3Flags.BoolVar(quiet, "q", false, "Quiet: print nothing but errors")
+	s3Flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s s3 [options] s3://bucket/key...\n", os.Args[0])
+		s3Flags.PrintDefaults()
+	}
+	parseFlags(s3Flags, "s3", args)
+
+	if s3Flags.NArg() == 0 {
+		s3Flags.Usage()
+		os.Exit(1)
+	}
+	client, err := newS3Client(*endpoint, *region)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	startLog()
+
+	fakeData := newFakeReader()
+	for _, uri := range s3Flags.Args() {
+		wipeObject(client, uri, *allVersions, fakeData)
+	}
+	fakeData.Close()
+	reportFailures()
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// scheduledWipe is a recurring wipe from the schedules section of the
+// config file:
+//
+//	schedules:
+//	  scratch:
+//	    cron: 0 3 * * *
+//	    args: -s /scratch
+//	    jitter: 15m
+type scheduledWipe struct {
+	name   string
+	cron   *cronSchedule
+	args   []string
+	jitter time.Duration
+
+	mu      sync.Mutex
+	running *exec.Cmd // The run still going, nil if none
+}
+
+// parseSchedules returns the scheduled wipes of the schedules section of
+// the config file
+func parseSchedules(entries []optionProfile) ([]*scheduledWipe, error) {
+	var schedules []*scheduledWipe
+	for _, entry := range entries {
+		schedule := &scheduledWipe{name: entry.name}
+		for key, value := range entry.options {
+			var err error
+			switch key {
+			case "cron":
+				schedule.cron, err = parseCron(value)
+			case "args":
+				schedule.args, err = splitArgs(value)
+			case "jitter":
+				schedule.jitter, err = time.ParseDuration(value)
+			default:
+				err = fmt.Errorf("unknown key '%s'", key)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("schedule '%s': %s", entry.name, err)
+			}
+		}
+		switch {
+		case schedule.cron == nil:
+			return nil, fmt.Errorf("schedule '%s': no cron expression", entry.name)
+		case len(schedule.args) == 0:
+			return nil, fmt.Errorf("schedule '%s': no args", entry.name)
+		case schedule.args[0] == "schedule":
+			return nil, fmt.Errorf("schedule '%s': cannot run wipefile schedule", entry.name)
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+// splitArgs splits a command line at spaces, keeping what is in single or
+// double quotes together
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	quote := rune(0)
+	for _, c := range line {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote, inArg = c, true
+		case c == ' ' || c == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unclosed quote in args")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// runScheduleCommand implements "wipefile schedule", which runs the wipes
+// of the schedules section of the config file at their times, until
+// SIGINT or SIGTERM
+func runScheduleCommand(args []string) {
+	scheduleFlags := flag.NewFlagSet("schedule", flag.ExitOnError)
+	registerOutputFlags(scheduleFlags)
+	registerLogFlags(scheduleFlags)
+	list := scheduleFlags.Bool("list", false, "List the schedules with their next run, and exit")
+	scheduleFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s schedule [options]\n", os.Args[0])
+		scheduleFlags.PrintDefaults()
+	}
+	parseFlags(scheduleFlags, "schedule", args)
+	if scheduleFlags.NArg() != 0 {
+		scheduleFlags.Usage()
+		os.Exit(1)
+	}
+
+	schedules, err := loadSchedules()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	if len(schedules) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no schedules in %s\n", configPath())
+		os.Exit(1)
+	}
+	if *list {
+		for _, schedule := range schedules {
+			next := "never"
+			if at := schedule.cron.next(time.Now()); !at.IsZero() {
+				next = at.Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%s: wipefile %s, next %s\n", schedule.name, strings.Join(schedule.args, " "), next)
+		}
+		return
+	}
+	startLog()
+
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot find the wipefile executable: %s\n", getSimpleError(err))
+		os.Exit(1)
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, schedule := range schedules {
+		wg.Add(1)
+		go func(schedule *scheduledWipe) {
+			defer wg.Done()
+			schedule.loop(self, stop)
+		}(schedule)
+	}
+	printStatus("scheduling %d wipes\n", len(schedules))
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	sig := <-signals
+	printStatus("stopping scheduler\n")
+	close(stop)
+	for _, schedule := range schedules {
+		schedule.signal(sig)
+	}
+	wg.Wait()
+}
+
+// loadSchedules returns the schedules of the config file
+func loadSchedules() ([]*scheduledWipe, error) {
+	path := configPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sections, err := parseConfigSections(string(data))
+	if err == nil {
+		var schedules []*scheduledWipe
+		schedules, err = parseSchedules(sections["schedules"])
+		if err == nil {
+			return schedules, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", path, err)
+}
+
+// loop starts the wipe of the schedule at each of its times, plus a random
+// part of its jitter, until stop is closed, and then waits for a run still
+// going. A run isn't started while the last one is still going.
+func (s *scheduledWipe) loop(self string, stop chan struct{}) {
+	var runs sync.WaitGroup
+	defer runs.Wait()
+	for {
+		next := s.cron.next(time.Now())
+		if next.IsZero() {
+			printWarning("schedule '%s' never runs\n", s.name)
+			return
+		}
+		if s.jitter > 0 {
+			next = next.Add(time.Duration(rand.Int63n(int64(s.jitter))))
+		}
+		printVerbose(verboseDetails, "schedule '%s': next run at %s\n", s.name, next.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.mu.Lock()
+		if s.running != nil {
+			s.mu.Unlock()
+			printWarning("skipping schedule '%s': its last run is still going\n", s.name)
+			continue
+		}
+		cmd := exec.Command(self, s.args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			s.mu.Unlock()
+			printError("cannot run schedule '%s': %s\n", s.name, getSimpleError(err))
+			continue
+		}
+		s.running = cmd
+		s.mu.Unlock()
+		printStatus("running schedule '%s': wipefile %s\n", s.name, strings.Join(s.args, " "))
+
+		runs.Add(1)
+		go func() {
+			defer runs.Done()
+			err := cmd.Wait()
+			s.mu.Lock()
+			s.running = nil
+			s.mu.Unlock()
+			if err != nil {
+				printError("schedule '%s' failed: %s\n", s.name, getSimpleError(err))
+			} else {
+				printStatus("schedule '%s' finished\n", s.name)
+			}
+		}()
+	}
+}
+
+// signal passes sig on to the running wipe of the schedule, if any, so it
+// can clean up before the scheduler exits
+func (s *scheduledWipe) signal(sig os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running != nil {
+		s.running.Process.Signal(sig)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseSchedules tests reading schedules from the config file
+func TestParseSchedules(t *testing.T) {
+	sections, err := parseConfigSections("profiles:\n  team:\n    xattrs: true\nschedules:\n  scratch:\n    cron: 0 3 * * *\n    args: -s '/mnt/my scratch'\n    jitter: 15m\n")
+	if err != nil {
+		t.Fatalf("parseConfigSections failed: %v", err)
+	}
+	if len(sections["profiles"]) != 1 {
+		t.Errorf("Expected the profile to be kept, got %v", sections["profiles"])
+	}
+	schedules, err := parseSchedules(sections["schedules"])
+	if err != nil {
+		t.Fatalf("parseSchedules failed: %v", err)
+	}
+	if len(schedules) != 1 || schedules[0].name != "scratch" || schedules[0].jitter.Minutes() != 15 {
+		t.Fatalf("Unexpected schedules: %+v", schedules)
+	}
+	if expected := []string{"-s", "/mnt/my scratch"}; !reflect.DeepEqual(schedules[0].args, expected) {
+		t.Errorf("Expected args %q, got %q", expected, schedules[0].args)
+	}
+
+	for _, invalid := range []string{
+		"schedules:\n  a:\n    args: -s /scratch\n",
+		"schedules:\n  a:\n    cron: '@daily'\n",
+		"schedules:\n  a:\n    cron: '@daily'\n    args: schedule\n",
+		"schedules:\n  a:\n    cron: '@daily'\n    args: -s /scratch\n    every: day\n",
+		"schedules:\n  a:\n    cron: '@daily'\n    args: -s '/scratch\n",
+	} {
+		sections, err := parseConfigSections(invalid)
+		if err == nil {
+			_, err = parseSchedules(sections["schedules"])
+		}
+		if err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+// TestSplitArgs tests splitting schedule args like a shell would
+func TestSplitArgs(t *testing.T) {
+	args, err := splitArgs(`-r  "/var/spool/old files" -pattern 'jpg,png'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"-r", "/var/spool/old files", "-pattern", "jpg,png"}; !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %q, got %q", expected, args)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// packedFilesystems pack or compress the tails of files, so the end of a
+// file's last block isn't slack that can be overwritten in place
+var packedFilesystems = map[string]bool{
+	"squashfs": true,
+	"erofs":    true,
+	"cramfs":   true,
+	"jffs2":    true,
+	"ubifs":    true,
+	"reiserfs": true,
+}
+
+// errSlackLocked is returned when another process holds a lock on a file
+var errSlackLocked = errors.New("Locked by another process")
+
+// wipeSlack overwrites the file slack of path, or of every file below it
+// if it is a directory, leaving the file contents alone.
+func wipeSlack(path string) {
+	files := 0
+	total := int64(0)
+	skipDevs := make(map[uint64]bool)
+	err := filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			printError("cannot access '%s': %s\n", filePath, getSimpleError(err))
+			if os.IsNotExist(err) {
+				countMissing(filePath)
+			} else {
+				countFailure(filePath, err)
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || skipSlack(filePath, skipDevs) {
+			return nil
+		}
+		wiped, err := wipeFileSlack(filePath)
+		if err != nil {
+			printError("cannot wipe slack of '%s': %s\n", filePath, getSimpleError(err))
+			countFailure(filePath, err)
+			return nil
+		}
+		if wiped > 0 {
+			files++
+			total += wiped
+			printVerbose(verboseActions, "overwrote %d bytes of slack in '%s'\n", wiped, filePath)
+		}
+		return nil
+	})
+	if err != nil {
+		printError("cannot wipe slack of '%s': %s\n", path, getSimpleError(err))
+		countFailure(path, err)
+		return
+	}
+	printSummary("overwrote slack of %d files (%d KB)\n", files, total/1024)
+}
+
+// skipSlack reports whether filePath is on a filesystem without slack to
+// overwrite in place, warning once per device. A copy-on-write filesystem
+// writes the extension elsewhere and its block size isn't the allocation
+// unit, and packed or compressed ones share the last block between files.
+func skipSlack(filePath string, skipDevs map[uint64]bool) bool {
+	dev, err := deviceOf(filePath)
+	if err != nil {
+		return false
+	}
+	if skip, ok := skipDevs[dev]; ok {
+		return skip
+	}
+	fsType, err := filesystemType(filePath)
+	skip := err == nil && (cowFilesystems[fsType] || packedFilesystems[fsType])
+	if skip {
+		printWarning("skipping slack of files on %s like '%s': the filesystem doesn't keep slack in place\n", fsType, filePath)
+	}
+	skipDevs[dev] = skip
+	return skip
+}
+
+// wipeFileSlack overwrites the bytes between the end of filePath and the
+// end of its last allocated block, which can still hold data of a deleted
+// file. The file is locked, extended over its slack with fake header data,
+// synced, then truncated back, and its times are restored. The truncate is
+// left out if the file changed meanwhile, as it would cut off what another
+// process appended. It returns the number of slack bytes overwritten.
+func wipeFileSlack(filePath string) (int64, error) {
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	if err := lockSlack(file); err != nil {
+		return 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	unit := allocationUnit(filePath, info)
+	if size == 0 || size%unit == 0 {
+		return 0, nil // No partial block, so no slack
+	}
+	slack := unit - size%unit
+
+	data := make([]byte, slack)
+	fakeData := newFakeReader()
+	fakeData.Read(data)
+	fakeData.Close()
+	if _, err := writeAtWithRetry(file, data, size); err != nil {
+		file.Truncate(size)
+		return 0, err
+	}
+	if err := syncFile(file); err != nil {
+		file.Truncate(size)
+		return 0, err
+	}
+	if now, err := file.Stat(); err != nil {
+		return 0, err
+	} else if now.Size() != size+slack {
+		return 0, fmt.Errorf("Changed while its slack was overwritten, left at %d bytes", now.Size())
+	}
+	if err := file.Truncate(size); err != nil {
+		return 0, err
+	}
+	if err := syncFile(file); err != nil {
+		return 0, err
+	}
+
+	// Writing changed the modification time, which would give away that
+	// the file was touched
+	if err := os.Chtimes(filePath, fileAccessTime(info), info.ModTime()); err != nil {
+		printVerboseWarning(verboseActions, "cannot restore times of '%s': %s\n", filePath, getSimpleError(err))
+	}
+	return slack, nil
+}
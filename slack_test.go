@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWipeFileSlack tests that slack is overwritten without changing content or times
+func TestWipeFileSlack(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.txt")
+	content := bytes.Repeat([]byte("slack"), 1000)
+	if err := os.WriteFile(testFile, content, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(testFile, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	info, err := os.Stat(testFile)
+	if err != nil {
+		t.Fatalf("Failed to stat test file: %v", err)
+	}
+	unit := allocationUnit(testFile, info)
+
+	wiped, err := wipeFileSlack(testFile)
+	if err != nil {
+		t.Fatalf("wipeFileSlack failed: %v", err)
+	}
+	if expected := unit - int64(len(content))%unit; wiped != expected {
+		t.Errorf("Expected %d bytes of slack, got %d", expected, wiped)
+	}
+
+	after, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	if !bytes.Equal(after, content) {
+		t.Error("File content should be unchanged")
+	}
+	if info, err := os.Stat(testFile); err != nil || !info.ModTime().Equal(modTime) {
+		t.Errorf("Modification time should be restored, got %v", info.ModTime())
+	}
+}
+
+// TestSkipSlack tests that slack is skipped on copy-on-write filesystems,
+// checking each device once
+func TestSkipSlack(t *testing.T) {
+	dir := t.TempDir()
+	fsType, err := filesystemType(dir)
+	if err != nil {
+		t.Skipf("Cannot get filesystem type: %v", err)
+	}
+	skipDevs := make(map[uint64]bool)
+	if skip := skipSlack(dir, skipDevs); skip != (cowFilesystems[fsType] || packedFilesystems[fsType]) {
+		t.Errorf("Expected skip %v on %s, got %v", !skip, fsType, skip)
+	}
+
+	dev, err := deviceOf(dir)
+	if err != nil {
+		t.Fatalf("Failed to get device: %v", err)
+	}
+	skipDevs[dev] = true
+	if !skipSlack(dir, skipDevs) {
+		t.Error("Expected the result for the device to be reused")
+	}
+}
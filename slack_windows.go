@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+)
+
+var procLockFileEx = kernel32.NewProc("LockFileEx")
+
+// lockSlack locks every byte of file, including those past its end, so no
+// other process can write to it while the slack is overwritten. The lock
+// goes with the handle.
+func lockSlack(file *os.File) error {
+	var overlapped syscall.Overlapped
+	if ret, _, err := procLockFileEx.Call(file.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0,
+		0xFFFFFFFF, 0xFFFFFFFF, uintptr(unsafe.Pointer(&overlapped))); ret == 0 {
+		if isLockedError(err) {
+			return errSlackLocked
+		}
+		return err
+	}
+	return nil
+}
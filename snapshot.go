@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// snapshot is a snapshot of a btrfs subvolume or ZFS dataset. dir is
+// where its contents can be read, or "" if it isn't mounted anywhere.
+type snapshot struct {
+	name string
+	dir  string
+}
+
+// volumeSnapshots are the snapshots of the subvolume or dataset at root
+type volumeSnapshots struct {
+	fsType    string
+	root      string
+	snapshots []snapshot
+}
+
+var (
+	snapshotsMu    sync.Mutex
+	snapshotsByDev = make(map[uint64]*volumeSnapshots)
+)
+
+// snapshotsOf returns the snapshots of the btrfs subvolume or ZFS dataset
+// holding path, looked up once per device. It returns nil elsewhere.
+func snapshotsOf(path string) *volumeSnapshots {
+	dev, err := deviceOf(path)
+	if err != nil {
+		return nil
+	}
+
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	if volume, ok := snapshotsByDev[dev]; ok {
+		return volume
+	}
+
+	var volume *volumeSnapshots
+	fsType, err := filesystemType(path)
+	if err == nil && (fsType == "btrfs" || fsType == "zfs") {
+		// Subvolumes and datasets have their own device ID, so this finds
+		// the subvolume or dataset root rather than the pool's
+		if root, err := mountPoint(filepath.Dir(path)); err == nil {
+			volume = &volumeSnapshots{fsType: fsType, root: root}
+			if fsType == "zfs" {
+				volume.snapshots, err = zfsSnapshots(root)
+			} else {
+				volume.snapshots, err = btrfsSnapshots(root)
+			}
+			if err != nil {
+				printVerboseWarning(verboseActions, "cannot list snapshots of '%s': %s\n", root, getSimpleError(err))
+			}
+		}
+	}
+	snapshotsByDev[dev] = volume
+	return volume
+}
+
+// zfsSnapshots lists the snapshots of the dataset mounted at root, which
+// ZFS exposes under .zfs/snapshot even when the directory is hidden.
+func zfsSnapshots(root string) ([]snapshot, error) {
+	snapDir := filepath.Join(root, ".zfs", "snapshot")
+	entries, err := os.ReadDir(snapDir)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []snapshot
+	for _, entry := range entries {
+		snapshots = append(snapshots, snapshot{name: entry.Name(), dir: filepath.Join(snapDir, entry.Name())})
+	}
+	return snapshots, nil
+}
+
+// warnSnapshotCopies lists the btrfs or ZFS snapshots that still hold
+// the data of filePath, which the overwrite can't reach.
+func warnSnapshotCopies(filePath string) {
+	volume := snapshotsOf(filePath)
+	if volume == nil || len(volume.snapshots) == 0 {
+		return
+	}
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return
+	}
+	relPath, err := filepath.Rel(volume.root, absPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return
+	}
+
+	var holding, unchecked []string
+	for _, snap := range volume.snapshots {
+		if snap.dir == "" {
+			unchecked = append(unchecked, snap.name)
+			continue
+		}
+		copyPath := filepath.Join(snap.dir, relPath)
+		if info, err := os.Lstat(copyPath); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if volume.fsType == "btrfs" {
+			if shared, err := sharesBlocks(filePath, copyPath); err == nil && !shared {
+				holding = append(holding, snap.name+" (older version)")
+				continue
+			}
+		}
+		holding = append(holding, snap.name)
+	}
+
+	if len(holding) > 0 {
+		printWarning("'%s' is still held by %d %s snapshots, which the wipe does not reach: %s\n", filePath, len(holding), volume.fsType, strings.Join(holding, ", "))
+	}
+	if len(unchecked) > 0 {
+		printVerbose(verboseActions, "'%s': cannot check unmounted snapshots: %s\n", filePath, strings.Join(unchecked, ", "))
+	}
+}
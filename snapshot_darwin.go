@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const tmSnapshotPrefix = "com.apple.TimeMachine."
+
+// checkSnapshots warns when the APFS volume holding path has local Time
+// Machine snapshots, since they keep the old contents of every wiped file.
+// With -thin-snapshots they are deleted instead.
+func checkSnapshots(path string) {
+	if fsType, err := filesystemType(path); err != nil || fsType != "apfs" {
+		return
+	}
+	volume, err := mountPoint(path)
+	if err != nil {
+		return
+	}
+
+	snapshots, err := localSnapshots(volume)
+	if err != nil {
+		printVerboseWarning(verboseActions, "cannot list local snapshots of '%s': %s\n", volume, getSimpleError(err))
+		return
+	}
+	if len(snapshots) == 0 {
+		return
+	}
+
+	if !*thinSnapshots {
+		printWarning("'%s' has %d local Time Machine snapshots, which may still hold the wiped files (use -thin-snapshots)\n", volume, len(snapshots))
+		for _, snapshot := range snapshots {
+			printVerbose(verboseActions, "local snapshot: %s\n", snapshot)
+		}
+		return
+	}
+
+	for _, snapshot := range snapshots {
+		date := strings.TrimSuffix(strings.TrimPrefix(snapshot, tmSnapshotPrefix), ".local")
+		if output, err := exec.Command("tmutil", "deletelocalsnapshots", date).CombinedOutput(); err != nil {
+			printError("cannot delete snapshot '%s': %s\n", snapshot, strings.TrimSpace(string(output)))
+		} else {
+			printVerbose(verboseActions, "deleted local snapshot '%s'\n", snapshot)
+		}
+	}
+
+	// Deleting can fail quietly, e.g. for snapshots in use by a backup
+	remaining, err := localSnapshots(volume)
+	if err == nil && len(remaining) > 0 {
+		printWarning("'%s' still has %d local snapshots\n", volume, len(remaining))
+	} else if err == nil {
+		printStatus("deleted %d local snapshots of '%s'\n", len(snapshots), volume)
+	}
+}
+
+// localSnapshots returns the local Time Machine snapshots of the volume
+// mounted at volume, as listed by tmutil.
+func localSnapshots(volume string) ([]string, error) {
+	output, err := exec.Command("tmutil", "listlocalsnapshots", volume).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("tmutil: %s", strings.TrimSpace(string(output)))
+	}
+
+	var snapshots []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, tmSnapshotPrefix) {
+			snapshots = append(snapshots, line)
+		}
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,7 @@
+//go:build !windows && !darwin
+
+package main
+
+// Volume Shadow Copies and local Time Machine snapshots are Windows and
+// macOS specific.
+func checkSnapshots(path string) {}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// checkSnapshots warns when Volume Shadow Copies of the volume holding path
+// exist, since they keep the old contents of every wiped file. With
+// -purge-shadow-copies they are deleted instead. Needs admin rights.
+func checkSnapshots(path string) {
+	volume, err := mountPoint(path)
+	if err != nil {
+		return
+	}
+	volume = strings.TrimSuffix(volume, `\`)
+
+	shadows, err := shadowCopies(volume)
+	if err != nil {
+		printVerboseWarning(verboseActions, "cannot list shadow copies of %s: %s\n", volume, getSimpleError(err))
+		return
+	}
+	if len(shadows) == 0 {
+		return
+	}
+
+	if !*purgeShadows {
+		printWarning("%s has %d shadow copies, which may still hold the wiped files (use -purge-shadow-copies)\n", volume, len(shadows))
+		for _, shadow := range shadows {
+			printVerbose(verboseActions, "shadow copy: %s\n", shadow)
+		}
+		return
+	}
+
+	output, err := exec.Command("vssadmin", "delete", "shadows", "/for="+volume, "/all", "/quiet").CombinedOutput()
+	if err != nil {
+		printError("cannot delete shadow copies of %s: %s\n", volume, strings.TrimSpace(string(output)))
+		return
+	}
+	printStatus("deleted %d shadow copies of %s\n", len(shadows), volume)
+}
+
+// shadowCopies returns the shadow copy volumes of volume (e.g. "C:"), as
+// listed by vssadmin. The device names are matched rather than the labels,
+// which are translated on non-English systems.
+func shadowCopies(volume string) ([]string, error) {
+	output, err := exec.Command("vssadmin", "list", "shadows", "/for="+volume).CombinedOutput()
+
+	var shadows []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if i := strings.Index(line, `\\?\GLOBALROOT\Device\HarddiskVolumeShadowCopy`); i >= 0 {
+			shadows = append(shadows, strings.TrimSpace(line[i:]))
+		}
+	}
+	if len(shadows) == 0 && err != nil && !strings.Contains(string(output), "No items found") {
+		return nil, fmt.Errorf("vssadmin: %s", strings.TrimSpace(string(output)))
+	}
+	return shadows, nil
+}
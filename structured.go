@@ -0,0 +1,219 @@
+package main
+
+import (
+	"crypto/cipher"
+)
+
+const (
+	structuredMinFile = 64 * 1024
+	structuredMaxFile = 4 * 1024 * 1024
+)
+
+// structuredFormat describes a fake file that stays consistent with its
+// header past the first 4K: after the header comes a sequence of records,
+// each a rendered template followed by up to payload random bytes.
+type structuredFormat struct {
+	header  string
+	records []string
+	payload int
+	// stuffed escapes 0xff in the payload like JPEG entropy-coded data,
+	// so it holds no stray markers
+	stuffed bool
+}
+
+var structuredFormats = []structuredFormat{
+	// .zip (and .docx/.xlsx): local file headers with data descriptors,
+	// each followed by deflated data
+	{
+		header: "\\50\\4b\\03\\04\\14\\00\\08\\00\\08\\00%x{4}\\00{12}\\13\\00\\00\\00[Content_Types].xml",
+		records: []string{
+			"\\50\\4b\\07\\08%x{6}\\00\\00%x%x%x\\00\\50\\4b\\03\\04\\14\\00\\08\\00\\08\\00%x{4}\\00{12}\\11\\00\\00\\00word/document.xml",
+			"\\50\\4b\\07\\08%x{6}\\00\\00%x%x%x\\00\\50\\4b\\03\\04\\14\\00\\08\\00\\08\\00%x{4}\\00{12}\\0f\\00\\00\\00word/styles.xml",
+			"\\50\\4b\\07\\08%x{6}\\00\\00%x%x%x\\00\\50\\4b\\03\\04\\14\\00\\08\\00\\08\\00%x{4}\\00{12}\\10\\00\\00\\00docProps/app.xml",
+			"\\50\\4b\\07\\08%x{6}\\00\\00%x%x%x\\00\\50\\4b\\03\\04\\14\\00\\08\\00\\08\\00%x{4}\\00{12}\\18\\00\\00\\00xl/worksheets/sheet%d.xml",
+			"\\50\\4b\\07\\08%x{6}\\00\\00%x%x%x\\00\\50\\4b\\03\\04\\14\\00\\08\\00\\08\\00%x{4}\\00{12}\\0c\\00\\00\\00IMG_%d{4}.jpg",
+		},
+		payload: 32 * 1024,
+	},
+
+	// .jpg: JFIF, quantization table, frame and scan headers, then
+	// entropy-coded data split by restart markers
+	{
+		header: "\\ff\\d8\\ff\\e0\\00\\10\\4a\\46\\49\\46\\00\\01\\01\\01\\00\\48\\00\\48\\00\\00" +
+			"\\ff\\db\\00\\43\\00%x{64}" +
+			"\\ff\\c0\\00\\11\\08%x{4}\\03\\01\\22\\00\\02\\11\\01\\03\\11\\01" +
+			"\\ff\\dd\\00\\04\\00%x" +
+			"\\ff\\da\\00\\0c\\03\\01\\00\\02\\11\\03\\11\\00\\3f\\00",
+		records: []string{
+			"\\ff\\d0", "\\ff\\d1", "\\ff\\d2", "\\ff\\d3", "\\ff\\d4", "\\ff\\d5", "\\ff\\d6", "\\ff\\d7",
+		},
+		payload: 8 * 1024,
+		stuffed: true,
+	},
+
+	mysqlDumpFormat,
+
+	// .pdf: a chain of compressed stream objects
+	{
+		header: "\\25PDF-1.7\\0a1 0 obj\\0a<< /Type /Catalog /Pages 2 0 R >>\\0aendobj\\0a3 0 obj\\0a<< /Filter /FlateDecode\\0a/Length %d{5}? >>\\0astream\\0a",
+		records: []string{
+			"\\0aendstream\\0aendobj\\0a%d%d%d? 0 obj\\0a<< /Filter /FlateDecode\\0a/Length %d{5}? >>\\0astream\\0a",
+			"\\0aendstream\\0aendobj\\0a%d%d%d? 0 obj\\0a<< /Type /XObject /Subtype /Image /Width %d%d%d /Height %d%d%d /BitsPerComponent 8 /Filter /DCTDecode /Length %d{5}? >>\\0astream\\0a",
+		},
+		payload: 16 * 1024,
+	},
+}
+
+// mysqlDumpFormat is a MySQL dump: one table definition, then rows of inserts
+var mysqlDumpFormat = structuredFormat{
+	header: "-- MySQL dump 10.1%d  Distrib 8.%d.%d%d, for Linux (x86_64)\\0a--\\0a-- Host: localhost    Database: %l{5}?\\0a" +
+		"-- ------------------------------------------------------\\0a\\0a" +
+		"CREATE TABLE `users` (\\0a  `id` int NOT NULL AUTO_INCREMENT,\\0a  `name` varchar(64) NOT NULL,\\0a" +
+		"  `email` varchar(255) NOT NULL,\\0a  `password` char(60) NOT NULL,\\0a  `created_at` datetime NOT NULL,\\0a" +
+		"  PRIMARY KEY (`id`)\\0a) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\\0a\\0a",
+	records: []string{
+		"INSERT INTO `users` VALUES (%d{4}?%d?,'%c%l{4}?%l?','%l{5}?%l?@%l{5}?.com','$2y$10$%b{53}','20%d%d-0%d-1%d %d%d:%d%d:%d%d');\\0a",
+		"INSERT INTO `users` VALUES (%d{4}?%d?,'%c%l{4}?%l? %c%l{5}?%l?','%l{5}?.%l{5}?@%l{5}?.%l%l','$2y$10$%b{53}','20%d%d-1%d-0%d %d%d:%d%d:%d%d');\\0a",
+	},
+}
+
+// textFormats are the formats -entropy low generates: printable and
+// compressible, like the logs, dumps and source code found on most disks
+var textFormats = []structuredFormat{
+	mysqlDumpFormat,
+
+	// syslog with high-precision timestamps
+	{
+		header: "20%d%d-0%d-1%dT%d%d:%d%d:%d%d.%d{6}+00:00 %l{5}? systemd[1]: Started Session %d%d%d? of User %l{5}?.\\0a",
+		records: []string{
+			"20%d%d-0%d-1%dT%d%d:%d%d:%d%d.%d{6}+00:00 %l{5}? sshd[%d{5}?]: Accepted publickey for %l{5}? from 10.%d.%d%d?.%d%d? port %d{5} ssh2: ED25519 SHA256:%b{22}\\0a",
+			"20%d%d-0%d-1%dT%d%d:%d%d:%d%d.%d{6}+00:00 %l{5}? CRON[%d{5}?]: pam_unix(cron:session): session opened for user root(uid=0) by (uid=0)\\0a",
+			"20%d%d-0%d-1%dT%d%d:%d%d:%d%d.%d{6}+00:00 %l{5}? CRON[%d{5}?]: pam_unix(cron:session): session closed for user root\\0a",
+			"20%d%d-0%d-1%dT%d%d:%d%d:%d%d.%d{6}+00:00 %l{5}? systemd[1]: Starting Daily apt upgrade and clean activities...\\0a",
+			"20%d%d-0%d-1%dT%d%d:%d%d:%d%d.%d{6}+00:00 %l{5}? kernel: [%d{5}.%d{6}] usb 1-%d: new high-speed USB device number %d using xhci_hcd\\0a",
+		},
+	},
+
+	// Go source
+	{
+		header: "package %l{5}?\\0a\\0aimport (\\0a\\09\"fmt\"\\0a\\09\"os\"\\0a\\09\"strings\"\\0a)\\0a\\0a",
+		records: []string{
+			"// %c%l{5}? returns the %l{5}? of %l{4}?\\0afunc %c%l{5}?(%l%l%l? string) (int, error) {\\0a\\09if %l%l%l? == \"\" {\\0a\\09\\09return 0, fmt.Errorf(\"empty %l{4}?\")\\0a\\09}\\0a\\09return len(%l%l%l?), nil\\0a}\\0a\\0a",
+			"func (%l *%l{5}?) %c%l{4}?() {\\0a\\09for _, %l%l := range %l.%l{5}? {\\0a\\09\\09fmt.Println(strings.TrimSpace(%l%l))\\0a\\09}\\0a}\\0a\\0a",
+			"func %l{6}?() {\\0a\\09if err := %l{4}?(); err != nil {\\0a\\09\\09fmt.Fprintln(os.Stderr, err)\\0a\\09\\09os.Exit(1)\\0a\\09}\\0a}\\0a\\0a",
+		},
+	},
+
+	// Python source
+	{
+		header: "#!/usr/bin/env python3\\0a\\0aimport json\\0aimport os\\0aimport sys\\0a\\0a\\0a",
+		records: []string{
+			"def %l{5}?_%l{4}?(%l{4}?):\\0a    with open(%l{4}?) as f:\\0a        return json.load(f)\\0a\\0a\\0a",
+			"class %c%l{5}?:\\0a    def __init__(self, %l{4}?):\\0a        self.%l{4}? = %l{4}?\\0a\\0a    def __repr__(self):\\0a        return f\"%c%l{5}?({self.%l{4}?!r})\"\\0a\\0a\\0a",
+			"if __name__ == \"__main__\":\\0a    sys.exit(%l{4}?(sys.argv[1:]))\\0a",
+		},
+	},
+}
+
+// fakeFile generates a run of structured fake files, one after the other.
+// Each file is a multiple of 4K long, so every file still starts at a 4K
+// boundary of the stream.
+type fakeFile struct {
+	formats []structuredFormat
+	minSize int
+	maxSize int
+
+	format    *structuredFormat
+	remaining int
+	pending   []byte
+	payload   int
+	scratch   []byte
+}
+
+// newFakeFile returns the generator for -structured and -entropy low, or
+// nil if neither is set. With only -entropy low, each fake file is a single
+// 4K block, like the unrelated headers of the default mode.
+func newFakeFile() *fakeFile {
+	formats := structuredFormats
+	if *entropy == "low" {
+		formats = textFormats
+	}
+	switch {
+	case *structured:
+		return &fakeFile{formats: formats, minSize: structuredMinFile, maxSize: structuredMaxFile}
+	case *entropy == "low":
+		return &fakeFile{formats: formats, minSize: bufferSize, maxSize: bufferSize}
+	}
+	return nil
+}
+
+// fill fills buf with the continuation of the current fake file, starting
+// new ones as they end
+func (f *fakeFile) fill(buf []byte, stream cipher.Stream) {
+	for len(buf) > 0 {
+		if f.remaining == 0 {
+			f.start(stream)
+		}
+		n := min(len(buf), f.remaining)
+		f.fillBody(buf[:n], stream)
+		buf = buf[n:]
+		f.remaining -= n
+	}
+}
+
+// start begins a new fake file of a random format and size
+func (f *fakeFile) start(stream cipher.Stream) {
+	f.format = &f.formats[generatorRand.Intn(len(f.formats))]
+	f.remaining = f.minSize
+	if f.maxSize > f.minSize {
+		f.remaining = (f.minSize + generatorRand.Intn(f.maxSize-f.minSize)) / bufferSize * bufferSize
+	}
+	f.scratch = renderTemplate(f.scratch[:0], f.format.header, stream)
+	f.pending = f.scratch
+	f.payload = generatorRand.Intn(f.format.payload + 1)
+}
+
+func (f *fakeFile) fillBody(buf []byte, stream cipher.Stream) {
+	for len(buf) > 0 {
+		if len(f.pending) > 0 {
+			copied := copy(buf, f.pending)
+			f.pending = f.pending[copied:]
+			buf = buf[copied:]
+			continue
+		}
+		if f.payload > 0 {
+			payload := buf[:min(len(buf), f.payload)]
+			for i := range payload {
+				payload[i] = 0
+			}
+			stream.XORKeyStream(payload, payload)
+			if f.format.stuffed {
+				stuffMarkers(payload)
+			}
+			f.payload -= len(payload)
+			buf = buf[len(payload):]
+			continue
+		}
+		record := f.format.records[generatorRand.Intn(len(f.format.records))]
+		f.scratch = renderTemplate(f.scratch[:0], record, stream)
+		f.pending = f.scratch
+		f.payload = generatorRand.Intn(f.format.payload + 1)
+	}
+}
+
+// stuffMarkers makes every 0xff in data a stuffed 0xff 0x00 pair, as in
+// JPEG scan data. A 0xff at the very end becomes 0xfe, since the byte
+// after it is not in data.
+func stuffMarkers(data []byte) {
+	for i := 0; i < len(data); i++ {
+		if data[i] != 0xff {
+			continue
+		}
+		if i+1 < len(data) {
+			data[i+1] = 0
+			i++
+		} else {
+			data[i] = 0xfe
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestFakeFileContinuation tests that structured files continue their format past the first 4K
+func TestFakeFileContinuation(t *testing.T) {
+	stream := newPaddingStream()
+	formats := append(append([]structuredFormat{}, structuredFormats...), textFormats...)
+	for i := range formats {
+		format := &formats[i]
+		file := fakeFile{formats: formats, format: format, remaining: structuredMinFile}
+		file.scratch = renderTemplate(nil, format.header, stream)
+		file.pending = file.scratch
+
+		buf := make([]byte, structuredMinFile)
+		file.fill(buf, stream)
+		if file.remaining != 0 {
+			t.Errorf("Format %d: expected file to end with the buffer, %d bytes remain", i, file.remaining)
+		}
+
+		// The fixed start of some record keeps appearing after the first 4K
+		found := false
+		for _, record := range format.records {
+			prefix := record
+			if end := strings.IndexByte(record, '%'); end >= 0 {
+				prefix = record[:end]
+			}
+			if bytes.Contains(buf[bufferSize:], renderTemplate(nil, prefix, stream)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Format %d: no records after the first 4K", i)
+		}
+	}
+}
+
+// TestFakeFileSize tests that fake files span whole 4K slots
+func TestFakeFileSize(t *testing.T) {
+	stream := newPaddingStream()
+	file := fakeFile{formats: structuredFormats, minSize: structuredMinFile, maxSize: structuredMaxFile}
+	for i := 0; i < 100; i++ {
+		file.start(stream)
+		if file.remaining%bufferSize != 0 || file.remaining < structuredMinFile || file.remaining > structuredMaxFile {
+			t.Fatalf("Fake file of %d bytes is not a multiple of 4K between %d and %d", file.remaining, structuredMinFile, structuredMaxFile)
+		}
+	}
+}
+
+// TestLowEntropyText tests that -entropy low generates printable, compressible text
+func TestLowEntropyText(t *testing.T) {
+	stream := newPaddingStream()
+	file := fakeFile{formats: textFormats, minSize: bufferSize, maxSize: bufferSize}
+	buf := make([]byte, 256*bufferSize)
+	file.fill(buf, stream)
+
+	for i, b := range buf {
+		if (b < ' ' || b > '~') && b != '\n' && b != '\t' {
+			t.Fatalf("Unprintable byte 0x%02x at offset %d", b, i)
+		}
+	}
+	// Random data is close to 8 bits per byte, English text around 4.5
+	if entropy := calculateEntropy(buf); entropy > 6.0 {
+		t.Errorf("Text entropy too high: %.2f", entropy)
+	}
+}
+
+// TestStuffMarkers tests that stuffed data holds no JPEG markers
+func TestStuffMarkers(t *testing.T) {
+	data := []byte{0x01, 0xff, 0xd9, 0xff, 0xff, 0x02, 0xff}
+	stuffMarkers(data)
+	for i := 0; i+1 < len(data); i++ {
+		if data[i] == 0xff && data[i+1] != 0 {
+			t.Errorf("Unstuffed marker at %d: % x", i, data)
+		}
+	}
+	if data[len(data)-1] == 0xff {
+		t.Error("Trailing 0xff should be escaped")
+	}
+}
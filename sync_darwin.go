@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// syncFile flushes file to stable storage. A plain fsync on macOS only
+// hands the data to the drive, so F_FULLFSYNC is used to also flush the
+// drive's write cache. Filesystems that don't support it fall back to fsync.
+func syncFile(file *os.File) error {
+	if *fullSync {
+		_, _, errno := syscall.Syscall(syscall.SYS_FCNTL, file.Fd(), syscall.F_FULLFSYNC, 0)
+		if errno == 0 {
+			return nil
+		}
+	}
+	return file.Sync()
+}
@@ -0,0 +1,10 @@
+//go:build !darwin
+
+package main
+
+import "os"
+
+// syncFile flushes file to stable storage.
+func syncFile(file *os.File) error {
+	return file.Sync()
+}
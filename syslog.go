@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// useSyslog is set by -syslog
+var useSyslog bool
+
+// syslogField is a structured field of a syslog record, named in upper
+// case like the journal's own fields
+type syslogField struct {
+	name  string
+	value string
+}
+
+// syslogWriter sends records to the system log: journald where it runs,
+// syslog otherwise
+type syslogWriter interface {
+	write(severity int, message string, fields []syslogField) error
+}
+
+// systemLog is the -syslog writer, nil without -syslog
+var systemLog syslogWriter
+
+// logResult sends what happened to a target to the system log, with the
+// path, size and result as fields so log pipelines can pick them up. size
+// is negative if unknown.
+func logResult(path string, size int64, result string, err error) {
+	if systemLog == nil {
+		return
+	}
+	fields := []syslogField{{"PATH", path}, {"RESULT", result}}
+	if size >= 0 {
+		fields = append(fields, syslogField{"BYTES", strconv.FormatInt(size, 10)})
+	}
+
+	severity, message := logInfo, fmt.Sprintf("wiped '%s'", path)
+	if result != "wiped" {
+		severity, message = logError, fmt.Sprintf("could not wipe '%s' (%s)", path, result)
+		if err != nil {
+			message += ": " + getSimpleError(err)
+			fields = append(fields, syslogField{"ERROR", getSimpleError(err)})
+		}
+	}
+	systemLog.write(severity, message, fields)
+}
+
+// journalPriorities are the syslog priorities of the severities
+var journalPriorities = [...]string{logInfo: "6", logWarning: "4", logError: "3"}
+
+// encodeJournalRecord encodes a record in the journal's native protocol:
+// a FIELD=value line per field, or the field name, a 64-bit little-endian
+// length and the raw value for values spanning lines. Extra fields get a
+// WIPEFILE_ prefix.
+func encodeJournalRecord(severity int, message string, fields []syslogField) []byte {
+	var record []byte
+	add := func(name, value string) {
+		if !strings.Contains(value, "\n") {
+			record = append(record, name+"="+value+"\n"...)
+			return
+		}
+		record = append(record, name+"\n"...)
+		record = binary.LittleEndian.AppendUint64(record, uint64(len(value)))
+		record = append(record, value+"\n"...)
+	}
+	add("MESSAGE", message)
+	add("PRIORITY", journalPriorities[severity])
+	add("SYSLOG_IDENTIFIER", "wipefile")
+	for _, field := range fields {
+		add("WIPEFILE_"+field.name, field.value)
+	}
+	return record
+}
+
+// formatSyslogFields appends fields to a plain syslog message as
+// name=value pairs, quoting values with spaces
+func formatSyslogFields(message string, fields []syslogField) string {
+	for _, field := range fields {
+		value := field.value
+		if value == "" || strings.ContainsAny(value, " \"=") {
+			value = strconv.Quote(value)
+		}
+		message += " " + strings.ToLower(field.name) + "=" + value
+	}
+	return message
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+)
+
+// recordingSyslog keeps the records sent to it
+type recordingSyslog struct {
+	severities []int
+	messages   []string
+	fields     [][]syslogField
+}
+
+func (r *recordingSyslog) write(severity int, message string, fields []syslogField) error {
+	r.severities = append(r.severities, severity)
+	r.messages = append(r.messages, message)
+	r.fields = append(r.fields, fields)
+	return nil
+}
+
+// TestLogResult tests that target results reach syslog with their fields
+func TestLogResult(t *testing.T) {
+	recorder := &recordingSyslog{}
+	systemLog = recorder
+	defer func() { systemLog = nil }()
+
+	logResult("/tmp/a b.txt", 4096, "wiped", nil)
+	logResult("/tmp/c.txt", -1, "denied", os.ErrPermission)
+
+	if len(recorder.messages) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(recorder.messages))
+	}
+	if recorder.severities[0] != logInfo || recorder.messages[0] != "wiped '/tmp/a b.txt'" {
+		t.Errorf("Unexpected record for a wiped file: %d %q", recorder.severities[0], recorder.messages[0])
+	}
+	if got := formatSyslogFields("", recorder.fields[0]); got != ` path="/tmp/a b.txt" result=wiped bytes=4096` {
+		t.Errorf("Unexpected fields for a wiped file: %q", got)
+	}
+	if recorder.severities[1] != logError || recorder.messages[1] != "could not wipe '/tmp/c.txt' (denied): Permission denied" {
+		t.Errorf("Unexpected record for a failure: %d %q", recorder.severities[1], recorder.messages[1])
+	}
+	if got := formatSyslogFields("", recorder.fields[1]); got != ` path=/tmp/c.txt result=denied error="Permission denied"` {
+		t.Errorf("Unexpected fields for a failure: %q", got)
+	}
+}
+
+// TestEncodeJournalRecord tests the journal's native protocol encoding
+func TestEncodeJournalRecord(t *testing.T) {
+	record := encodeJournalRecord(logWarning, "two\nlines", []syslogField{{"PATH", "/tmp/x"}})
+
+	length := make([]byte, 8)
+	binary.LittleEndian.PutUint64(length, 9)
+	expected := "MESSAGE\n" + string(length) + "two\nlines\n" +
+		"PRIORITY=4\nSYSLOG_IDENTIFIER=wipefile\nWIPEFILE_PATH=/tmp/x\n"
+	if !bytes.Equal(record, []byte(expected)) {
+		t.Errorf("Expected %q, got %q", expected, record)
+	}
+}
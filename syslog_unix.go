@@ -0,0 +1,55 @@
+//go:build unix
+
+package main
+
+import (
+	"log/syslog"
+	"net"
+	"os"
+)
+
+// journalSocket is where journald takes records in its native protocol
+const journalSocket = "/run/systemd/journal/socket"
+
+// journalWriter sends records to journald, which keeps the fields
+// searchable (journalctl WIPEFILE_RESULT=failed)
+type journalWriter struct {
+	conn *net.UnixConn
+}
+
+func (w *journalWriter) write(severity int, message string, fields []syslogField) error {
+	_, err := w.conn.Write(encodeJournalRecord(severity, message, fields))
+	return err
+}
+
+// plainSyslogWriter sends records to a classic syslog daemon, with the
+// fields appended to the message
+type plainSyslogWriter struct {
+	writer *syslog.Writer
+}
+
+func (w *plainSyslogWriter) write(severity int, message string, fields []syslogField) error {
+	message = formatSyslogFields(message, fields)
+	switch severity {
+	case logError:
+		return w.writer.Err(message)
+	case logWarning:
+		return w.writer.Warning(message)
+	}
+	return w.writer.Info(message)
+}
+
+// openSyslog connects to journald if it runs, and to syslog otherwise
+func openSyslog() (syslogWriter, error) {
+	if _, err := os.Stat(journalSocket); err == nil {
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+		if err == nil {
+			return &journalWriter{conn}, nil
+		}
+	}
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, "wipefile")
+	if err != nil {
+		return nil, err
+	}
+	return &plainSyslogWriter{writer}, nil
+}
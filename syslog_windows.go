@@ -0,0 +1,8 @@
+package main
+
+import "errors"
+
+// openSyslog fails, since Windows has the event log instead of syslog
+func openSyslog() (syslogWriter, error) {
+	return nil, errors.New("syslog is not available on Windows")
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRateLimiter tests that the limiter paces writes to the configured rate
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(1024 * 1024) // 1 MB/s
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		limiter.wait(64 * 1024)
+	}
+	elapsed := time.Since(start)
+
+	// The first write goes out immediately, the next four wait 62.5ms each
+	if elapsed < 200*time.Millisecond {
+		t.Errorf("5 x 64K at 1 MB/s should take at least 200ms, took %s", elapsed)
+	}
+
+	var unlimited *rateLimiter
+	start = time.Now()
+	unlimited.wait(1024 * 1024 * 1024)
+	if time.Since(start) > 10*time.Millisecond {
+		t.Error("A nil limiter should not wait")
+	}
+}
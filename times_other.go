@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+import "time"
+
+// setBirthTime is a no-op outside Windows. Linux has no interface for
+// setting btime, and on macOS utimes already moves the birth time back
+// when the new modification time is older than it.
+func setBirthTime(path string, t time.Time) error {
+	return nil
+}
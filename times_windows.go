@@ -0,0 +1,25 @@
+package main
+
+import (
+	"syscall"
+	"time"
+)
+
+// setBirthTime sets the NTFS creation time of path.
+func setBirthTime(path string, t time.Time) error {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	// FILE_FLAG_BACKUP_SEMANTICS is needed to open directories
+	handle, err := syscall.CreateFile(pathPtr, syscall.FILE_WRITE_ATTRIBUTES,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(handle)
+
+	ft := syscall.NsecToFiletime(t.UnixNano())
+	return syscall.SetFileTime(handle, &ft, nil, nil)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// trashEntries returns the paths of the entries of the trash directory
+// dir, except those named in skip. A trash that was never used doesn't
+// exist, which is no error; one that can't be read is counted as failed.
+// A symlinked trash is skipped, since reading it would list the entries of
+// wherever it points.
+func trashEntries(dir string, skip ...string) []string {
+	if info, err := os.Lstat(dir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		printWarning("skipping trash '%s': it is a symlink\n", dir)
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		printError("cannot read trash '%s': %s\n", dir, getSimpleError(err))
+		countFailure(dir, err)
+		return nil
+	}
+	var paths []string
+	for _, entry := range entries {
+		skipped := false
+		for _, name := range skip {
+			skipped = skipped || entry.Name() == name
+		}
+		if !skipped {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return paths
+}
+
+// runTrash implements "wipefile trash", which wipes what is in the trash
+// of the current user, along with the records of where it came from, so
+// the trashed copy of a file doesn't outlive the wiped original.
+func runTrash() {
+	targets := trashTargets()
+	if len(targets) > 0 {
+		printStatus("wiping %d items in the trash\n", len(targets))
+		*recursive = true
+		wipeTargets(targets)
+	}
+	reportFailures()
+	if len(targets) == 0 {
+		printSummary("the trash is empty\n")
+		return
+	}
+	printSummary("wiped %d items in the trash\n", len(targets))
+}
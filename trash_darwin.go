@@ -0,0 +1,18 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// trashTargets returns what is in the trash of the current user,
+// ~/.Trash. Finder keeps the original locations in the .DS_Store there,
+// which is wiped with the rest. Reading it takes Full Disk Access for the
+// terminal on macOS 10.15 and later.
+func trashTargets() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return trashEntries(filepath.Join(home, ".Trash"))
+}
@@ -0,0 +1,111 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// trashTargets returns what is in the XDG trash of the current user: the
+// home trash in $XDG_DATA_HOME/Trash (~/.local/share/Trash), and the
+// .Trash/UID and .Trash-UID directories at the top of mounted filesystems.
+// Besides the trashed files, each trash holds an info record with the
+// original path of every file, and a cache of directory sizes.
+func trashTargets() []string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	trashes := []string{filepath.Join(dataHome, "Trash")}
+	for _, top := range mountPoints() {
+		trashes = append(trashes, mountTrashes(top)...)
+	}
+
+	var targets []string
+	for _, trash := range trashes {
+		if !ownTrash(trash) {
+			continue
+		}
+		for _, dir := range []string{"files", "info", "expunged"} {
+			targets = append(targets, trashEntries(filepath.Join(trash, dir))...)
+		}
+		sizes := filepath.Join(trash, "directorysizes")
+		if _, err := os.Lstat(sizes); err == nil {
+			targets = append(targets, sizes)
+		}
+	}
+	return targets
+}
+
+// mountTrashes returns the trashes of the current user at the top of the
+// mounted filesystem top. Others can write there, so as the XDG trash spec
+// asks, the shared .Trash is only used when it is a sticky directory, not a
+// symlink, where no one can replace the directory of another user.
+func mountTrashes(top string) []string {
+	uid := strconv.Itoa(os.Getuid())
+	trashes := []string{filepath.Join(top, ".Trash-"+uid)}
+	shared := filepath.Join(top, ".Trash")
+	info, err := os.Lstat(shared)
+	if err != nil {
+		return trashes
+	}
+	if !info.IsDir() || info.Mode()&os.ModeSticky == 0 {
+		printWarning("skipping trash '%s': not a sticky directory\n", shared)
+		return trashes
+	}
+	return append([]string{filepath.Join(shared, uid)}, trashes...)
+}
+
+// ownTrash reports whether trash and its files, info and expunged
+// directories are real directories of the current user. A symlink there
+// would have the wipe follow it out of the trash, to wherever someone
+// pointed it.
+func ownTrash(trash string) bool {
+	for _, dir := range []string{trash, filepath.Join(trash, "files"), filepath.Join(trash, "info"), filepath.Join(trash, "expunged")} {
+		info, err := os.Lstat(dir)
+		if errors.Is(err, fs.ErrNotExist) && dir != trash {
+			continue
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			return false
+		}
+		if err != nil || !info.IsDir() || !ownedByMe(info) {
+			printWarning("skipping trash '%s': '%s' is not a directory of yours\n", trash, dir)
+			return false
+		}
+	}
+	return true
+}
+
+// mountPoints returns where filesystems are mounted, as listed in
+// /proc/self/mounts, or none where that isn't available
+func mountPoints() []string {
+	data, err := os.ReadFile("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+	var mounts []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		// Spaces and tabs in the path are octal escapes
+		mount := strings.NewReplacer(`\040`, " ", `\011`, "\t", `\134`, `\`).Replace(fields[1])
+		if !seen[mount] {
+			seen[mount] = true
+			mounts = append(mounts, mount)
+		}
+	}
+	return mounts
+}
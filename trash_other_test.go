@@ -0,0 +1,49 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// TestUnsafeTrashes tests that trashes with planted symlinks, or a shared
+// .Trash that isn't sticky, are skipped
+func TestUnsafeTrashes(t *testing.T) {
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	documents := filepath.Join(t.TempDir(), "Documents")
+	os.MkdirAll(documents, 0700)
+	os.WriteFile(filepath.Join(documents, "thesis.odt"), []byte("data"), 0600)
+	os.MkdirAll(filepath.Join(dataHome, "Trash", "info"), 0700)
+	if err := os.Symlink(documents, filepath.Join(dataHome, "Trash", "files")); err != nil {
+		t.Skip(err)
+	}
+	for _, target := range trashTargets() {
+		if filepath.Dir(target) == documents || filepath.Dir(filepath.Dir(target)) == filepath.Join(dataHome, "Trash") {
+			t.Errorf("trashTargets took '%s' from a trash with a symlinked files", target)
+		}
+	}
+
+	top := t.TempDir()
+	uid := strconv.Itoa(os.Getuid())
+	own := filepath.Join(top, ".Trash-"+uid)
+	shared := filepath.Join(top, ".Trash")
+	os.Mkdir(shared, 0777)
+	if trashes := mountTrashes(top); !reflect.DeepEqual(trashes, []string{own}) {
+		t.Errorf("mountTrashes without the sticky bit = %q, want %q", trashes, own)
+	}
+	os.Chmod(shared, 0777|os.ModeSticky)
+	want := []string{filepath.Join(shared, uid), own}
+	if trashes := mountTrashes(top); !reflect.DeepEqual(trashes, want) {
+		t.Errorf("mountTrashes = %q, want %q", trashes, want)
+	}
+	os.Remove(shared)
+	os.Symlink(documents, shared)
+	if trashes := mountTrashes(top); !reflect.DeepEqual(trashes, []string{own}) {
+		t.Errorf("mountTrashes with a symlinked .Trash = %q, want %q", trashes, own)
+	}
+}
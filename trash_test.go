@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"testing"
+)
+
+// TestTrashEntries tests that trashEntries lists a trash without the skipped names, and that a missing trash is no failure
+func TestTrashEntries(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"$RAB12CD.txt", "$IAB12CD.txt", "desktop.ini"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	entries := trashEntries(dir, "desktop.ini")
+	want := []string{filepath.Join(dir, "$IAB12CD.txt"), filepath.Join(dir, "$RAB12CD.txt")}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("trashEntries = %q, want %q", entries, want)
+	}
+
+	failed := wipeResults.failed.Load()
+	if entries := trashEntries(filepath.Join(dir, "missing")); entries != nil || wipeResults.failed.Load() != failed {
+		t.Errorf("trashEntries of a missing trash = %q, or counted as failed", entries)
+	}
+
+	link := filepath.Join(t.TempDir(), "files")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Skip(err)
+	}
+	if entries := trashEntries(link); entries != nil {
+		t.Errorf("trashEntries of a symlinked trash = %q, want none", entries)
+	}
+}
+
+// TestXDGTrashTargets tests that trashTargets returns the files, info records and directory sizes of the XDG home trash
+func TestXDGTrashTargets(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("XDG trash only")
+	}
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	trash := filepath.Join(dataHome, "Trash")
+	for _, name := range []string{"files/report.pdf", "files/project/notes.txt", "info/report.pdf.trashinfo", "info/project.trashinfo", "directorysizes"} {
+		path := filepath.Join(trash, name)
+		os.MkdirAll(filepath.Dir(path), 0700)
+		os.WriteFile(path, []byte("data"), 0600)
+	}
+
+	var targets []string
+	for _, target := range trashTargets() {
+		if filepath.Dir(filepath.Dir(target)) == trash || filepath.Dir(target) == trash {
+			targets = append(targets, target)
+		}
+	}
+	sort.Strings(targets)
+	want := []string{
+		filepath.Join(trash, "directorysizes"),
+		filepath.Join(trash, "files", "project"),
+		filepath.Join(trash, "files", "report.pdf"),
+		filepath.Join(trash, "info", "project.trashinfo"),
+		filepath.Join(trash, "info", "report.pdf.trashinfo"),
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("trashTargets = %q, want %q", targets, want)
+	}
+}
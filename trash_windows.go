@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"os/user"
+)
+
+// trashTargets returns what is in the Recycle Bin of the current user on
+// every drive: $Recycle.Bin\SID holds each deleted file as $R..., and the
+// record of its original path and deletion time as $I....
+func trashTargets() []string {
+	current, err := user.Current()
+	if err != nil {
+		return nil
+	}
+	var targets []string
+	for drive := 'A'; drive <= 'Z'; drive++ {
+		bin := string(drive) + `:\$Recycle.Bin\` + current.Uid
+		if _, err := os.Stat(bin); err == nil {
+			targets = append(targets, trashEntries(bin, "desktop.ini")...)
+		}
+	}
+	return targets
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const fiTrim = 0xC0185879 // _IOWR('X', 121, struct fstrim_range)
+
+type fstrimRange struct {
+	Start  uint64
+	Length uint64
+	MinLen uint64
+}
+
+// trimFilesystem asks the filesystem mounted at mountPoint to discard all
+// unused blocks, like fstrim. It returns the number of bytes trimmed.
+func trimFilesystem(mountPoint string) (uint64, error) {
+	dir, err := os.Open(mountPoint)
+	if err != nil {
+		return 0, err
+	}
+	defer dir.Close()
+
+	trimRange := fstrimRange{Length: ^uint64(0)}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dir.Fd(), fiTrim, uintptr(unsafe.Pointer(&trimRange))); errno != 0 {
+		return 0, errno
+	}
+	// The kernel reports the trimmed byte count back in Length
+	return trimRange.Length, nil
+}
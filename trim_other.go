@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// macOS and Windows trim freed blocks on their own.
+func trimFilesystem(mountPoint string) (uint64, error) {
+	return 0, errors.New("only supported on Linux")
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	sysIoUringSetup = 425
+	sysIoUringEnter = 426
+
+	ioringOpWrite        = 23
+	ioringEnterGetEvents = 1
+	ioringFeatSingleMmap = 1
+
+	ioringOffSqRing = 0
+	ioringOffCqRing = 0x8000000
+	ioringOffSqes   = 0x10000000
+)
+
+type ioSqringOffsets struct {
+	Head, Tail, RingMask, RingEntries, Flags, Dropped, Array, Resv1 uint32
+	UserAddr                                                        uint64
+}
+
+type ioCqringOffsets struct {
+	Head, Tail, RingMask, RingEntries, Overflow, Cqes, Flags, Resv1 uint32
+	UserAddr                                                        uint64
+}
+
+type ioUringParams struct {
+	SqEntries, CqEntries, Flags, SqThreadCPU, SqThreadIdle, Features, WqFd uint32
+	Resv                                                                   [3]uint32
+	SqOff                                                                  ioSqringOffsets
+	CqOff                                                                  ioCqringOffsets
+}
+
+type ioUringSqe struct {
+	Opcode      uint8
+	Flags       uint8
+	Ioprio      uint16
+	Fd          int32
+	Off         uint64
+	Addr        uint64
+	Len         uint32
+	RwFlags     uint32
+	UserData    uint64
+	BufIndex    uint16
+	Personality uint16
+	SpliceFdIn  int32
+	Addr3       uint64
+	Pad2        uint64
+}
+
+type ioUringCqe struct {
+	UserData uint64
+	Res      int32
+	Flags    uint32
+}
+
+// uring is a minimal io_uring instance used only for queued writes
+type uring struct {
+	fd     int
+	params ioUringParams
+	sqRing []byte
+	cqRing []byte
+	sqes   []byte
+
+	queued uint32 // SQEs queued but not yet submitted
+}
+
+func newUring(entries uint32) (*uring, error) {
+	r := &uring{}
+	fd, _, errno := syscall.Syscall(sysIoUringSetup, uintptr(entries), uintptr(unsafe.Pointer(&r.params)), 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	r.fd = int(fd)
+
+	sqSize := int(r.params.SqOff.Array + r.params.SqEntries*4)
+	cqSize := int(r.params.CqOff.Cqes + r.params.CqEntries*uint32(unsafe.Sizeof(ioUringCqe{})))
+	if r.params.Features&ioringFeatSingleMmap != 0 && cqSize > sqSize {
+		sqSize = cqSize
+	}
+
+	var err error
+	r.sqRing, err = syscall.Mmap(r.fd, ioringOffSqRing, sqSize, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	if err != nil {
+		r.close()
+		return nil, err
+	}
+	if r.params.Features&ioringFeatSingleMmap != 0 {
+		r.cqRing = r.sqRing
+	} else {
+		r.cqRing, err = syscall.Mmap(r.fd, ioringOffCqRing, cqSize, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+		if err != nil {
+			r.close()
+			return nil, err
+		}
+	}
+	r.sqes, err = syscall.Mmap(r.fd, ioringOffSqes, int(r.params.SqEntries)*int(unsafe.Sizeof(ioUringSqe{})), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	if err != nil {
+		r.close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *uring) close() {
+	if r.sqes != nil {
+		syscall.Munmap(r.sqes)
+	}
+	if r.cqRing != nil && &r.cqRing[0] != &r.sqRing[0] {
+		syscall.Munmap(r.cqRing)
+	}
+	if r.sqRing != nil {
+		syscall.Munmap(r.sqRing)
+	}
+	syscall.Close(r.fd)
+}
+
+func ringUint32(ring []byte, offset uint32) *uint32 {
+	return (*uint32)(unsafe.Pointer(&ring[offset]))
+}
+
+// queueWrite adds a write SQE; the caller makes sure the ring has room
+func (r *uring) queueWrite(fd int, buf []byte, offset int64, userData uint64) {
+	tail := atomic.LoadUint32(ringUint32(r.sqRing, r.params.SqOff.Tail))
+	index := tail & *ringUint32(r.sqRing, r.params.SqOff.RingMask)
+
+	sqe := (*ioUringSqe)(unsafe.Pointer(&r.sqes[uintptr(index)*unsafe.Sizeof(ioUringSqe{})]))
+	*sqe = ioUringSqe{
+		Opcode:   ioringOpWrite,
+		Fd:       int32(fd),
+		Off:      uint64(offset),
+		Addr:     uint64(uintptr(unsafe.Pointer(&buf[0]))),
+		Len:      uint32(len(buf)),
+		UserData: userData,
+	}
+	*ringUint32(r.sqRing, r.params.SqOff.Array+index*4) = index
+	atomic.StoreUint32(ringUint32(r.sqRing, r.params.SqOff.Tail), tail+1)
+	r.queued++
+}
+
+// submitAndWait submits all queued SQEs and waits for at least minComplete
+// completions
+func (r *uring) submitAndWait(minComplete uint32) error {
+	for {
+		submitted, _, errno := syscall.Syscall6(sysIoUringEnter, uintptr(r.fd), uintptr(r.queued), uintptr(minComplete), ioringEnterGetEvents, 0, 0)
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return errno
+		}
+		r.queued -= uint32(submitted)
+		return nil
+	}
+}
+
+// reap calls fn for every available completion
+func (r *uring) reap(fn func(cqe ioUringCqe)) {
+	headPtr := ringUint32(r.cqRing, r.params.CqOff.Head)
+	head := atomic.LoadUint32(headPtr)
+	tail := atomic.LoadUint32(ringUint32(r.cqRing, r.params.CqOff.Tail))
+	mask := *ringUint32(r.cqRing, r.params.CqOff.RingMask)
+
+	for ; head != tail; head++ {
+		offset := uintptr(r.params.CqOff.Cqes) + uintptr(head&mask)*unsafe.Sizeof(ioUringCqe{})
+		fn(*(*ioUringCqe)(unsafe.Pointer(&r.cqRing[offset])))
+	}
+	atomic.StoreUint32(headPtr, head)
+}
+
+// uringOverwrite overwrites file from start up to size with fake-header
+// data, keeping uringQueueDepth writes of uringWriteSize in flight at once.
+// progress is called with the offset up to which all writes completed.
+func uringOverwrite(file *os.File, start, size int64, progress func(done int64)) error {
+	r, err := newUring(uringQueueDepth)
+	if err != nil {
+		return err
+	}
+	defer r.close()
+
+	type pendingWrite struct {
+		buf    []byte
+		offset int64
+	}
+
+	fd := int(file.Fd())
+	buffers := make([][]byte, uringQueueDepth)
+	for i := range buffers {
+		buffers[i] = alignedBuffer(uringWriteSize) // Aligned in case of -direct
+	}
+	pending := make([]pendingWrite, uringQueueDepth)
+	var free []int
+	for i := range buffers {
+		free = append(free, i)
+	}
+
+	fakeData := newFakeReader()
+	defer fakeData.Close()
+	offset := start
+	inFlight := 0
+	var writeErr error
+	for (offset < size && writeErr == nil) || inFlight > 0 {
+		for writeErr == nil && offset < size && len(free) > 0 {
+			index := free[len(free)-1]
+			free = free[:len(free)-1]
+
+			length := int64(uringWriteSize)
+			if remaining := size - offset; remaining < length {
+				length = remaining
+			}
+			buf := buffers[index][:length]
+			fakeData.Read(buf)
+			writeLimiter.wait(len(buf))
+
+			pending[index] = pendingWrite{buf: buf, offset: offset}
+			r.queueWrite(fd, buf, offset, uint64(index))
+			offset += length
+			inFlight++
+		}
+
+		if err := r.submitAndWait(1); err != nil {
+			return err
+		}
+
+		r.reap(func(cqe ioUringCqe) {
+			index := int(cqe.UserData)
+			write := pending[index]
+			if cqe.Res > 0 {
+				metrics.bytesOverwritten.Add(int64(cqe.Res))
+			}
+			if cqe.Res > 0 && verbosity >= verboseWrites {
+				printVerbose(verboseWrites, "wrote %d bytes to '%s' at offset %d\n", cqe.Res, file.Name(), write.offset)
+			}
+			switch {
+			case cqe.Res < 0:
+				if writeErr == nil {
+					writeErr = syscall.Errno(-cqe.Res)
+				}
+			case cqe.Res == 0:
+				if writeErr == nil {
+					writeErr = io.ErrShortWrite
+				}
+			case int(cqe.Res) < len(write.buf):
+				// Short write, queue the rest again
+				pending[index] = pendingWrite{buf: write.buf[cqe.Res:], offset: write.offset + int64(cqe.Res)}
+				r.queueWrite(fd, pending[index].buf, pending[index].offset, uint64(index))
+				return
+			}
+			inFlight--
+			pending[index].buf = nil
+			free = append(free, index)
+		})
+
+		// Completions arrive out of order; everything below the lowest
+		// write still in flight is done
+		done := offset
+		for _, write := range pending {
+			if write.buf != nil && write.offset < done {
+				done = write.offset
+			}
+		}
+		if writeErr == nil {
+			progress(done)
+		}
+	}
+
+	if writeErr != nil {
+		return fmt.Errorf("io_uring write: %w", writeErr)
+	}
+	return nil
+}
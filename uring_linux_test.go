@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestUringOverwrite tests that the io_uring writer overwrites the whole file
+func TestUringOverwrite(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.bin")
+
+	size := 3*uringWriteSize + 1000
+	original := bytes.Repeat([]byte{0xAA}, size)
+	if err := os.WriteFile(testFile, original, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	file, err := os.OpenFile(testFile, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	err = uringOverwrite(file, 0, int64(size), func(int64) {})
+	file.Close()
+	if err != nil {
+		t.Skipf("io_uring not available: %v", err)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	if len(content) < size {
+		t.Fatalf("File should be at least %d bytes, got %d", size, len(content))
+	}
+	if bytes.Contains(content, bytes.Repeat([]byte{0xAA}, 64)) {
+		t.Error("Original content should be overwritten")
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+func uringOverwrite(file *os.File, start, size int64, progress func(done int64)) error {
+	return errors.New("io_uring is only available on Linux")
+}
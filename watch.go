@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// watchPollInterval is how often the watched directory is scanned while
+// something in it is settling, or always without change notifications
+const watchPollInterval = time.Second
+
+// Options of wipefile watch
+var (
+	settleDelay  time.Duration
+	wipeExisting bool
+)
+
+// registerWatchFlags adds the options of wipefile watch to flags
+func registerWatchFlags(flags *flag.FlagSet) {
+	flags.DurationVar(&settleDelay, "settle", 5*time.Second, "With wipefile watch, wipe what is dropped in once it hasn't changed for this long")
+	flags.BoolVar(&wipeExisting, "wipe-existing", false, "With wipefile watch, also wipe what is in the directory when watching starts")
+}
+
+// droppedEntry is an entry of the watched directory waiting to settle
+type droppedEntry struct {
+	signature string
+	since     time.Time
+}
+
+// runWatch wipes whatever is placed in dir, once it has settled, until
+// SIGINT or SIGTERM
+func runWatch(dir string) {
+	info, err := os.Stat(dir)
+	if err == nil && !info.IsDir() {
+		err = fmt.Errorf("not a directory")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot watch '%s': %s\n", dir, getSimpleError(err))
+		os.Exit(1)
+	}
+	// Nil where the platform can't notify, which makes every tick a scan
+	changes, err := watchChanges(dir)
+	if err != nil {
+		printWarning("cannot get change notifications for '%s', polling instead: %s\n", dir, getSimpleError(err))
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	*recursive = true
+	pending := make(map[string]droppedEntry)
+	ignored := make(map[string]bool)
+	if !wipeExisting {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			ignored[filepath.Join(dir, entry.Name())] = true
+		}
+		if len(entries) > 0 {
+			printStatus("leaving what is already in '%s' alone, %d entries (-wipe-existing wipes them)\n", dir, len(entries))
+		}
+	}
+	printStatus("watching '%s': anything placed in it is wiped once unchanged for %s\n", dir, settleDelay)
+
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+	scan := true
+	for {
+		if scan {
+			scanDropDir(dir, pending, ignored, time.Now())
+			for _, path := range settledEntries(pending, time.Now()) {
+				delete(pending, path)
+				printVerbose(verboseActions, "wiping dropped '%s'\n", path)
+				wipeTargets([]string{path})
+			}
+		}
+		select {
+		case <-changes:
+			scan = true
+		case <-ticker.C:
+			scan = changes == nil || len(pending) > 0
+		case <-signals:
+			printStatus("stopped watching '%s'\n", dir)
+			return
+		}
+	}
+}
+
+// scanDropDir updates pending with the entries of dir, restarting the
+// settle delay of those that changed, and forgets entries that are gone
+func scanDropDir(dir string, pending map[string]droppedEntry, ignored map[string]bool, now time.Time) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		printError("cannot read directory '%s': %s\n", dir, getSimpleError(err))
+		return
+	}
+	present := make(map[string]bool)
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		present[path] = true
+		if ignored[path] {
+			continue
+		}
+		signature := dropSignature(path)
+		if previous, ok := pending[path]; !ok || previous.signature != signature {
+			pending[path] = droppedEntry{signature: signature, since: now}
+		}
+	}
+	for path := range pending {
+		if !present[path] {
+			delete(pending, path)
+		}
+	}
+	for path := range ignored {
+		if !present[path] {
+			delete(ignored, path)
+		}
+	}
+}
+
+// settledEntries returns the pending entries unchanged for the settle delay
+func settledEntries(pending map[string]droppedEntry, now time.Time) []string {
+	var settled []string
+	for path, entry := range pending {
+		if now.Sub(entry.since) >= settleDelay {
+			settled = append(settled, path)
+		}
+	}
+	return settled
+}
+
+// dropSignature sums up the size and modification times of path and, for
+// a directory, everything below it, so a copy still in progress shows as
+// a change
+func dropSignature(path string) string {
+	var count, size, newest int64
+	filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			count++
+			size += info.Size()
+			if mtime := info.ModTime().UnixNano(); mtime > newest {
+				newest = mtime
+			}
+		}
+		return nil
+	})
+	return fmt.Sprintf("%d/%d/%d", count, size, newest)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// watchChanges returns nil, leaving wipefile watch to poll, since change
+// notifications are only used on Linux
+func watchChanges(dir string) (<-chan struct{}, error) {
+	return nil, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestScanDropDir tests that dropped entries are wiped only once they stop
+// changing, and that ignored entries are left alone
+func TestScanDropDir(t *testing.T) {
+	settle := settleDelay
+	settleDelay = 5 * time.Second
+	defer func() { settleDelay = settle }()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "old.txt"), []byte("old"), 0600)
+	file := filepath.Join(dir, "new.txt")
+	os.WriteFile(file, []byte("new"), 0600)
+	pending := make(map[string]droppedEntry)
+	ignored := map[string]bool{filepath.Join(dir, "old.txt"): true}
+
+	start := time.Now()
+	scanDropDir(dir, pending, ignored, start)
+	if len(pending) != 1 {
+		t.Fatalf("Expected only the new file to be pending, got %v", pending)
+	}
+	if settled := settledEntries(pending, start.Add(4*time.Second)); len(settled) != 0 {
+		t.Errorf("Expected nothing settled before the delay, got %v", settled)
+	}
+
+	// Growing restarts the delay
+	os.WriteFile(file, []byte("new and longer"), 0600)
+	scanDropDir(dir, pending, ignored, start.Add(4*time.Second))
+	if settled := settledEntries(pending, start.Add(6*time.Second)); len(settled) != 0 {
+		t.Errorf("Expected a changed file not to be settled, got %v", settled)
+	}
+	scanDropDir(dir, pending, ignored, start.Add(9*time.Second))
+	if settled := settledEntries(pending, start.Add(9*time.Second)); len(settled) != 1 || settled[0] != file {
+		t.Errorf("Expected %s to be settled, got %v", file, settled)
+	}
+
+	os.Remove(file)
+	os.Remove(filepath.Join(dir, "old.txt"))
+	scanDropDir(dir, pending, ignored, start.Add(10*time.Second))
+	if len(pending) != 0 || len(ignored) != 0 {
+		t.Errorf("Expected removed entries to be forgotten, got %v and %v", pending, ignored)
+	}
+}
+
+// TestDropSignature tests that a file added to a dropped directory changes
+// its signature
+func TestDropSignature(t *testing.T) {
+	dir := t.TempDir()
+	before := dropSignature(dir)
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0600)
+	if after := dropSignature(dir); after == before {
+		t.Errorf("Expected the signature to change, stayed %s", before)
+	}
+}
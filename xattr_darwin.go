@@ -0,0 +1,85 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// xattrNoFollow keeps the xattr calls from following symlinks
+const xattrNoFollow = 0x0001
+
+func listXattrs(path string) ([]string, error) {
+	pathPtr, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	size, _, errno := syscall.Syscall6(syscall.SYS_LISTXATTR, uintptr(unsafe.Pointer(pathPtr)), 0, 0, xattrNoFollow, 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	buf := make([]byte, size)
+	size, _, errno = syscall.Syscall6(syscall.SYS_LISTXATTR, uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), xattrNoFollow, 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	return splitXattrNames(buf[:size]), nil
+}
+
+func getXattrSize(path, name string) (int, error) {
+	pathPtr, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	namePtr, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	size, _, errno := syscall.Syscall6(syscall.SYS_GETXATTR, uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(namePtr)), 0, 0, 0, xattrNoFollow)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(size), nil
+}
+
+func setXattr(path, name string, value []byte) error {
+	pathPtr, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	namePtr, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+	var valuePtr unsafe.Pointer
+	if len(value) > 0 {
+		valuePtr = unsafe.Pointer(&value[0])
+	}
+	_, _, errno := syscall.Syscall6(syscall.SYS_SETXATTR, uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(namePtr)), uintptr(valuePtr), uintptr(len(value)), 0, xattrNoFollow)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func removeXattr(path, name string) error {
+	pathPtr, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	namePtr, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_REMOVEXATTR, uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(namePtr)), xattrNoFollow)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import "syscall"
+
+func listXattrs(path string) ([]string, error) {
+	size, err := syscall.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(path, buf)
+	if err != nil {
+		return nil, err
+	}
+	return splitXattrNames(buf[:size]), nil
+}
+
+func getXattrSize(path, name string) (int, error) {
+	return syscall.Getxattr(path, name, nil)
+}
+
+func setXattr(path, name string, value []byte) error {
+	return syscall.Setxattr(path, name, value, 0)
+}
+
+func removeXattr(path, name string) error {
+	return syscall.Removexattr(path, name)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// posixACL encodes an access ACL that gives the user uid read access, in
+// the format of the system.posix_acl_access attribute
+func posixACL(uid uint32) []byte {
+	const aclUndefinedID = 0xffffffff
+	entries := []struct {
+		tag, perm uint16
+		id        uint32
+	}{
+		{0x01, 6, aclUndefinedID}, // ACL_USER_OBJ
+		{0x02, 4, uid},            // ACL_USER
+		{0x04, 4, aclUndefinedID}, // ACL_GROUP_OBJ
+		{0x10, 4, aclUndefinedID}, // ACL_MASK
+		{0x20, 0, aclUndefinedID}, // ACL_OTHER
+	}
+	data := binary.LittleEndian.AppendUint32(nil, 2) // POSIX_ACL_XATTR_VERSION
+	for _, entry := range entries {
+		data = binary.LittleEndian.AppendUint16(data, entry.tag)
+		data = binary.LittleEndian.AppendUint16(data, entry.perm)
+		data = binary.LittleEndian.AppendUint32(data, entry.id)
+	}
+	return data
+}
+
+// TestWipeFileXattrs tests that -xattrs and -xattrs-overwrite leave no
+// attribute or ACL on the inode, which a remaining hard link still shows
+func TestWipeFileXattrs(t *testing.T) {
+	oldXattrs, oldXattrsOver, oldLinked := *xattrs, *xattrsOver, *forceLinked
+	defer func() {
+		*xattrs, *xattrsOver, *forceLinked = oldXattrs, oldXattrsOver, oldLinked
+		wipeResults.wiped.Store(0)
+	}()
+	*forceLinked = true
+
+	for _, overwrite := range []bool{false, true} {
+		*xattrs, *xattrsOver = !overwrite, overwrite
+		dir := t.TempDir()
+		file, link := filepath.Join(dir, "secret.txt"), filepath.Join(dir, "link.txt")
+		if err := os.WriteFile(file, []byte("secret"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Link(file, link); err != nil {
+			t.Fatal(err)
+		}
+		if err := setXattr(file, "user.secret", []byte("where it came from")); err != nil {
+			t.Skipf("Cannot set user attributes here: %v", err)
+		}
+		if err := setXattr(file, "system.posix_acl_access", posixACL(12345)); err != nil {
+			t.Logf("Cannot set an ACL here, testing attributes only: %v", err)
+		}
+
+		wipeFile(file)
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Fatalf("Expected '%s' to be wiped", file)
+		}
+		if names, err := listXattrs(link); err != nil || len(names) != 0 {
+			t.Errorf("Expected no attributes left (overwrite %v), got %q, %v", overwrite, names, err)
+		}
+	}
+}
+
+// TestWipeFileSecurityXattrs tests that -scrub-security removes the file
+// capabilities and SELinux label, and leaves other attributes to -xattrs
+func TestWipeFileSecurityXattrs(t *testing.T) {
+	oldLabels, oldLinked := *scrubLabels, *forceLinked
+	defer func() {
+		*scrubLabels, *forceLinked = oldLabels, oldLinked
+		wipeResults.wiped.Store(0)
+	}()
+	*scrubLabels, *forceLinked = true, true
+
+	dir := t.TempDir()
+	file, link := filepath.Join(dir, "tool"), filepath.Join(dir, "link")
+	if err := os.WriteFile(file, []byte("binary"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(file, link); err != nil {
+		t.Fatal(err)
+	}
+	// vfs_cap_data revision 2, permitting CAP_NET_BIND_SERVICE. Linux drops
+	// capabilities on the overwrite already, but keeps the SELinux label.
+	capability := binary.LittleEndian.AppendUint32(nil, 0x02000000)
+	capability = binary.LittleEndian.AppendUint32(capability, 1<<10)
+	capability = append(capability, make([]byte, 12)...)
+	if err := setXattr(file, "security.capability", capability); err != nil {
+		t.Skipf("Cannot set file capabilities here (needs root): %v", err)
+	}
+	if err := setXattr(file, "security.selinux", []byte("system_u:object_r:bin_t:s0\x00")); err != nil {
+		t.Logf("Cannot set an SELinux label here, testing capabilities only: %v", err)
+	}
+	if err := setXattr(file, "user.kept", []byte("kept")); err != nil {
+		t.Skipf("Cannot set user attributes here: %v", err)
+	}
+
+	wipeFile(file)
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("Expected '%s' to be wiped", file)
+	}
+	names, err := listXattrs(link)
+	if err != nil || len(names) != 1 || names[0] != "user.kept" {
+		t.Errorf("Expected only user.kept left, got %q, %v", names, err)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin
+
+package main
+
+// Extended attributes are not supported on this platform; there is
+// nothing to enumerate.
+func listXattrs(path string) ([]string, error) {
+	return nil, nil
+}
+
+func getXattrSize(path, name string) (int, error) {
+	return 0, nil
+}
+
+func setXattr(path, name string, value []byte) error {
+	return nil
+}
+
+func removeXattr(path, name string) error {
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	zapHeadSize   = 1024 * 1024
+	zapTailSize   = 1024 * 1024
+	zapMirrorSize = 4096
+)
+
+// zapRegion is a byte range of a device that holds partitioning or
+// filesystem metadata
+type zapRegion struct {
+	name   string
+	offset int64
+	length int64
+}
+
+// signatureRegions returns the regions of a size byte device where
+// partition tables and filesystem signatures live, clamped to the device.
+func signatureRegions(size int64) []zapRegion {
+	candidates := []zapRegion{
+		// MBR, primary GPT, and the superblocks of ext, XFS, btrfs, ZFS
+		// (labels 0 and 1), LVM, md 1.1/1.2, LUKS, ISO 9660 and swap
+		{"start of device", 0, zapHeadSize},
+		{"btrfs superblock mirror", 64 * 1024 * 1024, zapMirrorSize},
+		{"btrfs superblock mirror", 256 * 1024 * 1024 * 1024, zapMirrorSize},
+		// Backup GPT, md 0.90/1.0 and ZFS labels 2 and 3
+		{"end of device", size - zapTailSize, zapTailSize},
+	}
+
+	var regions []zapRegion
+	for _, region := range candidates {
+		if region.offset < 0 {
+			region.length += region.offset
+			region.offset = 0
+		}
+		if region.offset >= size || region.length <= 0 {
+			continue
+		}
+		if region.offset+region.length > size {
+			region.length = size - region.offset
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// zapSignatures overwrites the partition tables and filesystem signatures
+// of devicePath and of each of its partitions with fake header data.
+func zapSignatures(devicePath string) error {
+	file, err := os.OpenFile(devicePath, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	regions := signatureRegions(size)
+	for _, part := range devicePartitions(devicePath) {
+		for _, region := range signatureRegions(part.size) {
+			region.name = fmt.Sprintf("%s, %s", part.name, region.name)
+			region.offset += part.start
+			regions = append(regions, region)
+		}
+	}
+
+	fakeData := newFakeReader()
+	defer fakeData.Close()
+	for _, region := range regions {
+		buffer := make([]byte, region.length)
+		fakeData.Read(buffer)
+		if _, err := writeAtWithRetry(file, buffer, region.offset); err != nil {
+			return fmt.Errorf("%s: %w", region.name, err)
+		}
+		printVerbose(verboseActions, "overwrote %s: %d KB at offset %d\n", region.name, region.length/1024, region.offset)
+	}
+	if err := syncFile(file); err != nil {
+		return err
+	}
+
+	// Have the kernel drop the partitions it read from the old table
+	if err := rereadPartitions(file); err != nil {
+		printVerboseWarning(verboseActions, "cannot reread partition table of '%s': %s\n", devicePath, getSimpleError(err))
+	}
+	return nil
+}
+
+// runZapCommand implements "wipefile zap", which destroys the partition
+// table and filesystem signatures of a disk, like wipefs, but with fake
+// headers instead of zeros.
+func runZapCommand(args []string) {
+	zapFlags := flag.NewFlagSet("zap", flag.ExitOnError)
+	registerOutputFlags(zapFlags)
+	registerLogFlags(zapFlags)
+	zapFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s zap [options] <device>\n", os.Args[0])
+		zapFlags.PrintDefaults()
+	}
+	parseFlags(zapFlags, "zap", args)
+
+	if zapFlags.NArg() != 1 {
+		zapFlags.Usage()
+		os.Exit(1)
+	}
+	devicePath := zapFlags.Arg(0)
+	startLog()
+
+	info, err := os.Stat(devicePath)
+	if err != nil {
+		printError("cannot access '%s': %s\n", devicePath, getSimpleError(err))
+		os.Exit(1)
+	}
+	if info.Mode()&os.ModeDevice == 0 {
+		printError("'%s' is not a device\n", devicePath)
+		os.Exit(1)
+	}
+	if !confirmDevice(devicePath) {
+		os.Exit(1)
+	}
+
+	if err := zapSignatures(devicePath); err != nil {
+		printError("zapping '%s' failed: %s\n", devicePath, getSimpleError(err))
+		os.Exit(1)
+	}
+	printSummary("zapped partition table and signatures of '%s'\n", devicePath)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const blkRRPart = 0x125f // _IO(0x12, 95)
+
+// partition is a partition of a disk, in bytes from the disk's start
+type partition struct {
+	name  string
+	start int64
+	size  int64
+}
+
+// devicePartitions returns the partitions of the disk at devicePath that
+// the kernel knows about, according to sysfs.
+func devicePartitions(devicePath string) []partition {
+	resolved, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		return nil
+	}
+	sysPath := filepath.Join("/sys/class/block", filepath.Base(resolved))
+	entries, err := os.ReadDir(sysPath)
+	if err != nil {
+		return nil
+	}
+
+	var partitions []partition
+	for _, entry := range entries {
+		dir := filepath.Join(sysPath, entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, "partition")); err != nil {
+			continue
+		}
+		start, err1 := readSectors(filepath.Join(dir, "start"))
+		size, err2 := readSectors(filepath.Join(dir, "size"))
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		partitions = append(partitions, partition{name: entry.Name(), start: start, size: size})
+	}
+	return partitions
+}
+
+// readSectors reads a sysfs count of 512 byte sectors, returned in bytes
+func readSectors(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	sectors, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return sectors * 512, err
+}
+
+// rereadPartitions asks the kernel to reload the partition table of device.
+func rereadPartitions(device *os.File) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, device.Fd(), blkRRPart, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
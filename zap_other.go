@@ -0,0 +1,22 @@
+//go:build !linux
+
+package main
+
+import "os"
+
+// partition is a partition of a disk, in bytes from the disk's start
+type partition struct {
+	name  string
+	start int64
+	size  int64
+}
+
+// devicePartitions returns the partitions of a disk. Only Linux lists them
+// cheaply, elsewhere only the whole-disk regions get overwritten.
+func devicePartitions(devicePath string) []partition {
+	return nil
+}
+
+func rereadPartitions(device *os.File) error {
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSignatureRegions tests that regions cover both ends and stay on the device
+func TestSignatureRegions(t *testing.T) {
+	for _, size := range []int64{512, 1536 * 1024, 100 * 1024 * 1024, 300 * 1024 * 1024 * 1024} {
+		regions := signatureRegions(size)
+		if len(regions) == 0 || regions[0].offset != 0 {
+			t.Fatalf("Device of %d bytes should be zapped from the start", size)
+		}
+		last := regions[len(regions)-1]
+		if last.offset+last.length != size {
+			t.Errorf("Device of %d bytes should be zapped up to the end, got %d", size, last.offset+last.length)
+		}
+		for _, region := range regions {
+			if region.offset < 0 || region.length <= 0 || region.offset+region.length > size {
+				t.Errorf("Region %q at %d (%d bytes) is outside a device of %d bytes", region.name, region.offset, region.length, size)
+			}
+		}
+	}
+
+	if regions := signatureRegions(300 * 1024 * 1024 * 1024); len(regions) != 4 {
+		t.Errorf("Large device should include both btrfs mirrors, got %d regions", len(regions))
+	}
+}
+
+// TestZapSignatures tests that a disk image loses its head and tail but keeps its middle
+func TestZapSignatures(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "disk.img")
+	original := bytes.Repeat([]byte{0xaa}, 4*1024*1024)
+	if err := os.WriteFile(image, original, 0644); err != nil {
+		t.Fatalf("Failed to create image: %v", err)
+	}
+
+	if err := zapSignatures(image); err != nil {
+		t.Fatalf("zapSignatures failed: %v", err)
+	}
+
+	content, err := os.ReadFile(image)
+	if err != nil {
+		t.Fatalf("Failed to read image: %v", err)
+	}
+	if len(content) != len(original) {
+		t.Fatalf("Image should keep its size, got %d", len(content))
+	}
+	if bytes.Equal(content[:512], original[:512]) {
+		t.Error("MBR should be overwritten")
+	}
+	if bytes.Equal(content[len(content)-512:], original[:512]) {
+		t.Error("Backup GPT should be overwritten")
+	}
+	if !bytes.Equal(content[2*1024*1024:2*1024*1024+512], original[:512]) {
+		t.Error("Data area should be left as is")
+	}
+}